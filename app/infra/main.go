@@ -20,10 +20,15 @@ import (
 	// "github.com/aws/aws-cdk-go/awscdk/v2/awssqs"
 	"github.com/aws/aws-cdk-go/awscdk/v2"
 	agw "github.com/aws/aws-cdk-go/awscdk/v2/awsapigateway"
+	agwv2 "github.com/aws/aws-cdk-go/awscdk/v2/awsapigatewayv2"
 	cognito "github.com/aws/aws-cdk-go/awscdk/v2/awscognito"
 	docdb "github.com/aws/aws-cdk-go/awscdk/v2/awsdocdb"
 	ec2 "github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	events "github.com/aws/aws-cdk-go/awscdk/v2/awsevents"
+	targets "github.com/aws/aws-cdk-go/awscdk/v2/awseventstargets"
 	iam "github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	awslambda "github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
+	s3 "github.com/aws/aws-cdk-go/awscdk/v2/awss3"
 	identitypool "github.com/aws/aws-cdk-go/awscdkcognitoidentitypoolalpha/v2"
 	lambda "github.com/aws/aws-cdk-go/awscdklambdagoalpha/v2"
 	"github.com/aws/constructs-go/constructs/v10"
@@ -60,6 +65,10 @@ type Function struct {
 	Name        string `yaml:"Name"`
 	Description string `yaml:"Description"`
 	Method      string `yaml:"Method"`
+	Schedule    string `yaml:"Schedule"`
+	Route       string `yaml:"Route"`
+	// Authorizer protect the endpoint, cognito require a token of the user pool
+	Authorizer  string `yaml:"Authorizer"`
 	Environment struct {
 		Variables map[string]string `yaml:"Variables"`
 	} `yaml:"Environment"`
@@ -159,6 +168,13 @@ func NewInfraStack(scope constructs.Construct, id string, props *InfraStackProps
 		GenerateSecret: jsii.Bool(false),
 	})
 
+	//create the group of the support staff, its members can refund the rides
+	cognito.NewCfnUserPoolGroup(stack, jsii.String("tgs-with-go-support-group"), &cognito.CfnUserPoolGroupProps{
+		UserPoolId:  c.UserPoolId(),
+		GroupName:   jsii.String("support"),
+		Description: jsii.String("support staff allowed to refund the rides"),
+	})
+
 	identitypool.NewUserPoolAuthenticationProvider(&identitypool.UserPoolAuthenticationProviderProps{
 		UserPool:       c,
 		UserPoolClient: poolClient,
@@ -217,6 +233,43 @@ func NewInfraStack(scope constructs.Construct, id string, props *InfraStackProps
 		},
 	})
 
+	//create the authorizer checking the user pool tokens of the protected endpoints
+	authorizer := agw.NewCognitoUserPoolsAuthorizer(stack, jsii.String("tgs-with-go-authorizer"), &agw.CognitoUserPoolsAuthorizerProps{
+		CognitoUserPools: &[]cognito.IUserPool{c},
+	})
+
+	//================================================================= WebSocket
+	//create the websocket api pushing the ride updates, the routes are added with their functions
+	wsAPI := agwv2.NewCfnApi(stack, jsii.String("tgswithgows"), &agwv2.CfnApiProps{
+		Name:                     jsii.String("tgswithgows"),
+		ProtocolType:             jsii.String("WEBSOCKET"),
+		RouteSelectionExpression: jsii.String("$request.body.action"),
+	})
+
+	wsStage := agwv2.NewCfnStage(stack, jsii.String("tgswithgows-stage"), &agwv2.CfnStageProps{
+		ApiId:      wsAPI.Ref(),
+		StageName:  jsii.String(template.APIVersion),
+		AutoDeploy: jsii.Bool(true),
+	})
+
+	wsEndpoint := fmt.Sprintf("https://%s.execute-api.%s.amazonaws.com/%s", *wsAPI.Ref(), *stack.Region(), *wsStage.StageName())
+	wsArn := fmt.Sprintf("arn:aws:execute-api:%s:%s:%s/*", *stack.Region(), *stack.Account(), *wsAPI.Ref())
+
+	//allow the functions to push messages to the connected clients
+	role.AddToPrincipalPolicy(iam.NewPolicyStatement(&iam.PolicyStatementProps{
+		Actions:   jsii.Strings("execute-api:ManageConnections"),
+		Resources: jsii.Strings(wsArn),
+	}))
+
+	//================================================================= Storage-S3
+	//create the private bucket storing the invoices of the rides, they are only shared through presigned links
+	invoiceBucket := s3.NewBucket(stack, jsii.String("tgs-with-go-invoices"), &s3.BucketProps{
+		BlockPublicAccess: s3.BlockPublicAccess_BLOCK_ALL(),
+		Encryption:        s3.BucketEncryption_S3_MANAGED,
+		RemovalPolicy:     awscdk.RemovalPolicy_RETAIN,
+	})
+	invoiceBucket.GrantReadWrite(role, nil)
+
 	//extract secret from aws secret manager
 	secrets, err := ssm.GetSecrets(sess, template.Globals.SSMPoolName)
 	if err != nil {
@@ -224,9 +277,6 @@ func NewInfraStack(scope constructs.Construct, id string, props *InfraStackProps
 	}
 
 	for _, function := range template.Functions {
-		//create a new endpoint
-		endpoint := api.Root().AddResource(jsii.String(function.Path), nil)
-
 		//extract all environment variables
 		env := map[string]*string{}
 		for name, value := range function.Environment.Variables {
@@ -241,6 +291,8 @@ func NewInfraStack(scope constructs.Construct, id string, props *InfraStackProps
 		//put default environment variables
 		env["COGNITO_USER_POOL_ID"] = c.UserPoolId()
 		env["COGNITO_CLIENT_POOL_ID"] = poolClient.UserPoolClientId()
+		env["WEBSOCKET_ENDPOINT"] = jsii.String(wsEndpoint)
+		env["INVOICE_BUCKET"] = invoiceBucket.BucketName()
 
 		//create the new lambda function
 		lambdaFn := lambda.NewGoFunction(
@@ -257,6 +309,50 @@ func NewInfraStack(scope constructs.Construct, id string, props *InfraStackProps
 			},
 		)
 
+		//scheduled functions are triggered by an event bridge rule instead of an endpoint
+		if len(function.Schedule) > 0 {
+			events.NewRule(stack, jsii.String(function.Name+"-schedule"), &events.RuleProps{
+				Schedule: events.Schedule_Expression(jsii.String(function.Schedule)),
+				Targets:  &[]events.IRuleTarget{targets.NewLambdaFunction(lambdaFn, nil)},
+			})
+			continue
+		}
+
+		//websocket functions are integrated to their route of the websocket api
+		if len(function.Route) > 0 {
+			integration := agwv2.NewCfnIntegration(stack, jsii.String(function.Name+"-integration"), &agwv2.CfnIntegrationProps{
+				ApiId:           wsAPI.Ref(),
+				IntegrationType: jsii.String("AWS_PROXY"),
+				IntegrationUri: jsii.String(fmt.Sprintf(
+					"arn:aws:apigateway:%s:lambda:path/2015-03-31/functions/%s/invocations",
+					*stack.Region(),
+					*lambdaFn.FunctionArn(),
+				)),
+			})
+
+			agwv2.NewCfnRoute(stack, jsii.String(function.Name+"-route"), &agwv2.CfnRouteProps{
+				ApiId:    wsAPI.Ref(),
+				RouteKey: jsii.String(function.Route),
+				Target:   jsii.String("integrations/" + *integration.Ref()),
+			})
+
+			lambdaFn.AddPermission(jsii.String(function.Name+"-permission"), &awslambda.Permission{
+				Principal: iam.NewServicePrincipal(jsii.String("apigateway.amazonaws.com"), nil),
+				SourceArn: jsii.String(wsArn),
+			})
+			continue
+		}
+
+		//create a new endpoint, nested paths such as webhooks/{provider} create each of their resources
+		endpoint := api.Root().ResourceForPath(jsii.String(function.Path))
+
+		//protect the endpoint with the cognito authorizer, the token claims are passed to the function
+		options := &agw.MethodOptions{}
+		if function.Authorizer == "cognito" {
+			options.Authorizer = authorizer
+			options.AuthorizationType = agw.AuthorizationType_COGNITO
+		}
+
 		//adding endpoint and linking the function to it
 		endpoint.AddMethod(
 			jsii.String(function.Method),
@@ -264,7 +360,7 @@ func NewInfraStack(scope constructs.Construct, id string, props *InfraStackProps
 				lambdaFn,
 				nil,
 			),
-			&agw.MethodOptions{},
+			options,
 		)
 	}
 
@@ -23,7 +23,7 @@ func Handler(ctx context.Context, req events.APIGatewayProxyRequest, cfg *config
 		return lambda.SendError(ctx, http.StatusBadRequest, fmt.Errorf("invalid body: %v", err))
 	}
 
-	charge, err := provider.CreatePayment(ctx, data, cfg, t.Now)
+	charge, err := provider.CreatePayment(ctx, data, cfg, t.Aggregator, t.Now)
 	if err != nil {
 		return lambda.SendError(ctx, http.StatusBadRequest, fmt.Errorf("failed to create payment: %v", err))
 	}
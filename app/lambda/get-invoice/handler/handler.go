@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"vtc/business/v1/core/provider"
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/validate"
+	"vtc/foundation/config"
+	"vtc/foundation/lambda"
+)
+
+func Handler(ctx context.Context, req events.APIGatewayProxyRequest, cfg *config.App, t *lambda.RequestTrace) (events.APIGatewayProxyResponse, error) {
+	data := models.GetInvoiceDTO{
+		RideID: req.QueryStringParameters["rideID"],
+		UserID: req.QueryStringParameters["userID"],
+	}
+
+	if err := validate.Check(&data); err != nil {
+		return lambda.SendError(ctx, http.StatusBadRequest, fmt.Errorf("invalid query parameters: %v", err))
+	}
+
+	link, err := provider.GetInvoice(ctx, data, cfg, t.Now)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return lambda.SendError(ctx, http.StatusNotFound, err)
+		}
+		return lambda.SendError(ctx, http.StatusBadRequest, fmt.Errorf("failed to get invoice: %v", err))
+	}
+
+	return lambda.SendResponse(ctx, http.StatusOK, link)
+}
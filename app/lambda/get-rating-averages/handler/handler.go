@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"vtc/business/v1/core/provider"
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/validate"
+	"vtc/foundation/config"
+	"vtc/foundation/lambda"
+)
+
+func Handler(ctx context.Context, req events.APIGatewayProxyRequest, cfg *config.App, t *lambda.RequestTrace) (events.APIGatewayProxyResponse, error) {
+	data := models.RatingAveragesDTO{
+		Provider: req.QueryStringParameters["provider"],
+	}
+
+	if err := validate.Check(&data); err != nil {
+		return lambda.SendError(ctx, http.StatusBadRequest, fmt.Errorf("invalid query parameters: %v", err))
+	}
+
+	averages, err := provider.GetRatingAverages(ctx, data, cfg, t.Aggregator)
+	if errors.Is(err, provider.ErrUnknownAggregator) {
+		return lambda.SendError(ctx, http.StatusBadRequest, fmt.Errorf("failed to get rating averages: %v", err))
+	}
+	if err != nil {
+		return lambda.SendError(ctx, http.StatusInternalServerError, fmt.Errorf("failed to get rating averages: %v", err))
+	}
+
+	return lambda.SendResponse(ctx, http.StatusOK, averages)
+}
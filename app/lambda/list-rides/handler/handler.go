@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"vtc/business/v1/core/provider"
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/database"
+	"vtc/business/v1/sys/validate"
+	"vtc/foundation/config"
+	"vtc/foundation/lambda"
+)
+
+func Handler(ctx context.Context, req events.APIGatewayProxyRequest, cfg *config.App, t *lambda.RequestTrace) (events.APIGatewayProxyResponse, error) {
+	params := req.QueryStringParameters
+
+	data := models.ListRidesDTO{
+		UserID:   params["userID"],
+		Cursor:   params["cursor"],
+		Provider: params["provider"],
+		From:     params["from"],
+		To:       params["to"],
+		Type:     params["type"],
+		Sort:     params["sort"],
+	}
+
+	// statuses are given as a comma separated list
+	if statuses := params["status"]; len(statuses) > 0 {
+		data.Statuses = strings.Split(statuses, ",")
+	}
+
+	if limit := params["limit"]; len(limit) > 0 {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return lambda.SendError(ctx, http.StatusBadRequest, fmt.Errorf("invalid limit %v: %v", limit, err))
+		}
+		data.Limit = n
+	}
+
+	if err := validate.Check(&data); err != nil {
+		return lambda.SendError(ctx, http.StatusBadRequest, fmt.Errorf("invalid query parameters: %v", err))
+	}
+
+	page, err := provider.ListRides(ctx, data, cfg)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidCursor) {
+			return lambda.SendError(ctx, http.StatusBadRequest, err)
+		}
+		return lambda.SendError(ctx, http.StatusInternalServerError, fmt.Errorf("failed to list rides: %v", err))
+	}
+
+	return lambda.SendResponse(ctx, http.StatusOK, page)
+}
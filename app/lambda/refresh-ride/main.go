@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	awslambda "github.com/aws/aws-lambda-go/lambda"
+	"vtc/business/v1/core/provider"
+	"vtc/foundation/config"
+)
+
+var app, err = config.NewApp()
+
+func main() {
+	if err != nil {
+		log.Fatalf("failed to create a new app: [%v]", err)
+	}
+
+	awslambda.Start(handler)
+}
+
+// handler refresh all the ongoing rides with the latest data of their provider.
+// The function is triggered on a schedule by an aws event bridge rule.
+func handler(ctx context.Context, _ events.CloudWatchEvent) error {
+	updated, err := provider.RefreshRide(ctx, app, time.Now())
+	log.Printf("%d rides updated", updated)
+	if err != nil {
+		return fmt.Errorf("failed to refresh rides: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"vtc/business/v1/core/provider"
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/validate"
+	"vtc/foundation/config"
+	"vtc/foundation/lambda"
+)
+
+// Handler refund a ride. The route is protected by the cognito authorizer, only the members of the support group can
+// refund and the operator recorded on the refund is the authenticated user.
+func Handler(ctx context.Context, req events.APIGatewayProxyRequest, cfg *config.App, t *lambda.RequestTrace) (events.APIGatewayProxyResponse, error) {
+	id, err := lambda.GetIdentity(req)
+	if err != nil {
+		return lambda.SendError(ctx, http.StatusUnauthorized, err)
+	}
+
+	if !id.InGroup(cfg.Env.Refunds.SupportGroup) {
+		return lambda.SendError(ctx, http.StatusForbidden, fmt.Errorf("user %v is not allowed to refund rides", id.Username))
+	}
+
+	var data models.RefundRideDTO
+
+	if err := lambda.DecodeBody(req.Body, &data); err != nil {
+		return lambda.SendError(ctx, http.StatusBadRequest, fmt.Errorf("failed to decode request body: %v", err))
+	}
+
+	data.Operator = id.Email
+	if len(data.Operator) == 0 {
+		data.Operator = id.Username
+	}
+
+	if err := validate.Check(&data); err != nil {
+		return lambda.SendError(ctx, http.StatusBadRequest, fmt.Errorf("invalid body: %v", err))
+	}
+
+	ride, err := provider.RefundRide(ctx, data, cfg, t.Aggregator, t.Now)
+
+	switch {
+	case err == nil:
+		return lambda.SendResponse(ctx, http.StatusOK, ride)
+	case errors.Is(err, models.ErrNotFound):
+		return lambda.SendError(ctx, http.StatusNotFound, err)
+	case errors.Is(err, provider.ErrRefundExceedsCaptured):
+		return lambda.SendError(ctx, http.StatusConflict, err)
+	default:
+		return lambda.SendError(ctx, http.StatusBadRequest, fmt.Errorf("failed to refund ride: %v", err))
+	}
+}
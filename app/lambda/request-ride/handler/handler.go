@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"vtc/business/v1/core/provider"
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/validate"
+	"vtc/foundation/config"
+	"vtc/foundation/lambda"
+)
+
+func Handler(ctx context.Context, req events.APIGatewayProxyRequest, cfg *config.App, t *lambda.RequestTrace) (events.APIGatewayProxyResponse, error) {
+	var data models.NewRideDTO
+
+	if err := lambda.DecodeBody(req.Body, &data); err != nil {
+		return lambda.SendError(ctx, http.StatusBadRequest, fmt.Errorf("failed to decode request body: %v", err))
+	}
+
+	if err := validate.Check(&data); err != nil {
+		return lambda.SendError(ctx, http.StatusBadRequest, fmt.Errorf("invalid body: %v", err))
+	}
+
+	ride, err := provider.RequestRide(ctx, data, cfg, t.Aggregator, t.Now)
+
+	// the offer was re-quoted at a new price, the user must confirm the new offer
+	var priceErr *provider.PriceChangedError
+	if errors.As(err, &priceErr) {
+		return lambda.SendResponse(ctx, http.StatusConflict, struct {
+			Message string `json:"message"`
+			*provider.PriceChangedError
+		}{priceErr.Error(), priceErr})
+	}
+
+	if err != nil {
+		return lambda.SendError(ctx, http.StatusBadRequest, fmt.Errorf("failed to request ride: %v", err))
+	}
+
+	return lambda.SendResponse(ctx, http.StatusCreated, ride)
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"vtc/business/v1/core/provider"
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/validate"
+	"vtc/foundation/config"
+	"vtc/foundation/lambda"
+)
+
+func Handler(ctx context.Context, req events.APIGatewayProxyRequest, cfg *config.App, t *lambda.RequestTrace) (events.APIGatewayProxyResponse, error) {
+	var data models.ReviewRideDTO
+
+	if err := lambda.DecodeBody(req.Body, &data); err != nil {
+		return lambda.SendError(ctx, http.StatusBadRequest, fmt.Errorf("failed to decode request body: %v", err))
+	}
+
+	if err := validate.Check(&data); err != nil {
+		return lambda.SendError(ctx, http.StatusBadRequest, fmt.Errorf("invalid body: %v", err))
+	}
+
+	review, err := provider.ReviewRide(ctx, data, cfg, t.Now)
+
+	switch {
+	case err == nil:
+		return lambda.SendResponse(ctx, http.StatusCreated, review)
+	case errors.Is(err, models.ErrNotFound):
+		return lambda.SendError(ctx, http.StatusNotFound, err)
+	case errors.Is(err, provider.ErrAlreadyReviewed):
+		return lambda.SendError(ctx, http.StatusConflict, err)
+	default:
+		return lambda.SendError(ctx, http.StatusBadRequest, fmt.Errorf("failed to review ride: %v", err))
+	}
+}
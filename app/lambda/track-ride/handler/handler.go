@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"vtc/business/v1/core/provider"
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/validate"
+	"vtc/foundation/config"
+	"vtc/foundation/lambda"
+)
+
+func Handler(ctx context.Context, req events.APIGatewayProxyRequest, cfg *config.App, t *lambda.RequestTrace) (events.APIGatewayProxyResponse, error) {
+	data := models.TrackRideDTO{
+		RideID: req.QueryStringParameters["rideID"],
+		UserID: req.QueryStringParameters["userID"],
+	}
+
+	if err := validate.Check(&data); err != nil {
+		return lambda.SendError(ctx, http.StatusBadRequest, fmt.Errorf("invalid query parameters: %v", err))
+	}
+
+	tracking, err := provider.TrackRide(ctx, data, cfg, t.Now)
+	if err != nil {
+		return lambda.SendError(ctx, http.StatusBadRequest, fmt.Errorf("failed to track ride: %v", err))
+	}
+
+	return lambda.SendResponse(ctx, http.StatusOK, tracking)
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"vtc/business/v1/core/provider"
+	sysprovider "vtc/business/v1/sys/provider"
+	"vtc/foundation/config"
+	"vtc/foundation/lambda"
+)
+
+func Handler(ctx context.Context, req events.APIGatewayProxyRequest, cfg *config.App, t *lambda.RequestTrace) (events.APIGatewayProxyResponse, error) {
+	// the signature is computed on the raw body sent by the provider
+	body := []byte(req.Body)
+	if req.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(req.Body)
+		if err != nil {
+			return lambda.SendError(ctx, http.StatusBadRequest, fmt.Errorf("invalid body encoding: %v", err))
+		}
+		body = decoded
+	}
+
+	err := provider.HandleWebhook(ctx, cfg, req.PathParameters["provider"], req.Headers, body, t.Now)
+
+	switch {
+	case err == nil:
+		return lambda.SendResponse(ctx, http.StatusOK, map[string]string{"status": "received"})
+	case errors.Is(err, sysprovider.ErrInvalidSignature):
+		return lambda.SendError(ctx, http.StatusUnauthorized, err)
+	case errors.Is(err, sysprovider.ErrUnknownProvider):
+		return lambda.SendError(ctx, http.StatusNotFound, err)
+	case errors.Is(err, sysprovider.ErrInvalidWebhook):
+		return lambda.SendError(ctx, http.StatusBadRequest, err)
+	default:
+		return lambda.SendError(ctx, http.StatusInternalServerError, fmt.Errorf("failed to handle webhook: %v", err))
+	}
+}
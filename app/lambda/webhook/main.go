@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	awslambda "github.com/aws/aws-lambda-go/lambda"
+	"github.com/google/uuid"
+	"vtc/app/lambda/webhook/handler"
+	"vtc/foundation/config"
+	"vtc/foundation/lambda"
+)
+
+var app, err = config.NewApp()
+
+func main() {
+	if err != nil {
+		log.Fatalf("failed to create a new app: [%v]", err)
+	}
+
+	awslambda.Start(webhook)
+}
+
+// webhook serve the provider callbacks. The providers don't send any aggregator header so the request trace is
+// created here rather than through web.NewHandler, the aggregator being known once the ride is found.
+func webhook(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	trace := lambda.RequestTrace{
+		Now: time.Now(),
+		ID:  uuid.NewString(),
+	}
+
+	ctx := context.WithValue(context.Background(), lambda.CtxKey, &trace)
+
+	return handler.Handler(ctx, req, app, &trace)
+}
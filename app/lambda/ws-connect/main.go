@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	awslambda "github.com/aws/aws-lambda-go/lambda"
+	"vtc/business/v1/core/provider"
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/aws/cognito"
+	"vtc/business/v1/sys/validate"
+	"vtc/business/v1/web"
+	"vtc/foundation/config"
+)
+
+var app, err = config.NewApp()
+
+func main() {
+	if err != nil {
+		log.Fatalf("failed to create a new app: [%v]", err)
+	}
+
+	awslambda.Start(handler)
+}
+
+// handler register the websocket connection of a user. The user is authenticated by the access token given on login
+// through the token query parameter, browsers can't set headers on a websocket. A connection refused with a non 2xx
+// status is closed by api gateway.
+func handler(ctx context.Context, req events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
+	data := models.ConnectDTO{
+		ConnectionID: req.RequestContext.ConnectionID,
+		Token:        req.QueryStringParameters["token"],
+		Aggregator:   req.Headers[web.AggregatorHeaderName],
+	}
+
+	if err := validate.Check(&data); err != nil {
+		log.Printf("refusing connection %v: %v", data.ConnectionID, err)
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusBadRequest}, nil
+	}
+
+	err := provider.Connect(ctx, data, app, time.Now())
+	if errors.Is(err, cognito.ErrInvalidToken) || errors.Is(err, models.ErrNotFound) {
+		log.Printf("refusing connection %v: %v", data.ConnectionID, err)
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusUnauthorized}, nil
+	}
+	if err != nil {
+		log.Printf("failed to register connection %v: %v", data.ConnectionID, err)
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusInternalServerError}, nil
+	}
+
+	return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+}
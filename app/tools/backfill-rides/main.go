@@ -0,0 +1,35 @@
+// One-off migration setting the pickup date and the addresses of the rides booked before they were kept on the
+// ride, so the ride history can be sorted and shown without the offer. The env variables are parsed from the given
+// env file, it can be run again safely as only the missing fields are set.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/joho/godotenv"
+	"vtc/business/v1/core/provider"
+	"vtc/foundation/config"
+)
+
+func main() {
+	envFile := flag.String("env", ".env.local", "env file of the database to migrate")
+	batch := flag.Int("batch", 100, "number of rides read at once")
+	flag.Parse()
+
+	if err := godotenv.Load(*envFile); err != nil {
+		log.Fatalf("failed to parse env file: %v", err)
+	}
+
+	app, err := config.NewApp()
+	if err != nil {
+		log.Fatalf("failed to create new app config: %v", err)
+	}
+
+	updated, err := provider.BackfillRideSummaries(context.Background(), app, *batch)
+	log.Printf("%d rides backfilled", updated)
+	if err != nil {
+		log.Fatalf("failed to backfill rides: %v", err)
+	}
+}
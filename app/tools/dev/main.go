@@ -24,12 +24,22 @@ import (
 	"vtc/foundation/config"
 	"vtc/foundation/lambda"
 
+	cancelRide "vtc/app/lambda/cancel-ride/handler"
 	createPaymentMethod "vtc/app/lambda/create-payment-method/handler"
 	createPayment "vtc/app/lambda/create-payment/handler"
+	getCancellationFees "vtc/app/lambda/get-cancellation-fees/handler"
+	getInvoice "vtc/app/lambda/get-invoice/handler"
 	getOffers "vtc/app/lambda/get-offers/handler"
+	getRatingAverages "vtc/app/lambda/get-rating-averages/handler"
 	hello "vtc/app/lambda/hello/handler"
+	listRides "vtc/app/lambda/list-rides/handler"
 	login "vtc/app/lambda/login/handler"
+	refundRide "vtc/app/lambda/refund-ride/handler"
+	requestRide "vtc/app/lambda/request-ride/handler"
+	reviewRide "vtc/app/lambda/review-ride/handler"
 	signup "vtc/app/lambda/signup/handler"
+	trackRide "vtc/app/lambda/track-ride/handler"
+	webhook "vtc/app/lambda/webhook/handler"
 )
 
 type Template struct {
@@ -61,6 +71,8 @@ type Function struct {
 	Name        string `yaml:"Name"`
 	Description string `yaml:"Description"`
 	Method      string `yaml:"Method"`
+	Schedule    string `yaml:"Schedule"`
+	Route       string `yaml:"Route"`
 	Environment struct {
 		Variables map[string]string `yaml:"Variables"`
 	} `yaml:"Environment"`
@@ -73,6 +85,16 @@ var mapFunctionNameHandler = map[string]web.Handler{
 	"helloHandler":               hello.Handler,
 	"createPaymentMethodHandler": createPaymentMethod.Handler,
 	"createPaymentHandler":       createPayment.Handler,
+	"cancelRideHandler":          cancelRide.Handler,
+	"requestRideHandler":         requestRide.Handler,
+	"getCancellationFeesHandler": getCancellationFees.Handler,
+	"trackRideHandler":           trackRide.Handler,
+	"listRidesHandler":           listRides.Handler,
+	"getInvoiceHandler":          getInvoice.Handler,
+	"reviewRideHandler":          reviewRide.Handler,
+	"getRatingAveragesHandler":   getRatingAverages.Handler,
+	"refundRideHandler":          refundRide.Handler,
+	"webhookHandler":             webhook.Handler,
 }
 
 func main() {
@@ -103,6 +125,11 @@ func main() {
 	router := mux.NewRouter()
 
 	for _, function := range template.Functions {
+		//scheduled functions are workers and websocket routes are not served through http
+		if len(function.Schedule) > 0 || len(function.Route) > 0 {
+			continue
+		}
+
 		func(function Function) {
 			log.Printf("Registering new route [%s] with path [%s]", function.Name, function.Path)
 			router.HandleFunc("/"+function.Path, func(writer http.ResponseWriter, request *http.Request) {
@@ -116,7 +143,15 @@ func main() {
 
 				event.Path = request.URL.Path
 				event.PathParameters = vars
-				event.QueryStringParameters = vars
+				event.QueryStringParameters = map[string]string{}
+
+				for key, val := range vars {
+					event.QueryStringParameters[key] = val
+				}
+				for key, val := range request.URL.Query() {
+					//@todo handle array value
+					event.QueryStringParameters[key] = val[0]
+				}
 
 				bodyBytes, err := io.ReadAll(request.Body)
 				if err != nil {
@@ -0,0 +1,44 @@
+// Code for the local fake provider server. It answers the MySam and Uber endpoints used by the
+// integrations so the api can run offline. Point the providers to it through the env.local file:
+//
+//	MY_SAM_BASE_URL=http://localhost:3040/mysam
+//	UBER_BASE_URL=http://localhost:3040/uber
+//	UBER_COOKIE=fake
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"vtc/business/v1/sys/provider/fake"
+)
+
+func main() {
+	addr := flag.String("addr", ":3040", "address the fake provider server listen on")
+	scenario := flag.String("scenario", "nominal", "scenario played by the fake providers")
+	flag.Parse()
+
+	sc, ok := fake.Scenarios[*scenario]
+	if !ok {
+		var names []string
+		for name := range fake.Scenarios {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		log.Fatalf("unknown scenario %v, available scenarios are %v", *scenario, names)
+	}
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           fake.New(sc).Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	log.Printf("Starting fake provider server on %v playing the %v scenario", *addr, *scenario)
+	if err := srv.ListenAndServe(); err != nil {
+		log.Fatalf("failed to start the server: %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/provider"
+	"vtc/foundation/config"
+)
+
+// ErrUnknownAggregator is returned when the aggregator of a request is missing from the registry
+var ErrUnknownAggregator = errors.New("unknown aggregator")
+
+// findAggregator return the aggregator registered under the given code
+func findAggregator(ctx context.Context, cfg *config.App, agg string) (models.Aggregator, error) {
+	a, err := models.FindOne[models.Aggregator](ctx, cfg.DBClient, models.AggregatorCollection, bson.D{{Key: "code", Value: agg}})
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		return models.Aggregator{}, fmt.Errorf("%w: %q", ErrUnknownAggregator, agg)
+	case err != nil:
+		return models.Aggregator{}, fmt.Errorf("failed to find aggregator %v: [%w]", agg, err)
+	}
+
+	return *a, nil
+}
+
+// aggregatorIntegrations return the providers enabled for the given aggregator with its own credentials and branding
+func aggregatorIntegrations(ctx context.Context, cfg *config.App, agg string) (provider.Integrations, error) {
+	a, err := findAggregator(ctx, cfg, agg)
+	if err != nil {
+		return provider.Integrations{}, err
+	}
+
+	return provider.NewForAggregator(cfg, a), nil
+}
+
+// integrationsByAggregator resolve once the providers of every aggregator owning one of the rides
+func integrationsByAggregator(ctx context.Context, cfg *config.App, rides []models.Ride) (map[string]provider.Integrations, error) {
+	res := map[string]provider.Integrations{}
+
+	for _, ride := range rides {
+		if _, ok := res[ride.Aggregator]; ok {
+			continue
+		}
+
+		integrations, err := aggregatorIntegrations(ctx, cfg, ride.Aggregator)
+		if err != nil {
+			return nil, err
+		}
+		res[ride.Aggregator] = integrations
+	}
+
+	return res, nil
+}
@@ -0,0 +1,267 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/aws/s3"
+	"vtc/business/v1/sys/invoice"
+	"vtc/business/v1/sys/provider"
+	"vtc/foundation/config"
+)
+
+// invoiceNature is the nature of the service invoiced for a ride
+const invoiceNature = "Transport de personnes"
+
+// storage save the invoices, it is created once per lambda container. The invoices are written to a local directory
+// when no bucket is configured.
+var storage struct {
+	sync.Once
+	s3.Storage
+}
+
+// invoiceStorage return the storage of the invoices
+func invoiceStorage(cfg *config.App) s3.Storage {
+	storage.Do(func() {
+		if len(cfg.Env.Invoices.Bucket) > 0 {
+			storage.Storage = s3.NewBucket(cfg.AWSSession, cfg.Env.Invoices.Bucket)
+			return
+		}
+		storage.Storage = s3.FileSystem{Dir: cfg.Env.Invoices.LocalDir}
+	})
+
+	return storage.Storage
+}
+
+// GetInvoice return a temporary link to download the invoice of a completed ride. The invoice is issued if it
+// couldn't be when the ride was completed.
+func GetInvoice(ctx context.Context, data models.GetInvoiceDTO, cfg *config.App, now time.Time) (models.InvoiceLink, error) {
+	ride, err := models.FindOne[models.Ride](ctx, cfg.DBClient, models.RideCollection, bson.D{{Key: "_id", Value: data.RideID}, {Key: "userID", Value: data.UserID}})
+	if err != nil {
+		return models.InvoiceLink{}, fmt.Errorf("ride with id %v not found: %w", data.RideID, err)
+	}
+
+	if ride.Status != provider.Completed {
+		return models.InvoiceLink{}, fmt.Errorf("ride with status %v has no invoice", ride.Status)
+	}
+
+	// a ride is only invoiced once its price is captured, the invoice bills the captured price
+	if ride.PriceStatus != models.PriceStatusCaptured && ride.PriceStatus != models.PriceStatusPartiallyCaptured {
+		return models.InvoiceLink{}, fmt.Errorf("ride with price status %v has no invoice", ride.PriceStatus)
+	}
+
+	if len(ride.Invoice.InvoiceFileOnS3) == 0 {
+		// the invoice number is saved as soon as it is taken, the upload is retried on the next call
+		if err := issueInvoice(ctx, cfg, ride, now); err != nil {
+			return models.InvoiceLink{}, err
+		}
+	}
+
+	expire := time.Duration(cfg.Env.Invoices.LinkTTLMinutes) * time.Minute
+
+	link, err := invoiceStorage(cfg).PresignGet(ctx, ride.Invoice.InvoiceFileOnS3, expire)
+	if err != nil {
+		return models.InvoiceLink{}, fmt.Errorf("failed to get link to invoice %v: [%w]", ride.Invoice.Number, err)
+	}
+
+	return models.InvoiceLink{
+		RideID:    ride.ID,
+		Number:    ride.Invoice.Number,
+		URL:       link,
+		ExpiresAt: now.Add(expire),
+	}, nil
+}
+
+// issueInvoice number the invoice of a completed ride, render it and upload it. The invoice bills the final price of
+// the ride, the part that couldn't be charged is printed as still due. The number is taken from the sequence of the
+// ride aggregator once the invoice is known to be valid, and saved on the ride in the same transaction so no number
+// is lost. A failed upload is retried with the same number. Once uploaded, only the file of the invoice is saved on
+// the ride so the concurrent updates of the ride are kept.
+func issueInvoice(ctx context.Context, cfg *config.App, ride *models.Ride, now time.Time) error {
+	a, err := models.FindOne[models.Aggregator](ctx, cfg.DBClient, models.AggregatorCollection, bson.D{{Key: "code", Value: ride.Aggregator}})
+	if err != nil {
+		return fmt.Errorf("aggregator %v not found: %w", ride.Aggregator, err)
+	}
+
+	if len(ride.Invoice.Number) == 0 {
+		u, err := models.FindOne[models.User](ctx, cfg.DBClient, models.UserCollection, bson.D{{Key: "_id", Value: ride.UserID}})
+		if err != nil {
+			return fmt.Errorf("user with id %v not found: %w", ride.UserID, err)
+		}
+
+		line := invoice.NewLine(invoiceNature, ride.DisplayPriceNumeric, float64(cfg.Env.Invoices.VATPercent))
+
+		ride.Invoice = models.Invoice{
+			Amount:      line.AmountTTC,
+			AmountHT:    line.AmountHT,
+			VATPercent:  line.VATPercent,
+			VATAmount:   line.VATAmount,
+			Outstanding: ride.Payment.OutstandingPrice,
+			Date:        now,
+			Nature:      invoiceNature,
+			To:          u.Name,
+			From:        a.Legal.CompanyName,
+			AddressTo:   billingAddress(*u),
+			CreatedAt:   now.String(),
+			UpdatedAt:   now.String(),
+		}
+
+		if err := invoiceDocument(*a, *ride).ValidateDraft(); err != nil {
+			return err
+		}
+
+		if err := numberInvoice(ctx, cfg, *a, ride, now); err != nil {
+			return err
+		}
+	}
+
+	pdf, err := invoice.Render(invoiceDocument(*a, *ride))
+	if err != nil {
+		return fmt.Errorf("failed to render invoice: %v", err)
+	}
+
+	key := fmt.Sprintf("invoices/%s/%s.pdf", a.Code, ride.Invoice.Number)
+	if err := invoiceStorage(cfg).Put(ctx, key, "application/pdf", pdf); err != nil {
+		return fmt.Errorf("failed to store invoice %v: [%w]", ride.Invoice.Number, err)
+	}
+
+	filter := bson.D{{Key: "_id", Value: ride.ID}, {Key: "invoice.number", Value: ride.Invoice.Number}}
+	fields := bson.D{
+		{Key: "invoice.invoiceFileOnS3", Value: key},
+		{Key: "invoice.updatedAt", Value: now.String()},
+	}
+
+	if _, err := models.UpdateWhere(ctx, cfg.DBClient, models.RideCollection, filter, fields); err != nil {
+		return fmt.Errorf("failed to save invoice %v: [%w]", ride.Invoice.Number, err)
+	}
+
+	ride.Invoice.InvoiceFileOnS3 = key
+	ride.Invoice.UpdatedAt = now.String()
+
+	return nil
+}
+
+// numberInvoice take the next number of the aggregator sequence and save the numbered invoice on the ride together.
+// When the ride was numbered concurrently the number is given back and the ride take the saved invoice.
+func numberInvoice(ctx context.Context, cfg *config.App, a models.Aggregator, ride *models.Ride, now time.Time) error {
+	prefix := a.Legal.InvoicePrefix
+	if len(prefix) == 0 {
+		prefix = a.Code
+	}
+
+	numbered := ride.Invoice
+	errNumbered := errors.New("invoice already numbered")
+
+	err := models.WithTransaction(ctx, cfg.DBClient, func(ctx context.Context) error {
+		seq, err := models.NextSequence(ctx, cfg.DBClient, "invoice:"+a.Code)
+		if err != nil {
+			return fmt.Errorf("failed to number invoice: [%w]", err)
+		}
+		numbered.Number = invoice.Number(prefix, now.Year(), seq)
+
+		filter := bson.D{{Key: "_id", Value: ride.ID}, {Key: "invoice.number", Value: bson.D{{Key: "$in", Value: bson.A{"", nil}}}}}
+		saved, err := models.UpdateWhere(ctx, cfg.DBClient, models.RideCollection, filter, bson.D{{Key: "invoice", Value: numbered}})
+		if err != nil {
+			return fmt.Errorf("failed to save invoice %v: [%w]", numbered.Number, err)
+		}
+		if !saved {
+			return errNumbered
+		}
+
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, errNumbered):
+		stored, err := models.FindOne[models.Ride](ctx, cfg.DBClient, models.RideCollection, bson.D{{Key: "_id", Value: ride.ID}})
+		if err != nil {
+			return fmt.Errorf("ride with id %v not found: %w", ride.ID, err)
+		}
+		ride.Invoice = stored.Invoice
+	case err != nil:
+		return err
+	default:
+		ride.Invoice = numbered
+	}
+
+	return nil
+}
+
+// invoiceDocument create the document of the invoice of the ride, it is paid on the capture of the ride price
+func invoiceDocument(a models.Aggregator, ride models.Ride) invoice.Document {
+	return invoice.Document{
+		Number:      ride.Invoice.Number,
+		Date:        ride.Invoice.Date,
+		PaidAt:      ride.Payment.CapturedAt,
+		Outstanding: ride.Invoice.Outstanding,
+		Seller: invoice.Seller{
+			Name:      a.Legal.CompanyName,
+			Address:   a.Legal.Address,
+			SIRET:     a.Legal.SIRET,
+			RCS:       a.Legal.RCS,
+			VATNumber: a.Legal.VATNumber,
+			Capital:   a.Legal.Capital,
+			Mentions:  a.Legal.Mentions,
+		},
+		Buyer: invoice.Buyer{Name: ride.Invoice.To, Address: ride.Invoice.AddressTo},
+		Lines: []invoice.Line{{
+			Description: rideDescription(ride),
+			AmountHT:    ride.Invoice.AmountHT,
+			VATPercent:  ride.Invoice.VATPercent,
+			VATAmount:   ride.Invoice.VATAmount,
+			AmountTTC:   ride.Invoice.Amount,
+		}},
+	}
+}
+
+// issueCompletedInvoice issue the invoice of a ride once its price is captured. A failure is only logged, the
+// invoice is issued again when the user ask for it.
+func issueCompletedInvoice(ctx context.Context, cfg *config.App, ride *models.Ride, now time.Time) {
+	if ride.Status != provider.Completed || len(ride.Invoice.InvoiceFileOnS3) > 0 {
+		return
+	}
+	if ride.PriceStatus != models.PriceStatusCaptured && ride.PriceStatus != models.PriceStatusPartiallyCaptured {
+		return
+	}
+
+	if err := issueInvoice(ctx, cfg, ride, now); err != nil {
+		log.Printf("ride %v: failed to issue invoice: %v", ride.ID, err)
+	}
+}
+
+// rideDescription describe the ride on its invoice
+func rideDescription(ride models.Ride) string {
+	date := ride.PickupDate
+	if date.IsZero() {
+		date = ride.Payment.Date
+	}
+
+	parts := []string{fmt.Sprintf("Course du %s", date.Format("02/01/2006 15:04"))}
+	if len(ride.StartAddress) > 0 && len(ride.EndAddress) > 0 {
+		parts = append(parts, fmt.Sprintf("%s - %s", ride.StartAddress, ride.EndAddress))
+	}
+
+	return strings.Join(parts, " : ")
+}
+
+// billingAddress return the address the invoices of the user are sent to, its home address when it saved one
+func billingAddress(u models.User) string {
+	for _, a := range u.Addresses {
+		if strings.EqualFold(a.Type, "home") {
+			return a.Address
+		}
+	}
+
+	if len(u.Addresses) > 0 {
+		return u.Addresses[0].Address
+	}
+
+	return ""
+}
@@ -0,0 +1,30 @@
+package provider_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	core "vtc/business/v1/core/provider"
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/provider"
+)
+
+func Test_GetInvoice(t *testing.T) {
+	t.Log("Given the need to only invoice the rides whose price is captured")
+	{
+		ctx := context.Background()
+		now := time.Now()
+		atTime(now)
+
+		ride := bookRide(t, provider.MySamName, provider.Completed, now)
+
+		if _, err := core.GetInvoice(ctx, models.GetInvoiceDTO{RideID: ride.ID, UserID: userID}, &cfg, now); err == nil {
+			t.Fatalf("\t%s\t Test: \tShould not invoice a ride whose price is pending", failure)
+		}
+		if saved := findRide(t, ride.ID); len(saved.Invoice.Number) != 0 {
+			t.Fatalf("\t%s\t Test: \tShould not number an invoice, receive %+v", failure, saved.Invoice)
+		}
+		t.Logf("\t%s\t Test: \tShould not invoice a ride whose price is pending", success)
+	}
+}
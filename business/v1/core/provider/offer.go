@@ -3,35 +3,62 @@ package provider
 import (
 	"context"
 	"fmt"
+	"log"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/geo"
 	"vtc/business/v1/sys/provider"
 	"vtc/business/v1/sys/validate"
 	"vtc/foundation/config"
 )
 
-// GetOffers return all offer that match the given search
-func GetOffers(ctx context.Context, data models.GetOfferDTO, cfg *config.App, agg string, now time.Time) ([]models.Offer, error) {
+// GetOffers return all offer that match the given search alongside the outcome of each asked provider
+func GetOffers(ctx context.Context, data models.GetOfferDTO, cfg *config.App, agg string, now time.Time) (models.OfferList, error) {
 	u, err := models.FindOne[models.User](ctx, cfg.DBClient, models.UserCollection, bson.D{{"_id", data.UserID}})
 	if err != nil {
-		return nil, fmt.Errorf("failed to find user %v: [%w]", data.UserID, err)
+		return models.OfferList{}, fmt.Errorf("failed to find user %v: [%w]", data.UserID, err)
+	}
+
+	integrations, err := aggregatorIntegrations(ctx, cfg, agg)
+	if err != nil {
+		return models.OfferList{}, err
 	}
 
 	startDate, isPlanned := now, false
 	if len(data.StartDate) > 0 {
 		date, err := time.Parse(time.RFC3339, data.StartDate)
 		if err != nil {
-			return nil, fmt.Errorf("invalid date format: %v", err)
+			return models.OfferList{}, fmt.Errorf("invalid date format: %v", err)
 		}
 		if diff := date.Sub(time.Now()); diff.Hours() < 2 {
-			return nil, fmt.Errorf("start date should be 2 hours in advance")
+			return models.OfferList{}, fmt.Errorf("start date should be 2 hours in advance")
 		}
 
 		startDate, isPlanned = date, true
 	}
 
+	// the route go through the stops in the order given by the user
+	points := []geo.Point{{Latitude: data.StartLatitude, Longitude: data.StartLongitude}}
+	stops := make([]models.Stop, 0, len(data.Stops))
+	for _, stop := range data.Stops {
+		points = append(points, geo.Point{Latitude: stop.Latitude, Longitude: stop.Longitude})
+		stops = append(stops, models.Stop{
+			Address:   stop.Address,
+			Latitude:  stop.Latitude,
+			Longitude: stop.Longitude,
+			Country:   stop.Country,
+			PlaceID:   stop.PlaceID,
+		})
+	}
+	points = append(points, geo.Point{Latitude: data.EndLatitude, Longitude: data.EndLongitude})
+
+	route, err := geo.RouteVia(ctx, geo.New(cfg), points...)
+	if err != nil {
+		return models.OfferList{}, fmt.Errorf("failed to compute the route: [%w]", err)
+	}
+
 	search := models.Search{
 		ID:         validate.GenerateID(),
 		UserID:     u.ID,
@@ -41,35 +68,61 @@ func GetOffers(ctx context.Context, data models.GetOfferDTO, cfg *config.App, ag
 		StartLatitude:  data.StartLatitude,
 		StartLongitude: data.StartLongitude,
 		StartCountry:   data.StartCountry,
+		StartPlaceID:   data.StartPlaceID,
 
 		EndAddress:   data.EndAddress,
 		EndLatitude:  data.EndLatitude,
 		EndLongitude: data.EndLongitude,
 		EndCountry:   data.EndCountry,
+		EndPlaceID:   data.EndPlaceID,
+
+		Stops: stops,
 
 		StartDate:      startDate,
 		AskedProvider:  data.ProviderList,
-		Distance:       data.Distance,
+		Distance:       route.Distance,
 		NbrOfPassenger: data.NbrOfPassenger,
+		Options:        data.Options,
 		IsPlanned:      isPlanned,
 
+		Duration:         route.Duration.Seconds(),
+		DistanceSource:   route.Source,
+		ClientDistance:   data.Distance,
+		DistanceMismatch: data.Distance > 0 && !geo.Matches(route.Distance, data.Distance, cfg.Env.Geo.DistanceTolerancePercent),
+
 		CreatedAt: now.String(),
 		UpdatedAt: now.String(),
 		DeletedAt: "",
 	}
 
-	offers, err := provider.New(cfg).GetOffers(ctx, provider.UserInfo{ID: u.ID}, search, now)
+	if search.DistanceMismatch {
+		log.Printf("search %v: client distance %v doesn't match the %v distance %v", search.ID, search.ClientDistance, search.DistanceSource, search.Distance)
+	}
+
+	offers, results, err := integrations.GetOffers(ctx, provider.UserInfo{ID: u.ID}, search, now)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch offer: [%w]", err)
+		return models.OfferList{}, fmt.Errorf("failed to fetch offer: [%w]", err)
 	}
 
+	filter := provider.OfferFilter{
+		VehicleTypes: data.VehicleTypes,
+		MaxPrice:     data.MaxPrice,
+		MaxETA:       data.MaxETA,
+		MinCapacity:  data.MinCapacity,
+	}
+
+	offers = provider.RankOffers(offers, filter, data.SortBy)
+	list := models.OfferList{Offers: offers, Providers: results}
+
 	if len(offers) <= 0 {
-		return offers, nil
+		return list, nil
 	}
 
+	ensureOfferIndex(ctx, cfg)
+
 	if err := models.InsertMany[models.Offer](ctx, cfg.DBClient, models.OfferCollection, offers); err != nil {
-		return nil, fmt.Errorf("failed to save offers: [%w]", err)
+		return models.OfferList{}, fmt.Errorf("failed to save offers: [%w]", err)
 	}
 
-	return offers, nil
+	return list, nil
 }
@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"go.mongodb.org/mongo-driver/bson"
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/stripe"
+	"vtc/foundation/config"
+)
+
+// applyMargin add the aggregator margin to the given provider price, the result is rounded to the cent
+func applyMargin(price float64, marginPercent int) float64 {
+	return roundPrice(price * (1 + float64(marginPercent)/100))
+}
+
+// roundPrice round the given price to the cent
+func roundPrice(price float64) float64 {
+	return math.Round(price*100) / 100
+}
+
+// captureRide capture the final price of a completed ride on its pre-authorized payment. The margin of the ride
+// aggregator is applied on the final provider price. When the final price exceed the pre-authorization, the whole
+// authorization is captured and the difference is charged off session on the same payment method. If this last charge
+// fail, the remaining amount is kept as outstanding on the payment and the ride is marked as partially captured.
+//
+// The stripe calls are idempotent and the price is only saved on a ride still pending, so a capture retried after a
+// failed save or run concurrently by a webhook and the refresher never charge the user twice. A pre-authorization
+// canceled before the capture, e.g. expired, leaves the ride pending and is reported.
+func captureRide(ctx context.Context, cfg *config.App, ride *models.Ride, providerPrice float64, now time.Time) error {
+	if ride.PriceStatus != models.PriceStatusPending {
+		return nil
+	}
+
+	if providerPrice <= 0 {
+		providerPrice = ride.ProviderPrice
+	}
+
+	agg, err := findAggregator(ctx, cfg, ride.Aggregator)
+	if err != nil {
+		return err
+	}
+
+	price := applyMargin(providerPrice, agg.MarginPercent)
+	captured := math.Min(price, ride.Payment.PreAuthPrice)
+
+	if err := stripe.CapturePayment(cfg.Env.Stripe.Key, ride.Payment.PreAuthID, captured, "capture-"+ride.ID+"-"+ride.Payment.PreAuthID); err != nil {
+		err = fmt.Errorf("ride %v: failed to capture payment %v: [%w]", ride.ID, ride.Payment.PreAuthID, err)
+		// the ride stays pending, a canceled pre-authorization is a loss the support has to follow up
+		if errors.Is(err, stripe.ErrPaymentCanceled) {
+			sentry.CaptureException(err)
+		}
+		return err
+	}
+
+	ride.ProviderPrice = providerPrice
+	ride.DisplayPriceNumeric = price
+	ride.DisplayPrice = fmt.Sprintf("%f %s", price, "€")
+	ride.PriceStatus = models.PriceStatusCaptured
+	ride.Payment.Status = string(stripe.PaymentIntentStatusSucceeded)
+	ride.Payment.CapturedPrice = captured
+	ride.Payment.CapturedAt = now
+	ride.Payment.UpdatedAt = now.String()
+
+	if supplement := roundPrice(price - captured); supplement > 0 {
+		chargeRideSupplement(ctx, cfg, ride, supplement)
+	}
+
+	return saveCapture(ctx, cfg, ride)
+}
+
+// chargeRideSupplement charge the part of the final price not covered by the pre-authorization. A failed charge is
+// reported and kept as outstanding on the payment, the ride is then partially captured.
+func chargeRideSupplement(ctx context.Context, cfg *config.App, ride *models.Ride, supplement float64) {
+	charge, err := chargeSupplement(ctx, cfg, *ride, supplement)
+	if err == nil && charge.Status != stripe.PaymentIntentStatusSucceeded {
+		err = fmt.Errorf("supplement payment %v is %v", charge.ID, charge.Status)
+	}
+
+	if err != nil {
+		err = fmt.Errorf("ride %v: failed to charge the %.2f supplement: %w", ride.ID, supplement, err)
+		log.Print(err)
+		sentry.CaptureException(err)
+
+		ride.PriceStatus = models.PriceStatusPartiallyCaptured
+		ride.Payment.OutstandingPrice = supplement
+		ride.Payment.LastError = err.Error()
+		return
+	}
+
+	ride.Payment.SupplementID = charge.ID
+	ride.Payment.SupplementPrice = supplement
+}
+
+// saveCapture save the captured price of the ride only if it is still pending. When another capture of the ride was
+// saved first, the ride is given the stored price instead.
+func saveCapture(ctx context.Context, cfg *config.App, ride *models.Ride) error {
+	filter := bson.D{{Key: "_id", Value: ride.ID}, {Key: "priceStatus", Value: models.PriceStatusPending}}
+	fields := bson.D{
+		{Key: "providerPrice", Value: ride.ProviderPrice},
+		{Key: "displayPrice", Value: ride.DisplayPrice},
+		{Key: "displayPriceNumeric", Value: ride.DisplayPriceNumeric},
+		{Key: "priceStatus", Value: ride.PriceStatus},
+		{Key: "payment", Value: ride.Payment},
+	}
+
+	saved, err := models.UpdateWhere(ctx, cfg.DBClient, models.RideCollection, filter, fields)
+	if err != nil {
+		return fmt.Errorf("failed to save the captured price of ride %v: [%w]", ride.ID, err)
+	}
+	if saved {
+		return nil
+	}
+
+	stored, err := models.FindOne[models.Ride](ctx, cfg.DBClient, models.RideCollection, bson.D{{Key: "_id", Value: ride.ID}})
+	if err != nil {
+		return fmt.Errorf("ride with id %v not found: %w", ride.ID, err)
+	}
+
+	ride.ProviderPrice = stored.ProviderPrice
+	ride.DisplayPrice = stored.DisplayPrice
+	ride.DisplayPriceNumeric = stored.DisplayPriceNumeric
+	ride.PriceStatus = stored.PriceStatus
+	ride.Payment = stored.Payment
+
+	return nil
+}
+
+// chargeSupplement charge the user for the amount of a ride not covered by the pre-authorization
+func chargeSupplement(ctx context.Context, cfg *config.App, ride models.Ride, amount float64) (stripe.Charge, error) {
+	u, err := models.FindOne[models.User](ctx, cfg.DBClient, models.UserCollection, bson.D{{Key: "_id", Value: ride.UserID}})
+	if err != nil {
+		return stripe.Charge{}, fmt.Errorf("user with id %v not found: %w", ride.UserID, err)
+	}
+
+	return stripe.ChargeOffSession(cfg.Env.Stripe.Key, amount, u.StripeID, ride.Payment.PaymentMethodID, "eur", "supplement-"+ride.ID+"-"+ride.Payment.PreAuthID)
+}
@@ -0,0 +1,358 @@
+package provider_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	stripeapi "github.com/stripe/stripe-go/v74"
+	"go.mongodb.org/mongo-driver/bson"
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/database"
+	"vtc/business/v1/sys/provider"
+	"vtc/business/v1/sys/provider/fake"
+	"vtc/foundation/config"
+)
+
+const (
+	success = "\u2713"
+	failure = "\u2717"
+)
+
+// aggregator own every ride of the tests, its margin is applied on the captured prices
+const aggregator = "core-test"
+
+var (
+	cfg      config.App
+	backend  *fake.Server
+	payments *fakeStripe
+	userID   string
+)
+
+func TestMain(m *testing.M) {
+	client, err := database.NewClient(database.Config{
+		Username:   "user",
+		Password:   "password",
+		Host:       "0.0.0.0",
+		Port:       "20000",
+		Database:   "thegoodseat_core_test",
+		SSLEnabled: false,
+	})
+	if err != nil {
+		log.Fatalf("\t%s\t Test: \tShould be able to open a new client: %v", failure, err)
+	}
+
+	// the refresher and the reservation worker go through every ride, they must only find the ones of the tests
+	if err := client.Drop(context.Background()); err != nil {
+		log.Fatalf("\t%s\t Test: \tShould be able to reset the database: %v", failure, err)
+	}
+
+	backend = fake.New(fake.Nominal)
+	providers := httptest.NewServer(backend.Handler())
+
+	payments = &fakeStripe{intents: map[string]string{}, fail: map[string]bool{}, created: map[string]string{}}
+	stripeSrv := httptest.NewServer(payments)
+	stripeapi.SetBackend(stripeapi.APIBackend, stripeapi.GetBackendWithConfig(stripeapi.APIBackend, &stripeapi.BackendConfig{
+		URL:               stripeapi.String(stripeSrv.URL),
+		MaxNetworkRetries: stripeapi.Int64(0),
+		LeveledLogger:     &stripeapi.LeveledLogger{Level: stripeapi.LevelNull},
+	}))
+
+	invoices, err := os.MkdirTemp("", "invoices")
+	if err != nil {
+		log.Fatalf("\t%s\t Test: \tShould be able to create the invoices directory: %v", failure, err)
+	}
+
+	cfg.DBClient = client
+	cfg.Env.Stripe.Key = "sk_test"
+	cfg.Env.Providers.MySam.BaseURL = providers.URL + fake.MySamPath
+	cfg.Env.Providers.Uber.BaseURL = providers.URL + fake.UberPath
+	cfg.Env.Providers.Uber.Cookie = "cookie"
+	cfg.Env.Refresh.Concurrency = 2
+	cfg.Env.Reservations.ReminderMinutes = 60
+	cfg.Env.Reservations.ConfirmationMinutes = 90
+	cfg.Env.Reservations.CheckIntervalHours = 6
+	cfg.Env.Reservations.PreAuthValidityDays = 7
+	cfg.Env.Reservations.PreAuthRenewalHours = 24
+	cfg.Env.Invoices.LocalDir = invoices
+
+	ctx := context.Background()
+
+	agg := models.Aggregator{
+		ID:            uuid.NewString(),
+		Code:          aggregator,
+		Name:          "Core Test",
+		MarginPercent: 10,
+		Providers: []models.AggregatorProvider{
+			{Name: provider.MySamName, Enabled: true},
+			{Name: provider.UberName, Enabled: true},
+		},
+	}
+	if err := models.InsertOne[models.Aggregator](ctx, client, models.AggregatorCollection, &agg); err != nil {
+		log.Fatalf("\t%s\t Test: \tShould be able to save the aggregator: %v", failure, err)
+	}
+
+	userID = uuid.NewString()
+	u := models.User{ID: userID, Name: "Jane Doe", StripeID: "cus_test", Aggregator: aggregator}
+	if err := models.InsertOne[models.User](ctx, client, models.UserCollection, &u); err != nil {
+		log.Fatalf("\t%s\t Test: \tShould be able to save the user: %v", failure, err)
+	}
+
+	code := m.Run()
+
+	providers.Close()
+	stripeSrv.Close()
+	os.RemoveAll(invoices)
+
+	os.Exit(code)
+}
+
+// search is the trip booked by the rides of the tests
+func search(now time.Time) models.Search {
+	return models.Search{
+		ID:             uuid.NewString(),
+		UserID:         userID,
+		Aggregator:     aggregator,
+		StartAddress:   "Gare de Lyon, Paris",
+		StartCountry:   "FR",
+		StartLatitude:  48.8443,
+		StartLongitude: 2.3744,
+		EndAddress:     "Tour Eiffel, Paris",
+		EndCountry:     "FR",
+		EndLatitude:    48.8584,
+		EndLongitude:   2.2945,
+		NbrOfPassenger: 1,
+		StartDate:      now,
+	}
+}
+
+// bookRide book a ride on the fake backend of the given provider and save it with the given status and a pending
+// pre-authorization of 25 €
+func bookRide(t *testing.T, name, status string, now time.Time) models.Ride {
+	var p provider.IProvider = provider.NewMySam(http.DefaultClient, &cfg)
+	if name == provider.UberName {
+		p = provider.NewUber(http.DefaultClient, &cfg)
+	}
+
+	s := search(now)
+
+	offers, err := p.GetOffers(context.Background(), provider.UserInfo{}, s, now)
+	if err != nil || len(offers) == 0 {
+		t.Fatalf("\t%s\t Test: \tShould get an offer to book, receive %v", failure, err)
+	}
+
+	info, err := p.RequestRide(context.Background(), offers[0], provider.UserInfo{}, s, now)
+	if err != nil {
+		t.Fatalf("\t%s\t Test: \tShould book a ride, receive %v", failure, err)
+	}
+
+	ride := models.Ride{
+		ID:             uuid.NewString(),
+		UserID:         userID,
+		OfferID:        offers[0].ID,
+		Aggregator:     aggregator,
+		ProviderName:   name,
+		ProviderRideID: info.Id,
+		VehicleType:    offers[0].VehicleType,
+		ProviderPrice:  info.Price,
+		Status:         status,
+		PriceStatus:    models.PriceStatusPending,
+		Search:         s,
+		PickupDate:     now,
+		Payment: models.Payment{
+			Date:            now,
+			PreAuthID:       "pi_" + uuid.NewString(),
+			PreAuthPrice:    25,
+			PaymentMethodID: "pm_test",
+			Status:          "requires_capture",
+		},
+	}
+
+	saveRide(t, &ride)
+
+	return ride
+}
+
+// saveRide save the ride of a test
+func saveRide(t *testing.T, ride *models.Ride) {
+	if err := models.InsertOne[models.Ride](context.Background(), cfg.DBClient, models.RideCollection, ride); err != nil {
+		t.Fatalf("\t%s\t Test: \tShould be able to save the ride: %v", failure, err)
+	}
+}
+
+// findRide return the ride as saved in the database
+func findRide(t *testing.T, id string) models.Ride {
+	ride, err := models.FindOne[models.Ride](context.Background(), cfg.DBClient, models.RideCollection, bson.D{{Key: "_id", Value: id}})
+	if err != nil {
+		t.Fatalf("\t%s\t Test: \tShould find the saved ride: %v", failure, err)
+	}
+
+	return *ride
+}
+
+// resetRides remove the rides of the previous tests so the workers only process the rides of the running test
+func resetRides(t *testing.T) {
+	if _, err := cfg.DBClient.Collection(string(models.RideCollection)).DeleteMany(context.Background(), bson.D{}); err != nil {
+		t.Fatalf("\t%s\t Test: \tShould be able to remove the rides: %v", failure, err)
+	}
+}
+
+// atTime move the clock of the fake providers to the given time
+func atTime(now time.Time) {
+	backend.Now = func() time.Time { return now }
+}
+
+// stripeCall is a call received by the fake stripe api
+type stripeCall struct {
+	Op       string
+	IntentID string
+	Amount   int64
+	Key      string
+}
+
+// fakeStripe answer the stripe endpoints used to settle the rides. Every call is recorded, a call can be made to
+// fail by operation ( capture, cancel, charge, preauthorize, refund ) or by operation and payment intent. A payment
+// created again with the same idempotency key is the one created the first time.
+type fakeStripe struct {
+	mu      sync.Mutex
+	intents map[string]string
+	fail    map[string]bool
+	calls   []stripeCall
+	created map[string]string
+	nextID  int
+}
+
+// reset forget the calls and the failures of the previous tests
+func (s *fakeStripe) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = nil
+	s.fail = map[string]bool{}
+}
+
+// failOn make the given operation fail, for every payment intent or only the given ones
+func (s *fakeStripe) failOn(op string, intentIDs ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(intentIDs) == 0 {
+		s.fail[op] = true
+	}
+	for _, id := range intentIDs {
+		s.fail[op+" "+id] = true
+	}
+}
+
+// cancelIntent cancel the given payment intent, as stripe does once a pre-authorization expire
+func (s *fakeStripe) cancelIntent(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.intents[id] = string(stripeapi.PaymentIntentStatusCanceled)
+}
+
+// callsOf return the successful calls of the given operation
+func (s *fakeStripe) callsOf(op string) []stripeCall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res []stripeCall
+	for _, c := range s.calls {
+		if c.Op == op {
+			res = append(res, c)
+		}
+	}
+
+	return res
+}
+
+func (s *fakeStripe) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/"), "/")
+	parts := strings.Split(path, "/")
+
+	var op, id string
+	switch {
+	case path == "refunds":
+		op, id = "refund", r.PostForm.Get("payment_intent")
+	case path == "payment_intents":
+		op = "charge"
+		if r.PostForm.Get("capture_method") == "manual" {
+			op = "preauthorize"
+		}
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		op, id = "get", parts[1]
+	case len(parts) == 3:
+		op, id = parts[2], parts[1]
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fail[op] || s.fail[op+" "+id] {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusPaymentRequired)
+		fmt.Fprint(w, `{"error": {"type": "card_error", "code": "card_declined", "message": "Your card was declined."}}`)
+		return
+	}
+
+	amount, _ := strconv.ParseInt(r.PostForm.Get("amount"), 10, 64)
+	if op == "capture" {
+		amount, _ = strconv.ParseInt(r.PostForm.Get("amount_to_capture"), 10, 64)
+	}
+
+	status := s.intents[id]
+	if len(status) == 0 {
+		status = string(stripeapi.PaymentIntentStatusRequiresCapture)
+	}
+
+	switch op {
+	case "charge", "preauthorize":
+		key := r.Header.Get("Idempotency-Key")
+		if id = s.created[key]; len(id) == 0 {
+			s.nextID++
+			id = fmt.Sprintf("pi_fake_%d", s.nextID)
+			s.created[key] = id
+		}
+		status = string(stripeapi.PaymentIntentStatusSucceeded)
+		if op == "preauthorize" {
+			status = string(stripeapi.PaymentIntentStatusRequiresCapture)
+		}
+	case "capture":
+		status = string(stripeapi.PaymentIntentStatusSucceeded)
+	case "cancel":
+		status = string(stripeapi.PaymentIntentStatusCanceled)
+	}
+
+	if op != "get" {
+		s.calls = append(s.calls, stripeCall{Op: op, IntentID: id, Amount: amount, Key: r.Header.Get("Idempotency-Key")})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if op == "refund" {
+		s.nextID++
+		fmt.Fprintf(w, `{"id": "re_fake_%d", "object": "refund", "amount": %d, "status": "succeeded", "payment_intent": %q}`, s.nextID, amount, id)
+		return
+	}
+
+	s.intents[id] = status
+	fmt.Fprintf(w, `{"id": %q, "object": "payment_intent", "amount": %d, "status": %q}`, id, amount, status)
+}
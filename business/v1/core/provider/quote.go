@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/provider"
+	"vtc/foundation/config"
+)
+
+var ErrOfferUnavailable = errors.New("the offer is no longer available from the provider")
+
+// PriceChangedError is returned when an expired offer was re-quoted at a price drifting beyond the tolerance.
+// The user must confirm the new offer before it can be booked.
+type PriceChangedError struct {
+	Offer         models.Offer `json:"offer"`
+	PreviousPrice float64      `json:"previousPrice"`
+}
+
+func (e *PriceChangedError) Error() string {
+	return fmt.Sprintf("the offer price changed from %v to %v, the new offer %v must be confirmed", e.PreviousPrice, e.Offer.ProviderPrice, e.Offer.ID)
+}
+
+// offerIndex make sure the offers ttl index is created once per lambda container
+var offerIndex struct {
+	sync.Mutex
+	created bool
+}
+
+// ensureOfferIndex create the ttl index removing the expired offers once the retention period is over. The offers
+// are kept after their expiry so they can still be re-quoted.
+func ensureOfferIndex(ctx context.Context, cfg *config.App) {
+	offerIndex.Lock()
+	defer offerIndex.Unlock()
+
+	if offerIndex.created {
+		return
+	}
+
+	retention := time.Duration(cfg.Env.Offers.RetentionHours) * time.Hour
+	if err := models.CreateTTLIndex(ctx, cfg.DBClient, models.OfferCollection, "expiresAt", retention); err != nil {
+		log.Printf("failed to create the offers ttl index: %v", err)
+		return
+	}
+
+	offerIndex.created = true
+}
+
+// requote fetch a fresh offer for the same provider product as the expired one and save it. When its price
+// drift beyond the configured tolerance a PriceChangedError carrying the fresh offer is returned.
+func requote(ctx context.Context, cfg *config.App, integrations provider.Integrations, u provider.UserInfo, of models.Offer, now time.Time) (models.Offer, error) {
+	search := of.Search
+	search.AskedProvider = []string{of.Provider}
+	if !search.IsPlanned {
+		search.StartDate = now
+	}
+
+	offers, _, err := integrations.GetOffers(ctx, u, search, now)
+	if err != nil {
+		return models.Offer{}, fmt.Errorf("failed to re-quote offer %v: [%w]", of.ID, err)
+	}
+
+	var fresh *models.Offer
+	for i := range offers {
+		if offers[i].ProviderProductID == of.ProviderProductID {
+			fresh = &offers[i]
+			break
+		}
+	}
+
+	if fresh == nil {
+		return models.Offer{}, fmt.Errorf("%w: %v %v", ErrOfferUnavailable, of.Provider, of.ProviderProductID)
+	}
+
+	if err := models.InsertOne[models.Offer](ctx, cfg.DBClient, models.OfferCollection, fresh); err != nil {
+		return models.Offer{}, fmt.Errorf("failed to save re-quoted offer: [%w]", err)
+	}
+
+	if priceDrift(of.ProviderPrice, fresh.ProviderPrice) > float64(cfg.Env.Offers.PriceTolerancePercent) {
+		return models.Offer{}, &PriceChangedError{Offer: *fresh, PreviousPrice: of.ProviderPrice}
+	}
+
+	return *fresh, nil
+}
+
+// priceDrift return the change between the two prices in percent of the previous one
+func priceDrift(previous, current float64) float64 {
+	if previous <= 0 {
+		return math.Inf(1)
+	}
+
+	return math.Abs(current-previous) / previous * 100
+}
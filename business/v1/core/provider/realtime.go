@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/aws/cognito"
+	"vtc/business/v1/sys/websocket"
+	"vtc/foundation/config"
+)
+
+// connectionIndex make sure the connections ttl index is created once per lambda container
+var connectionIndex struct {
+	sync.Mutex
+	created bool
+}
+
+// poster push the ride updates to the websocket clients, it is created once per lambda container and stay nil
+// when no websocket endpoint is configured
+var poster struct {
+	sync.Once
+	websocket.Poster
+}
+
+// Connect register the websocket connection of a user so the updates of its rides are pushed to it. The user is the
+// owner of the cognito access token of the connection, an invalid token or an unknown user is refused.
+func Connect(ctx context.Context, data models.ConnectDTO, cfg *config.App, now time.Time) error {
+	cognitoID, err := cognito.GetUsername(cfg.AWSSession, data.Token)
+	if err != nil {
+		return fmt.Errorf("failed to authenticate connection: [%w]", err)
+	}
+
+	u, err := models.FindOne[models.User](ctx, cfg.DBClient, models.UserCollection, bson.D{{Key: "cognitoID", Value: cognitoID}, {Key: "aggregator", Value: data.Aggregator}})
+	if err != nil {
+		return fmt.Errorf("user of the connection not found: %w", err)
+	}
+
+	ensureConnectionIndex(ctx, cfg)
+
+	conn := models.Connection{
+		ID:          data.ConnectionID,
+		UserID:      u.ID,
+		Aggregator:  data.Aggregator,
+		ConnectedAt: now,
+		ExpiresAt:   now.Add(time.Duration(cfg.Env.WebSocket.TTLHours) * time.Hour),
+	}
+
+	if err := models.InsertOne[models.Connection](ctx, cfg.DBClient, models.ConnectionCollection, &conn); err != nil {
+		return fmt.Errorf("failed to save connection: [%w]", err)
+	}
+
+	return nil
+}
+
+// Disconnect forget a closed websocket connection
+func Disconnect(ctx context.Context, connectionID string, cfg *config.App) error {
+	if err := models.DeleteOne[models.Connection](ctx, cfg.DBClient, models.ConnectionCollection, connectionID); err != nil {
+		return fmt.Errorf("failed to delete connection: [%w]", err)
+	}
+
+	return nil
+}
+
+// ensureConnectionIndex create the ttl index removing the connections api gateway closed without notice
+func ensureConnectionIndex(ctx context.Context, cfg *config.App) {
+	connectionIndex.Lock()
+	defer connectionIndex.Unlock()
+
+	if connectionIndex.created {
+		return
+	}
+
+	if err := models.CreateTTLIndex(ctx, cfg.DBClient, models.ConnectionCollection, "expiresAt", 0); err != nil {
+		log.Printf("failed to create the connections ttl index: %v", err)
+		return
+	}
+
+	connectionIndex.created = true
+}
+
+// updateRide save the ride and push its status, driver and ETA to the connections of its user
+func updateRide(ctx context.Context, cfg *config.App, ride *models.Ride, now time.Time) error {
+	if err := models.UpdateOne[models.Ride](ctx, cfg.DBClient, models.RideCollection, ride.ID, ride); err != nil {
+		return fmt.Errorf("failed to update ride: %v", err)
+	}
+
+	broadcastRide(ctx, cfg, *ride, now)
+	return nil
+}
+
+// broadcastRide push the ride to all the connections of its user. The push is best effort, a failure is only
+// logged.
+func broadcastRide(ctx context.Context, cfg *config.App, ride models.Ride, now time.Time) {
+	data, err := json.Marshal(models.RideUpdate{
+		Type:      models.RideUpdateType,
+		RideID:    ride.ID,
+		Status:    ride.Status,
+		Driver:    ride.Driver,
+		ETA:       ride.ETA,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		log.Printf("ride %v: failed to marshal the update: %v", ride.ID, err)
+		return
+	}
+
+	if _, err := postToUser(ctx, cfg, ride.UserID, data); err != nil {
+		log.Printf("ride %v: %v", ride.ID, err)
+	}
+}
+
+// postToUser push the message to all the connections of the user and return the number of connections reached. The
+// connections closed by the clients are forgotten.
+func postToUser(ctx context.Context, cfg *config.App, userID string, data []byte) (int, error) {
+	poster.Do(func() {
+		if len(cfg.Env.WebSocket.Endpoint) > 0 {
+			poster.Poster = websocket.NewAPIGateway(cfg.AWSSession, cfg.Env.WebSocket.Endpoint)
+		}
+	})
+	if poster.Poster == nil {
+		return 0, nil
+	}
+
+	conns, err := models.Find[models.Connection](ctx, cfg.DBClient, models.ConnectionCollection, bson.D{{Key: "userID", Value: userID}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to find the connections of user %v: %v", userID, err)
+	}
+
+	sent := 0
+	for _, conn := range conns {
+		err := poster.Post(ctx, conn.ID, data)
+		if errors.Is(err, websocket.ErrGone) {
+			if err := Disconnect(ctx, conn.ID, cfg); err != nil {
+				log.Printf("user %v: %v", userID, err)
+			}
+			continue
+		}
+		if err != nil {
+			log.Printf("user %v: %v", userID, err)
+			continue
+		}
+		sent++
+	}
+
+	return sent, nil
+}
@@ -0,0 +1,98 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/stripe"
+	"vtc/business/v1/sys/validate"
+	"vtc/foundation/config"
+)
+
+// ErrRefundExceedsCaptured is returned when a refund exceeds the amount left to refund on the ride
+var ErrRefundExceedsCaptured = models.ErrRefundExceedsCaptured
+
+// RefundRide give back all or part of the price captured for a ride. The refund is taken from the pre-authorized
+// payment first then from the supplement charged on top of it, each stripe refund is recorded on the ride. The sum
+// of the refunds can't exceed the captured amount. Only the rides of the given aggregator can be refunded.
+func RefundRide(ctx context.Context, data models.RefundRideDTO, cfg *config.App, agg string, now time.Time) (models.Ride, error) {
+	ride, err := models.FindOne[models.Ride](ctx, cfg.DBClient, models.RideCollection, bson.D{{Key: "_id", Value: data.RideID}, {Key: "aggregator", Value: agg}})
+	if err != nil {
+		return models.Ride{}, fmt.Errorf("ride with id %v not found: %w", data.RideID, err)
+	}
+
+	switch ride.PriceStatus {
+	case models.PriceStatusCaptured, models.PriceStatusPartiallyCaptured, models.PriceStatusPartiallyRefunded:
+	default:
+		return models.Ride{}, fmt.Errorf("ride with price status %v can't be refunded", ride.PriceStatus)
+	}
+
+	parts, err := ride.Payment.SplitRefund(data.Amount)
+	if err != nil {
+		return models.Ride{}, err
+	}
+
+	var refundErr error
+
+	for _, part := range parts {
+		refund, err := refundPayment(cfg, ride, part.PaymentIntentID, part.Amount, data, now)
+		if err != nil {
+			refundErr = err
+			break
+		}
+
+		ride.Payment.Refunds = append(ride.Payment.Refunds, refund)
+		ride.Payment.RefundedPrice = roundPrice(ride.Payment.RefundedPrice + refund.Amount)
+	}
+
+	// the refunds made before a failure are saved so they are never made twice
+	if ride.Payment.RefundedPrice > 0 {
+		ride.PriceStatus = models.PriceStatusPartiallyRefunded
+		if ride.Payment.Refundable() < models.RefundTolerance {
+			ride.PriceStatus = models.PriceStatusRefunded
+		}
+		ride.Payment.UpdatedAt = now.String()
+		ride.UpdatedAt = now.String()
+
+		if err := updateRide(ctx, cfg, ride, now); err != nil {
+			return models.Ride{}, err
+		}
+	}
+
+	if refundErr != nil {
+		return models.Ride{}, refundErr
+	}
+
+	return *ride, nil
+}
+
+// refundPayment refund the amount on one of the payments of the ride. The idempotency key is made of the ride and
+// of the number of refunds already recorded, a retry is answered with the same stripe refund while a concurrent
+// refund of a different amount is rejected by stripe.
+func refundPayment(cfg *config.App, ride *models.Ride, paymentID string, amount float64, data models.RefundRideDTO, now time.Time) (models.Refund, error) {
+	key := fmt.Sprintf("refund-%s-%d-%s", ride.ID, len(ride.Payment.Refunds), paymentID)
+	metadata := map[string]string{
+		"rideID":   ride.ID,
+		"reason":   data.Reason,
+		"operator": data.Operator,
+	}
+
+	r, err := stripe.RefundPayment(cfg.Env.Stripe.Key, paymentID, amount, stripe.RefundReasonRequestedByCustomer, key, metadata)
+	if err != nil {
+		return models.Refund{}, fmt.Errorf("failed to refund ride: [%w]", err)
+	}
+
+	return models.Refund{
+		ID:              validate.GenerateID(),
+		Amount:          r.Amount,
+		Reason:          data.Reason,
+		Operator:        data.Operator,
+		PaymentIntentID: paymentID,
+		StripeRefundID:  r.ID,
+		Status:          string(r.Status),
+		Date:            now,
+	}, nil
+}
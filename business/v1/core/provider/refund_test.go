@@ -0,0 +1,125 @@
+package provider_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	core "vtc/business/v1/core/provider"
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/provider"
+)
+
+// capturedRide save a completed ride with 30 € captured on its pre-authorization and a 5 € supplement
+func capturedRide(t *testing.T, now time.Time) models.Ride {
+	ride := models.Ride{
+		ID:          uuid.NewString(),
+		UserID:      userID,
+		Aggregator:  aggregator,
+		Status:      provider.Completed,
+		PriceStatus: models.PriceStatusCaptured,
+		Payment: models.Payment{
+			Date:            now,
+			PreAuthID:       "pi_" + uuid.NewString(),
+			PreAuthPrice:    30,
+			CapturedPrice:   30,
+			CapturedAt:      now,
+			SupplementID:    "pi_" + uuid.NewString(),
+			SupplementPrice: 5,
+		},
+	}
+
+	saveRide(t, &ride)
+
+	return ride
+}
+
+func Test_RefundRide(t *testing.T) {
+	t.Log("Given the need to refund a ride in several times")
+	{
+		ctx := context.Background()
+		now := time.Now()
+
+		payments.reset()
+		ride := capturedRide(t, now)
+
+		refund := models.RefundRideDTO{RideID: ride.ID, Amount: 32, Reason: "detour", Operator: "support@thegoodseat.fr"}
+
+		refunded, err := core.RefundRide(ctx, refund, &cfg, aggregator, now)
+		if err != nil {
+			t.Fatalf("\t%s\t Test: \tShould refund the ride: %v", failure, err)
+		}
+		if refunded.PriceStatus != models.PriceStatusPartiallyRefunded || refunded.Payment.RefundedPrice != 32 || len(refunded.Payment.Refunds) != 2 {
+			t.Fatalf("\t%s\t Test: \tShould refund the ride partially, receive %+v", failure, refunded.Payment)
+		}
+		t.Logf("\t%s\t Test: \tShould refund the ride partially", success)
+
+		calls := payments.callsOf("refund")
+		if len(calls) != 2 ||
+			calls[0].IntentID != ride.Payment.PreAuthID || calls[0].Amount != 3000 || calls[0].Key != "refund-"+ride.ID+"-0-"+ride.Payment.PreAuthID ||
+			calls[1].IntentID != ride.Payment.SupplementID || calls[1].Amount != 200 || calls[1].Key != "refund-"+ride.ID+"-1-"+ride.Payment.SupplementID {
+			t.Fatalf("\t%s\t Test: \tShould refund the pre-authorization first then the supplement, receive %+v", failure, calls)
+		}
+		t.Logf("\t%s\t Test: \tShould refund the pre-authorization first then the supplement", success)
+
+		saved := findRide(t, ride.ID)
+		if r := saved.Payment.Refunds[0]; r.Operator != refund.Operator || r.Reason != refund.Reason || len(r.StripeRefundID) == 0 {
+			t.Fatalf("\t%s\t Test: \tShould record the refund with its operator, receive %+v", failure, r)
+		}
+		t.Logf("\t%s\t Test: \tShould record the refund with its operator", success)
+
+		refund.Amount = 4
+		if _, err := core.RefundRide(ctx, refund, &cfg, aggregator, now); !errors.Is(err, core.ErrRefundExceedsCaptured) {
+			t.Fatalf("\t%s\t Test: \tShould reject a refund above the remaining captured amount, receive %v", failure, err)
+		}
+		if len(payments.callsOf("refund")) != 2 {
+			t.Fatalf("\t%s\t Test: \tShould not call stripe for a rejected refund", failure)
+		}
+		t.Logf("\t%s\t Test: \tShould reject a refund above the remaining captured amount", success)
+
+		refund.Amount = 0
+		refunded, err = core.RefundRide(ctx, refund, &cfg, aggregator, now)
+		if err != nil || refunded.PriceStatus != models.PriceStatusRefunded || refunded.Payment.RefundedPrice != 35 {
+			t.Fatalf("\t%s\t Test: \tShould refund the rest of the ride, receive %+v: %v", failure, refunded.Payment, err)
+		}
+		if calls := payments.callsOf("refund"); len(calls) != 3 || calls[2].IntentID != ride.Payment.SupplementID || calls[2].Amount != 300 {
+			t.Fatalf("\t%s\t Test: \tShould refund the rest of the supplement, receive %+v", failure, calls)
+		}
+		t.Logf("\t%s\t Test: \tShould refund the rest of the ride", success)
+	}
+
+	t.Log("Given the need to keep the refunds made before a failure")
+	{
+		ctx := context.Background()
+		now := time.Now()
+
+		payments.reset()
+		ride := capturedRide(t, now)
+		payments.failOn("refund", ride.Payment.SupplementID)
+
+		refund := models.RefundRideDTO{RideID: ride.ID, Reason: "ride not done", Operator: "support@thegoodseat.fr"}
+
+		if _, err := core.RefundRide(ctx, refund, &cfg, aggregator, now); err == nil {
+			t.Fatalf("\t%s\t Test: \tShould fail to refund the supplement", failure)
+		}
+
+		saved := findRide(t, ride.ID)
+		if saved.PriceStatus != models.PriceStatusPartiallyRefunded || saved.Payment.RefundedPrice != 30 || len(saved.Payment.Refunds) != 1 {
+			t.Fatalf("\t%s\t Test: \tShould save the refund of the pre-authorization, receive %+v", failure, saved.Payment)
+		}
+		t.Logf("\t%s\t Test: \tShould save the refund of the pre-authorization", success)
+	}
+
+	t.Log("Given the need to only refund the rides of the aggregator")
+	{
+		ride := capturedRide(t, time.Now())
+
+		refund := models.RefundRideDTO{RideID: ride.ID, Reason: "detour", Operator: "support@thegoodseat.fr"}
+		if _, err := core.RefundRide(context.Background(), refund, &cfg, "other", time.Now()); !errors.Is(err, models.ErrNotFound) {
+			t.Fatalf("\t%s\t Test: \tShould not find the ride of another aggregator, receive %v", failure, err)
+		}
+		t.Logf("\t%s\t Test: \tShould not find the ride of another aggregator", success)
+	}
+}
@@ -0,0 +1,346 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/notify"
+	"vtc/business/v1/sys/provider"
+	"vtc/business/v1/sys/stripe"
+	"vtc/foundation/config"
+)
+
+// notifier deliver the notifications of the users having no open websocket connection. Push and sms are not
+// supported yet, the notifications are only logged.
+var notifier notify.Notifier = notify.LogNotifier{}
+
+// ProcessReservations follow every scheduled ride until its pickup. For each ride it renews the pre-authorization
+// when it would expire before the ride, reminds the user before the pickup, checks the ride is still confirmed by its
+// provider and re-books it with another provider when the original one dropped it. It returns the number of rides
+// that were updated.
+func ProcessReservations(ctx context.Context, cfg *config.App, now time.Time) (int, error) {
+	rides, err := models.Find[models.Ride](ctx, cfg.DBClient, models.RideCollection, bson.D{{Key: "status", Value: provider.Scheduled}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to find scheduled rides: [%w]", err)
+	}
+
+	aggIntegrations, err := integrationsByAggregator(ctx, cfg, rides)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		updated int
+		errs    []error
+	)
+
+	for _, ride := range rides {
+		changed, err := processReservation(ctx, cfg, aggIntegrations[ride.Aggregator], ride, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ride %v: %w", ride.ID, err))
+		}
+		if changed {
+			updated++
+		}
+	}
+
+	if len(errs) > 0 {
+		return updated, fmt.Errorf("failed to process %d of %d reservations: %v", len(errs), len(rides), errs)
+	}
+
+	return updated, nil
+}
+
+// processReservation run all the reservation steps due for the ride and save it if it changed. The changes made
+// before a failing step are saved so they are not repeated on the next run.
+func processReservation(ctx context.Context, cfg *config.App, integrations provider.Integrations, ride models.Ride, now time.Time) (bool, error) {
+	r := cfg.Env.Reservations
+	changed := false
+
+	renewed, err := renewPreAuth(ctx, cfg, &ride, now)
+	changed = changed || renewed
+
+	until := ride.PickupDate.Sub(now)
+
+	if err == nil && until <= time.Duration(r.ReminderMinutes)*time.Minute && ride.Reservation.ReminderSentAt.IsZero() {
+		notifyUser(ctx, cfg, notify.Notification{
+			UserID:  ride.UserID,
+			RideID:  ride.ID,
+			Kind:    notify.KindReservationReminder,
+			Message: fmt.Sprintf("your ride is planned at %v", ride.PickupDate.Format(time.RFC3339)),
+			Date:    now,
+		})
+		ride.Reservation.ReminderSentAt = now
+		changed = true
+	}
+
+	// the provider is checked regularly and at every run once the pickup is close
+	inWindow := until <= time.Duration(r.ConfirmationMinutes)*time.Minute
+	due := now.Sub(ride.Reservation.LastCheckedAt) >= time.Duration(r.CheckIntervalHours)*time.Hour
+
+	if err == nil && (inWindow || due) {
+		err = checkReservation(ctx, cfg, integrations, &ride, now)
+		changed = changed || ride.Reservation.LastCheckedAt.Equal(now)
+	}
+
+	if !changed {
+		return false, err
+	}
+
+	ride.UpdatedAt = now.String()
+
+	if uErr := updateRide(ctx, cfg, &ride, now); uErr != nil {
+		return false, uErr
+	}
+
+	return true, err
+}
+
+// renewPreAuth replace the pre-authorization of the ride when it would expire before the pickup. The new one is
+// created off session on the same payment method and saved on the ride, only if the ride still hold the old one,
+// before the old one is released. The renewal is idempotent, a retry never hold the amount twice on the card.
+func renewPreAuth(ctx context.Context, cfg *config.App, ride *models.Ride, now time.Time) (bool, error) {
+	r := cfg.Env.Reservations
+
+	expiry := ride.Payment.Date.Add(time.Duration(r.PreAuthValidityDays) * 24 * time.Hour)
+	if !ride.PickupDate.After(expiry) || now.Before(expiry.Add(-time.Duration(r.PreAuthRenewalHours)*time.Hour)) {
+		return false, nil
+	}
+
+	u, err := models.FindOne[models.User](ctx, cfg.DBClient, models.UserCollection, bson.D{{Key: "_id", Value: ride.UserID}})
+	if err != nil {
+		return false, fmt.Errorf("user with id %v not found: %w", ride.UserID, err)
+	}
+
+	previous := ride.Payment.PreAuthID
+
+	charge, err := stripe.PreAuthorizeOffSession(cfg.Env.Stripe.Key, ride.Payment.PreAuthPrice, u.StripeID, ride.Payment.PaymentMethodID, "eur", "renew-"+ride.ID+"-"+previous)
+	if err != nil {
+		return false, fmt.Errorf("failed to renew pre-authorization %v: [%w]", previous, err)
+	}
+
+	if charge.Status != stripe.PaymentIntentStatusRequiresCapture {
+		return false, fmt.Errorf("failed to renew pre-authorization %v, receive status %v", previous, charge.Status)
+	}
+
+	filter := bson.D{{Key: "_id", Value: ride.ID}, {Key: "payment.preAuthID", Value: previous}}
+	fields := bson.D{
+		{Key: "payment.preAuthID", Value: charge.ID},
+		{Key: "payment.status", Value: string(charge.Status)},
+		{Key: "payment.date", Value: now},
+		{Key: "payment.updatedAt", Value: now.String()},
+		{Key: "reservation.preAuthRenewedAt", Value: now},
+	}
+
+	saved, err := models.UpdateWhere(ctx, cfg.DBClient, models.RideCollection, filter, fields)
+	if err != nil {
+		return false, fmt.Errorf("failed to save the renewed pre-authorization %v: [%w]", charge.ID, err)
+	}
+
+	// another run saved its renewal first, with the same idempotency key it holds the same pre-authorization
+	if !saved {
+		stored, err := models.FindOne[models.Ride](ctx, cfg.DBClient, models.RideCollection, bson.D{{Key: "_id", Value: ride.ID}})
+		if err != nil {
+			return false, fmt.Errorf("ride with id %v not found: %w", ride.ID, err)
+		}
+		if stored.Payment.PreAuthID != charge.ID {
+			return false, fmt.Errorf("pre-authorization %v of ride %v was replaced during its renewal", previous, ride.ID)
+		}
+	}
+
+	ride.Payment.PreAuthID = charge.ID
+	ride.Payment.Status = string(charge.Status)
+	ride.Payment.Date = now
+	ride.Payment.UpdatedAt = now.String()
+	ride.Reservation.PreAuthRenewedAt = now
+
+	if !stripe.CancelPayment(cfg.Env.Stripe.Key, previous, stripe.CancellationReasonAbandoned) {
+		log.Printf("ride %v: failed to release the previous pre-authorization %v", ride.ID, previous)
+	}
+
+	return true, nil
+}
+
+// checkReservation poll the provider of a scheduled ride. A ride dropped by its provider is re-booked, a ride with
+// an assigned driver is confirmed and a ride whose pickup date is reached is handed over to RefreshRide.
+func checkReservation(ctx context.Context, cfg *config.App, integrations provider.Integrations, ride *models.Ride, now time.Time) error {
+	info, err := integrations.GetRide(ctx, *ride)
+	if err != nil {
+		return fmt.Errorf("failed to check reservation: [%w]", err)
+	}
+
+	ride.Reservation.LastCheckedAt = now
+	machine := provider.NewStatusMachine()
+
+	switch info.Status {
+	case provider.Cancelled, provider.DriverCancelled, provider.NoDriverFound:
+		return rebookRide(ctx, cfg, integrations, ride, info.Status, now)
+	case provider.Accepted, provider.Arriving, provider.InProgress:
+		if err := machine.Transition(ride, info.Status, provider.SourceProviderPoll, now); err != nil {
+			return err
+		}
+		ride.Reservation.ConfirmedAt = now
+		ride.Driver = info.Driver
+		ride.ETA = info.ETA
+	default:
+		if !now.Before(ride.PickupDate) {
+			return machine.Transition(ride, provider.Processing, provider.SourceProviderPoll, now)
+		}
+	}
+
+	return nil
+}
+
+// rebookRide replace the provider that dropped the ride with the best value offer of the other providers, offers
+// of the same vehicle type being preferred. The offers are quoted from the search stored on the ride as its own offer
+// may already be expired and removed. When no provider can take over the ride, it takes the status given by its
+// provider and its pre-authorization is released.
+func rebookRide(ctx context.Context, cfg *config.App, integrations provider.Integrations, ride *models.Ride, reason string, now time.Time) error {
+	search, err := rideSearch(ctx, cfg, *ride)
+	if err != nil {
+		return err
+	}
+
+	u, err := models.FindOne[models.User](ctx, cfg.DBClient, models.UserCollection, bson.D{{Key: "_id", Value: ride.UserID}})
+	if err != nil {
+		return fmt.Errorf("user with id %v not found: %w", ride.UserID, err)
+	}
+
+	userInfo := provider.UserInfo{ID: u.ID, MySamID: u.MySamClientID}
+
+	search.AskedProvider = nil
+	for _, name := range integrations.Names() {
+		if name != ride.ProviderName {
+			search.AskedProvider = append(search.AskedProvider, name)
+		}
+	}
+
+	var offers []models.Offer
+	if len(search.AskedProvider) > 0 {
+		if offers, _, err = integrations.GetOffers(ctx, userInfo, search, now); err != nil {
+			return fmt.Errorf("failed to fetch replacement offers: [%w]", err)
+		}
+	}
+
+	candidates := provider.RankOffers(offers, provider.OfferFilter{VehicleTypes: []string{ride.VehicleType}}, provider.SortBestValue)
+	if len(candidates) == 0 {
+		candidates = provider.RankOffers(offers, provider.OfferFilter{}, provider.SortBestValue)
+	}
+
+	previous := ride.DisplayProviderName
+	if len(previous) == 0 {
+		previous = ride.ProviderName
+	}
+
+	for _, candidate := range candidates {
+		if err := models.InsertOne[models.Offer](ctx, cfg.DBClient, models.OfferCollection, &candidate); err != nil {
+			return fmt.Errorf("failed to save replacement offer: [%w]", err)
+		}
+
+		info, err := integrations.RequestRide(ctx, candidate, userInfo, search, now)
+		if err != nil {
+			log.Printf("ride %v: failed to re-book with %v: %v", ride.ID, candidate.Provider, err)
+			continue
+		}
+
+		ride.Reservation.Rebookings = append(ride.Reservation.Rebookings, models.Rebooking{
+			FromProvider:       ride.ProviderName,
+			FromProviderRideID: ride.ProviderRideID,
+			ToProvider:         candidate.Provider,
+			Reason:             reason,
+			Date:               now,
+		})
+
+		ride.ProviderName = candidate.Provider
+		ride.ProviderRideID = info.Id
+		ride.ProviderRideRef = info.Ref
+		ride.VehicleType = candidate.VehicleType
+		ride.DisplayProviderName = candidate.DisplayProviderName
+		ride.OfferID = candidate.ID
+		ride.ProviderPrice = info.Price
+		ride.DisplayPrice = candidate.DisplayPrice
+		ride.DisplayPriceNumeric = candidate.DisplayPriceNumeric
+		ride.Driver = info.Driver
+		ride.ETA = info.ETA
+		ride.Reservation.ConfirmedAt = time.Time{}
+
+		notifyUser(ctx, cfg, notify.Notification{
+			UserID:  ride.UserID,
+			RideID:  ride.ID,
+			Kind:    notify.KindRideRebooked,
+			Message: fmt.Sprintf("your planned ride was dropped by %v and re-booked with %v", previous, candidate.DisplayProviderName),
+			Date:    now,
+		})
+
+		return nil
+	}
+
+	if err := provider.NewStatusMachine().Transition(ride, reason, provider.SourceProviderPoll, now); err != nil {
+		return err
+	}
+
+	if stripe.CancelPayment(cfg.Env.Stripe.Key, ride.Payment.PreAuthID, stripe.CancellationReasonAbandoned) {
+		ride.Payment.Status = string(stripe.PaymentIntentStatusCanceled)
+		ride.PriceStatus = models.PriceStatusCancelled
+		ride.Payment.UpdatedAt = now.String()
+	}
+
+	notifyUser(ctx, cfg, notify.Notification{
+		UserID:  ride.UserID,
+		RideID:  ride.ID,
+		Kind:    notify.KindReservationFailed,
+		Message: "your planned ride was dropped by its provider and no other provider could take it over",
+		Date:    now,
+	})
+
+	return nil
+}
+
+// rideSearch return the trip of the ride. The rides booked before the search was kept on them fall back on the
+// search of their offer.
+func rideSearch(ctx context.Context, cfg *config.App, ride models.Ride) (models.Search, error) {
+	if len(ride.Search.ID) > 0 {
+		return ride.Search, nil
+	}
+
+	of, err := models.FindOne[models.Offer](ctx, cfg.DBClient, models.OfferCollection, bson.D{{Key: "_id", Value: ride.OfferID}})
+	if err != nil {
+		return models.Search{}, fmt.Errorf("search of ride %v not found, its offer %v is gone: %w", ride.ID, ride.OfferID, err)
+	}
+
+	return of.Search, nil
+}
+
+// notifyUser push the notification to the open websocket connections of the user. A user without connection is
+// notified through the fallback notifier. A failed delivery is only logged as it must not stop the reservation
+// process.
+func notifyUser(ctx context.Context, cfg *config.App, n notify.Notification) {
+	data, err := json.Marshal(models.RideNotification{
+		Type:    models.NotificationType,
+		RideID:  n.RideID,
+		Kind:    n.Kind,
+		Message: n.Message,
+		Date:    n.Date,
+	})
+	if err != nil {
+		log.Printf("ride %v: failed to marshal the %v notification: %v", n.RideID, n.Kind, err)
+		return
+	}
+
+	sent, err := postToUser(ctx, cfg, n.UserID, data)
+	if err != nil {
+		log.Printf("ride %v: failed to push %v notification: %v", n.RideID, n.Kind, err)
+	}
+	if sent > 0 {
+		return
+	}
+
+	if err := notifier.Notify(ctx, n); err != nil {
+		log.Printf("ride %v: failed to send %v notification: %v", n.RideID, n.Kind, err)
+	}
+}
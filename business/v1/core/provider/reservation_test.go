@@ -0,0 +1,146 @@
+package provider_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	core "vtc/business/v1/core/provider"
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/provider"
+	"vtc/business/v1/sys/provider/fake"
+)
+
+func Test_ProcessReservations(t *testing.T) {
+	t.Log("Given the need to re-book a planned ride dropped by its provider")
+	{
+		ctx := context.Background()
+		now := time.Now()
+		atTime(now)
+
+		resetRides(t)
+		payments.reset()
+		backend.SetScenario(provider.MySamName, fake.Scenario{
+			Price:       20,
+			Progression: []fake.Step{{After: 0, Status: provider.NoDriverFound}},
+		})
+		backend.SetScenario(provider.UberName, fake.Nominal)
+
+		ride := bookRide(t, provider.MySamName, provider.Scheduled, now)
+		ride.PickupDate = now.Add(2 * time.Hour)
+		if err := models.UpdateOne[models.Ride](ctx, cfg.DBClient, models.RideCollection, ride.ID, &ride); err != nil {
+			t.Fatalf("\t%s\t Test: \tShould be able to plan the ride: %v", failure, err)
+		}
+
+		updated, err := core.ProcessReservations(ctx, &cfg, now)
+		if err != nil || updated != 1 {
+			t.Fatalf("\t%s\t Test: \tShould update the ride, receive %v updates: %v", failure, updated, err)
+		}
+
+		saved := findRide(t, ride.ID)
+		if saved.Status != provider.Scheduled || saved.ProviderName != provider.UberName || saved.ProviderRideID == ride.ProviderRideID {
+			t.Fatalf("\t%s\t Test: \tShould re-book the ride with another provider, receive %+v", failure, saved)
+		}
+		if saved.VehicleType != ride.VehicleType || saved.DisplayProviderName != "Uber" {
+			t.Fatalf("\t%s\t Test: \tShould keep the vehicle type, receive %v with %v", failure, saved.VehicleType, saved.DisplayProviderName)
+		}
+		t.Logf("\t%s\t Test: \tShould re-book the ride with another provider", success)
+
+		rebookings := saved.Reservation.Rebookings
+		if len(rebookings) != 1 || rebookings[0].FromProvider != provider.MySamName || rebookings[0].ToProvider != provider.UberName || rebookings[0].Reason != provider.NoDriverFound {
+			t.Fatalf("\t%s\t Test: \tShould record the re-booking, receive %+v", failure, rebookings)
+		}
+		t.Logf("\t%s\t Test: \tShould record the re-booking", success)
+
+		created := backend.LastRequest(fake.UberPath + "/createRide")
+		legs, _ := created["tripLegs"].([]any)
+		if len(legs) != 1 {
+			t.Fatalf("\t%s\t Test: \tShould book the stored search, receive %v", failure, created)
+		}
+		pickup, _ := legs[0].(map[string]any)["pickupAddress"].(map[string]any)
+		if pickup["fullAddress"] != ride.Search.StartAddress {
+			t.Fatalf("\t%s\t Test: \tShould book the stored search, receive %v", failure, pickup)
+		}
+		t.Logf("\t%s\t Test: \tShould book the search stored on the ride", success)
+	}
+
+	t.Log("Given the need to release a planned ride no provider can take over")
+	{
+		ctx := context.Background()
+		now := time.Now()
+		atTime(now)
+
+		resetRides(t)
+		payments.reset()
+		backend.SetScenario(provider.MySamName, fake.Scenario{
+			Price:       20,
+			Progression: []fake.Step{{After: 0, Status: provider.NoDriverFound}},
+		})
+		backend.SetScenario(provider.UberName, fake.Scenarios["no_cars"])
+
+		ride := bookRide(t, provider.MySamName, provider.Scheduled, now)
+		ride.PickupDate = now.Add(2 * time.Hour)
+		if err := models.UpdateOne[models.Ride](ctx, cfg.DBClient, models.RideCollection, ride.ID, &ride); err != nil {
+			t.Fatalf("\t%s\t Test: \tShould be able to plan the ride: %v", failure, err)
+		}
+
+		if _, err := core.ProcessReservations(ctx, &cfg, now); err != nil {
+			t.Fatalf("\t%s\t Test: \tShould process the reservation: %v", failure, err)
+		}
+
+		saved := findRide(t, ride.ID)
+		if saved.Status != provider.NoDriverFound || saved.PriceStatus != models.PriceStatusCancelled || saved.ProviderName != provider.MySamName {
+			t.Fatalf("\t%s\t Test: \tShould give the ride the provider status, receive %+v", failure, saved)
+		}
+
+		cancels := payments.callsOf("cancel")
+		if len(cancels) != 1 || cancels[0].IntentID != ride.Payment.PreAuthID {
+			t.Fatalf("\t%s\t Test: \tShould release the pre-authorization, receive %+v", failure, cancels)
+		}
+		t.Logf("\t%s\t Test: \tShould release the ride no provider can take over", success)
+
+		backend.SetScenario(provider.UberName, fake.Nominal)
+	}
+
+	t.Log("Given the need to renew the pre-authorization of a ride planned after its expiry")
+	{
+		ctx := context.Background()
+		now := time.Now()
+		atTime(now)
+
+		resetRides(t)
+		payments.reset()
+		backend.SetScenario(provider.MySamName, fake.Scenario{
+			Price:       20,
+			Progression: []fake.Step{{After: 0, Status: provider.Processing}},
+		})
+
+		// the pre-authorization expire in 12 hours, within the 24 hours renewal window
+		ride := bookRide(t, provider.MySamName, provider.Scheduled, now)
+		ride.PickupDate = now.Add(48 * time.Hour)
+		ride.Payment.Date = now.Add(-7*24*time.Hour + 12*time.Hour)
+		if err := models.UpdateOne[models.Ride](ctx, cfg.DBClient, models.RideCollection, ride.ID, &ride); err != nil {
+			t.Fatalf("\t%s\t Test: \tShould be able to plan the ride: %v", failure, err)
+		}
+
+		if _, err := core.ProcessReservations(ctx, &cfg, now); err != nil {
+			t.Fatalf("\t%s\t Test: \tShould process the reservation: %v", failure, err)
+		}
+
+		holds := payments.callsOf("preauthorize")
+		if len(holds) != 1 || holds[0].Amount != 2500 || holds[0].Key != "renew-"+ride.ID+"-"+ride.Payment.PreAuthID {
+			t.Fatalf("\t%s\t Test: \tShould hold the amount again with an idempotency key, receive %+v", failure, holds)
+		}
+
+		saved := findRide(t, ride.ID)
+		if saved.Payment.PreAuthID != holds[0].IntentID || saved.Reservation.PreAuthRenewedAt.IsZero() {
+			t.Fatalf("\t%s\t Test: \tShould save the new pre-authorization, receive %+v", failure, saved.Payment)
+		}
+
+		cancels := payments.callsOf("cancel")
+		if len(cancels) != 1 || cancels[0].IntentID != ride.Payment.PreAuthID {
+			t.Fatalf("\t%s\t Test: \tShould release the previous pre-authorization, receive %+v", failure, cancels)
+		}
+		t.Logf("\t%s\t Test: \tShould replace the pre-authorization before releasing the previous one", success)
+	}
+}
@@ -0,0 +1,135 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/provider"
+	"vtc/foundation/config"
+)
+
+var (
+	ErrAlreadyReviewed = errors.New("the ride was already reviewed")
+	ErrReviewClosed    = errors.New("the ride can no longer be reviewed")
+)
+
+// ReviewRide save the review of a completed ride. A ride can only be reviewed once, within the review window
+// following its completion given by the REVIEW_WINDOW_DAYS env variable.
+func ReviewRide(ctx context.Context, data models.ReviewRideDTO, cfg *config.App, now time.Time) (models.RideReview, error) {
+	ride, err := models.FindOne[models.Ride](ctx, cfg.DBClient, models.RideCollection, bson.D{{Key: "_id", Value: data.RideID}, {Key: "userID", Value: data.UserID}})
+	if err != nil {
+		return models.RideReview{}, fmt.Errorf("ride with id %v not found: %w", data.RideID, err)
+	}
+
+	completedAt, ok := provider.StatusReachedAt(*ride, provider.Completed)
+	if ride.Status != provider.Completed || !ok {
+		return models.RideReview{}, fmt.Errorf("ride with status %v can't be reviewed", ride.Status)
+	}
+
+	if len(ride.Review.CreatedAt) > 0 {
+		return models.RideReview{}, ErrAlreadyReviewed
+	}
+
+	window := time.Duration(cfg.Env.Reviews.WindowDays) * 24 * time.Hour
+	if now.After(completedAt.Add(window)) {
+		return models.RideReview{}, fmt.Errorf("%w: completed on %v", ErrReviewClosed, completedAt.Format(time.RFC3339))
+	}
+
+	review := models.RideReview{
+		ID:           ride.ID,
+		UserID:       ride.UserID,
+		Aggregator:   ride.Aggregator,
+		ProviderName: ride.ProviderName,
+		VehicleType:  ride.VehicleType,
+		Rating:       float64(data.Rating),
+		Comment:      data.Comment,
+		Tags:         data.Tags,
+		CreatedAt:    now,
+	}
+
+	// the review id is the ride id, a concurrent review of the same ride is rejected by the database
+	if err := models.InsertOne[models.RideReview](ctx, cfg.DBClient, models.ReviewCollection, &review); err != nil {
+		if errors.Is(err, models.ErrDuplicate) {
+			return models.RideReview{}, ErrAlreadyReviewed
+		}
+		return models.RideReview{}, fmt.Errorf("failed to save review: [%w]", err)
+	}
+
+	ride.Review = models.Review{
+		Rating:    review.Rating,
+		Comment:   review.Comment,
+		Tags:      review.Tags,
+		CreatedAt: now.String(),
+		UpdatedAt: now.String(),
+	}
+	ride.UpdatedAt = now.String()
+
+	if err := models.UpdateOne[models.Ride](ctx, cfg.DBClient, models.RideCollection, ride.ID, ride); err != nil {
+		// the review is removed so the user can submit it again
+		if dErr := models.DeleteOne[models.RideReview](ctx, cfg.DBClient, models.ReviewCollection, review.ID); dErr != nil {
+			return models.RideReview{}, fmt.Errorf("failed to update ride: %v, then failed to remove review %v: %v", err, review.ID, dErr)
+		}
+		return models.RideReview{}, fmt.Errorf("failed to update ride: %v", err)
+	}
+
+	return review, nil
+}
+
+// GetRatingAverages return the average rating of the reviewed rides of the aggregator per provider and per offer
+// type. The reviews can be restricted to the rides of a provider.
+func GetRatingAverages(ctx context.Context, data models.RatingAveragesDTO, cfg *config.App, agg string) (models.RatingAverages, error) {
+	if _, err := findAggregator(ctx, cfg, agg); err != nil {
+		return models.RatingAverages{}, err
+	}
+
+	match := bson.D{{Key: "aggregator", Value: agg}}
+	if len(data.Provider) > 0 {
+		match = append(match, bson.E{Key: "providerName", Value: data.Provider})
+	}
+
+	byProvider, err := ratingAverages(ctx, cfg, match, "providerName")
+	if err != nil {
+		return models.RatingAverages{}, err
+	}
+
+	byOfferType, err := ratingAverages(ctx, cfg, match, "vehicleType")
+	if err != nil {
+		return models.RatingAverages{}, err
+	}
+
+	return models.RatingAverages{ByProvider: byProvider, ByOfferType: byOfferType}, nil
+}
+
+// ratingAverages group the reviews matching the filter on the given field and average their rating
+func ratingAverages(ctx context.Context, cfg *config.App, match bson.D, field string) ([]models.RatingAverage, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$" + field},
+			{Key: "average", Value: bson.D{{Key: "$avg", Value: "$rating"}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	}
+
+	res, err := models.Aggregate[models.RatingAverage](ctx, cfg.DBClient, models.ReviewCollection, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to average ratings by %v: [%w]", field, err)
+	}
+
+	for i := range res {
+		res[i].Average = math.Round(res[i].Average*100) / 100
+	}
+
+	if res == nil {
+		res = []models.RatingAverage{}
+	}
+
+	return res, nil
+}
@@ -3,19 +3,23 @@ package provider
 import (
 	"context"
 	"fmt"
+	"log"
+	"strings"
+	"sync"
 	"time"
 	"vtc/business/v1/sys/provider"
 	"vtc/business/v1/sys/validate"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/database"
 	"vtc/business/v1/sys/stripe"
 	"vtc/foundation/config"
 )
 
 // CreatePayment create a new payment for an offer. The created payment is not save in our database upon creation
-// but rather when the ride will get booked by the user
-func CreatePayment(ctx context.Context, data models.CreatePaymentDTO, cfg *config.App, now time.Time) (stripe.Charge, error) {
+// but rather when the ride will get booked by the user. The amount include the margin of the aggregator of the request.
+func CreatePayment(ctx context.Context, data models.CreatePaymentDTO, cfg *config.App, agg string, now time.Time) (stripe.Charge, error) {
 	u, err := models.FindOne[models.User](ctx, cfg.DBClient, models.UserCollection, bson.D{{"_id", data.UserID}})
 	if err != nil {
 		return stripe.Charge{}, fmt.Errorf("failed to find user with id: %v, [%w]", data.UserID, err)
@@ -36,7 +40,14 @@ func CreatePayment(ctx context.Context, data models.CreatePaymentDTO, cfg *confi
 		return stripe.Charge{}, fmt.Errorf("user has no valid credit card")
 	}
 
-	charge, err := stripe.CreateCharge(cfg.Env.Stripe.Key, of.ProviderPrice, u.StripeID, paymentMethod.StripeID, data.ReturnURL, "eur")
+	a, err := findAggregator(ctx, cfg, agg)
+	if err != nil {
+		return stripe.Charge{}, err
+	}
+
+	amount := applyMargin(of.ProviderPrice, a.MarginPercent)
+
+	charge, err := stripe.CreateCharge(cfg.Env.Stripe.Key, amount, u.StripeID, paymentMethod.StripeID, data.ReturnURL, "eur")
 	if err != nil {
 		return stripe.Charge{}, fmt.Errorf("failed to create a charge for given payment method and user id: %v, %v", u.StripeID, paymentMethod.ID)
 	}
@@ -44,7 +55,9 @@ func CreatePayment(ctx context.Context, data models.CreatePaymentDTO, cfg *confi
 	return charge, nil
 }
 
-func RequestRide(ctx context.Context, data models.NewRideDTO, cfg *config.App, now time.Time) (models.Ride, error) {
+// RequestRide book an offer for the user. The ride belongs to the aggregator of the request, an offer found through
+// another aggregator can't be booked.
+func RequestRide(ctx context.Context, data models.NewRideDTO, cfg *config.App, agg string, now time.Time) (models.Ride, error) {
 	u, err := models.FindOne[models.User](ctx, cfg.DBClient, models.UserCollection, bson.D{{"_id", data.UserID}})
 	if err != nil {
 		return models.Ride{}, fmt.Errorf("user with id %v not found: %w", data.UserID, err)
@@ -55,6 +68,10 @@ func RequestRide(ctx context.Context, data models.NewRideDTO, cfg *config.App, n
 		return models.Ride{}, fmt.Errorf("offer with id %v not found: %w", data.OfferID, err)
 	}
 
+	if of.Search.Aggregator != agg {
+		return models.Ride{}, fmt.Errorf("offer with id %v not found: %w", data.OfferID, models.ErrNotFound)
+	}
+
 	pi, err := stripe.GetPaymentIntent(cfg.Env.Stripe.Key, data.StripeIntentID)
 	if err != nil {
 		return models.Ride{}, fmt.Errorf("no payment with id %v found: %w", data.StripeIntentID, err)
@@ -64,7 +81,23 @@ func RequestRide(ctx context.Context, data models.NewRideDTO, cfg *config.App, n
 		return models.Ride{}, fmt.Errorf("3DS process failed, please request a new ride and change the payment_method method")
 	}
 
-	rideInfo, err := provider.New(cfg).RequestRide(ctx, *of, provider.UserInfo{ID: u.ID, MySamID: u.MySamClientID}, of.Search, now)
+	integrations, err := aggregatorIntegrations(ctx, cfg, agg)
+	if err != nil {
+		return models.Ride{}, err
+	}
+
+	userInfo := provider.UserInfo{ID: u.ID, MySamID: u.MySamClientID}
+
+	// an expired offer is transparently re-quoted, a price drifting beyond the tolerance must be confirmed again
+	if of.IsExpired(now) {
+		fresh, err := requote(ctx, cfg, integrations, userInfo, *of, now)
+		if err != nil {
+			return models.Ride{}, err
+		}
+		of = &fresh
+	}
+
+	rideInfo, err := integrations.RequestRide(ctx, *of, userInfo, of.Search, now)
 	if err != nil {
 		return models.Ride{}, fmt.Errorf("failed to request ride: [%w]", err)
 	}
@@ -73,7 +106,7 @@ func RequestRide(ctx context.Context, data models.NewRideDTO, cfg *config.App, n
 		Date:            now,
 		Status:          string(pi.Status),
 		PreAuthID:       data.StripeIntentID,
-		PreAuthPrice:    rideInfo.Price,
+		PreAuthPrice:    float64(pi.Amount) / 100,
 		PaymentMethodID: pi.PaymentMethod.ID,
 		CreatedAt:       now.String(),
 		UpdatedAt:       now.String(),
@@ -85,25 +118,45 @@ func RequestRide(ctx context.Context, data models.NewRideDTO, cfg *config.App, n
 		UserID:              u.ID,
 		OfferID:             of.ID,
 		ProviderRideID:      rideInfo.Id,
+		ProviderRideRef:     rideInfo.Ref,
+		VehicleType:         of.VehicleType,
+		DisplayProviderName: of.DisplayProviderName,
 		IsPlanned:           of.IsPlanned,
 		ETA:                 rideInfo.ETA,
 		CancellationFees:    0,
 		StartDate:           of.StartDate,
 		PaymentByTGS:        true,
-		Aggregator:          data.AggregatorCode,
-		Status:              rideInfo.Status,
+		Aggregator:          agg,
 		ProviderPrice:       rideInfo.Price,
 		DisplayPrice:        of.DisplayPrice,
 		DisplayPriceNumeric: of.DisplayPriceNumeric,
-		PriceStatus:         "pending",
+		PriceStatus:         models.PriceStatusPending,
 		Review:              models.Review{},
 		Invoice:             models.Invoice{},
 		Payment:             payment,
 		Driver:              rideInfo.Driver,
+		StartAddress:        of.Search.StartAddress,
+		EndAddress:          of.Search.EndAddress,
+		Search:              of.Search,
+		PickupDate:          of.Search.StartDate,
 		CreatedAt:           now.String(),
 		UpdatedAt:           now.String(),
 	}
 
+	// a booked ride without known status is still waiting for a driver, a planned one is scheduled until a driver
+	// is assigned or its pickup date is reached
+	status := rideInfo.Status
+	if len(status) == 0 || status == provider.Processing {
+		status = provider.Processing
+		if of.IsPlanned {
+			status = provider.Scheduled
+		}
+	}
+
+	if err := provider.NewStatusMachine().Transition(&ride, status, provider.SourceUser, now); err != nil {
+		return models.Ride{}, fmt.Errorf("invalid ride status: %w", err)
+	}
+
 	if err := models.InsertOne[models.Ride](ctx, cfg.DBClient, models.RideCollection, &ride); err != nil {
 		return models.Ride{}, fmt.Errorf("failed to save ride: %v", err)
 	}
@@ -113,9 +166,504 @@ func RequestRide(ctx context.Context, data models.NewRideDTO, cfg *config.App, n
 
 func GetRide(ctx context.Context, id string, cfg *config.App) (models.Ride, error) {
 	ride, err := models.FindOne[models.Ride](ctx, cfg.DBClient, models.RideCollection, bson.D{{"_id", id}})
-	return *ride, err
+	if err != nil {
+		return models.Ride{}, err
+	}
+
+	return *ride, nil
+}
+
+// defaultRidesLimit is the number of rides returned per page when the limit isn't given
+const defaultRidesLimit = 20
+
+// rideSummaryProjection only keep the fields of a ride shown in the ride history
+var rideSummaryProjection = bson.D{
+	{Key: "providerName", Value: 1},
+	{Key: "status", Value: 1},
+	{Key: "isPlanned", Value: 1},
+	{Key: "pickupDate", Value: 1},
+	{Key: "startAddress", Value: 1},
+	{Key: "endAddress", Value: 1},
+	{Key: "displayPrice", Value: 1},
+	{Key: "displayPriceNumeric", Value: 1},
+	{Key: "priceStatus", Value: 1},
+	{Key: "driver.driverName", Value: 1},
+	{Key: "driver.carModel", Value: 1},
+	{Key: "createdAt", Value: 1},
+}
+
+// ListRides return a page of the rides of a user sorted on their pickup date, the most recent first unless the
+// ascending sort is asked. The rides can be filtered on their status, provider, pickup date and type.
+func ListRides(ctx context.Context, data models.ListRidesDTO, cfg *config.App) (models.RidePage, error) {
+	filter := bson.D{{Key: "userID", Value: data.UserID}}
+
+	if len(data.Statuses) > 0 {
+		filter = append(filter, bson.E{Key: "status", Value: bson.D{{Key: "$in", Value: data.Statuses}}})
+	}
+
+	if len(data.Provider) > 0 {
+		filter = append(filter, bson.E{Key: "providerName", Value: data.Provider})
+	}
+
+	switch data.Type {
+	case "planned":
+		filter = append(filter, bson.E{Key: "isPlanned", Value: true})
+	case "immediate":
+		filter = append(filter, bson.E{Key: "isPlanned", Value: false})
+	}
+
+	dates := bson.D{}
+	if len(data.From) > 0 {
+		from, err := time.Parse(time.RFC3339, data.From)
+		if err != nil {
+			return models.RidePage{}, fmt.Errorf("invalid from date %v: %v", data.From, err)
+		}
+		dates = append(dates, bson.E{Key: "$gte", Value: from})
+	}
+	if len(data.To) > 0 {
+		to, err := time.Parse(time.RFC3339, data.To)
+		if err != nil {
+			return models.RidePage{}, fmt.Errorf("invalid to date %v: %v", data.To, err)
+		}
+		dates = append(dates, bson.E{Key: "$lte", Value: to})
+	}
+	if len(dates) > 0 {
+		filter = append(filter, bson.E{Key: "pickupDate", Value: dates})
+	}
+
+	limit := data.Limit
+	if limit <= 0 {
+		limit = defaultRidesLimit
+	}
+
+	page := database.Page{
+		SortField:  "pickupDate",
+		Descending: data.Sort != "asc",
+		Limit:      int64(limit),
+		Cursor:     data.Cursor,
+		Projection: rideSummaryProjection,
+	}
+
+	rides, next, err := models.FindPage[models.RideSummary](ctx, cfg.DBClient, models.RideCollection, filter, page)
+	if err != nil {
+		return models.RidePage{}, fmt.Errorf("failed to list rides of user %v: [%w]", data.UserID, err)
+	}
+
+	if rides == nil {
+		rides = []models.RideSummary{}
+	}
+
+	return models.RidePage{Rides: rides, NextCursor: next}, nil
+}
+
+// defaultBackfillBatch is the number of rides read at once by the backfill of the ride summaries
+const defaultBackfillBatch = 100
+
+// BackfillRideSummaries set the pickup date and the addresses of the rides booked before they were kept on the ride,
+// so every ride of the history can be sorted on its pickup date. It is a one-off migration going through the rides
+// by batches. They are taken from the search of the ride offer, the pickup date fall back on the ride creation date
+// once the offer is gone. Only the missing fields are set, and only with a value, so it can be run again safely. It
+// return the number of rides updated.
+func BackfillRideSummaries(ctx context.Context, cfg *config.App, batch int) (int, error) {
+	if batch <= 0 {
+		batch = defaultBackfillBatch
+	}
+
+	filter := bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "pickupDate", Value: bson.D{{Key: "$in", Value: bson.A{nil, time.Time{}}}}}},
+		bson.D{{Key: "startAddress", Value: bson.D{{Key: "$in", Value: bson.A{"", nil}}}}},
+		bson.D{{Key: "endAddress", Value: bson.D{{Key: "$in", Value: bson.A{"", nil}}}}},
+	}}}
+
+	page := database.Page{SortField: "createdAt", Limit: int64(batch)}
+	updated := 0
+
+	for {
+		rides, next, err := models.FindPage[models.Ride](ctx, cfg.DBClient, models.RideCollection, filter, page)
+		if err != nil {
+			return updated, fmt.Errorf("failed to find the rides to backfill: [%w]", err)
+		}
+
+		for _, ride := range rides {
+			saved, err := backfillRideSummary(ctx, cfg, ride)
+			if err != nil {
+				return updated, err
+			}
+			if saved {
+				updated++
+			}
+		}
+
+		if len(next) == 0 {
+			return updated, nil
+		}
+		page.Cursor = next
+	}
 }
 
-func CancelRide() {}
+// backfillRideSummary set the missing pickup date and addresses of the ride. A field is only set while it is still
+// missing, a ride updated meanwhile keeps its own values.
+func backfillRideSummary(ctx context.Context, cfg *config.App, ride models.Ride) (bool, error) {
+	filter := bson.D{{Key: "_id", Value: ride.ID}}
+	fields := bson.D{}
+
+	search, searchErr := rideSearch(ctx, cfg, ride)
+
+	if ride.PickupDate.IsZero() {
+		pickup := search.StartDate
+		if searchErr != nil || pickup.IsZero() {
+			var err error
+			if pickup, err = parseStoredDate(ride.CreatedAt); err != nil {
+				return false, fmt.Errorf("ride %v: invalid creation date: %v", ride.ID, err)
+			}
+		}
+		filter = append(filter, bson.E{Key: "pickupDate", Value: bson.D{{Key: "$in", Value: bson.A{nil, time.Time{}}}}})
+		fields = append(fields, bson.E{Key: "pickupDate", Value: pickup})
+	}
+
+	if len(ride.StartAddress) == 0 && len(search.StartAddress) > 0 {
+		filter = append(filter, bson.E{Key: "startAddress", Value: bson.D{{Key: "$in", Value: bson.A{"", nil}}}})
+		fields = append(fields, bson.E{Key: "startAddress", Value: search.StartAddress})
+	}
+
+	if len(ride.EndAddress) == 0 && len(search.EndAddress) > 0 {
+		filter = append(filter, bson.E{Key: "endAddress", Value: bson.D{{Key: "$in", Value: bson.A{"", nil}}}})
+		fields = append(fields, bson.E{Key: "endAddress", Value: search.EndAddress})
+	}
+
+	if len(fields) == 0 {
+		return false, nil
+	}
 
-func RefreshRide() {}
+	saved, err := models.UpdateWhere(ctx, cfg.DBClient, models.RideCollection, filter, fields)
+	if err != nil {
+		return false, fmt.Errorf("failed to backfill ride %v: [%w]", ride.ID, err)
+	}
+
+	return saved, nil
+}
+
+// parseStoredDate parse a date saved with time.Time.String, the monotonic clock reading it may end with is ignored
+func parseStoredDate(s string) (time.Time, error) {
+	if i := strings.Index(s, " m="); i >= 0 {
+		s = s[:i]
+	}
+
+	return time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", s)
+}
+
+// CancelRide cancel a ride booked by a user. The cancellation fees applied by the provider are captured on the
+// pre-authorized payment, if there is no fee the pre-authorization is released. The cancellation is saved before the
+// payment is settled, a failed settlement is marked on the ride and retried by cancelling the ride again.
+func CancelRide(ctx context.Context, data models.CancelRideDTO, cfg *config.App, now time.Time) (models.Ride, error) {
+	ride, err := models.FindOne[models.Ride](ctx, cfg.DBClient, models.RideCollection, bson.D{{Key: "_id", Value: data.RideID}, {Key: "userID", Value: data.UserID}})
+	if err != nil {
+		return models.Ride{}, fmt.Errorf("ride with id %v not found: %w", data.RideID, err)
+	}
+
+	// a ride already cancelled at the provider only has its payment left to settle
+	if !awaitingSettlement(*ride) {
+		if err := cancelAtProvider(ctx, cfg, ride, now); err != nil {
+			return models.Ride{}, err
+		}
+	}
+
+	if settleErr := settleCancellation(cfg, ride); settleErr != nil {
+		ride.PriceStatus = models.PriceStatusSettlementFailed
+		ride.Payment.LastError = settleErr.Error()
+		ride.Payment.UpdatedAt = now.String()
+
+		if err := updateRide(ctx, cfg, ride, now); err != nil {
+			return models.Ride{}, fmt.Errorf("%v, %v", settleErr, err)
+		}
+
+		return models.Ride{}, settleErr
+	}
+
+	ride.Payment.LastError = ""
+	ride.Payment.UpdatedAt = now.String()
+	ride.UpdatedAt = now.String()
+
+	if err := updateRide(ctx, cfg, ride, now); err != nil {
+		return models.Ride{}, err
+	}
+
+	return *ride, nil
+}
+
+// cancelledStatus list the status of a ride cancelled at the provider
+var cancelledStatus = []string{provider.Cancelled, provider.DriverCancelled, provider.OnboardCancelled}
+
+// awaitingSettlement check if the ride was cancelled at the provider but its payment wasn't settled yet
+func awaitingSettlement(ride models.Ride) bool {
+	if ride.PriceStatus == models.PriceStatusSettlementFailed {
+		return true
+	}
+
+	if ride.PriceStatus != models.PriceStatusPending {
+		return false
+	}
+
+	for _, status := range cancelledStatus {
+		if ride.Status == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cancelAtProvider cancel the ride at its provider and save the new status with the cancellation fees
+func cancelAtProvider(ctx context.Context, cfg *config.App, ride *models.Ride, now time.Time) error {
+	machine := provider.NewStatusMachine()
+	if !machine.CanTransition(ride.Status, provider.Cancelled) {
+		return fmt.Errorf("ride with status %v can't be cancelled", ride.Status)
+	}
+
+	integrations, err := aggregatorIntegrations(ctx, cfg, ride.Aggregator)
+	if err != nil {
+		return err
+	}
+
+	quote, err := integrations.GetCancellationFees(ctx, *ride, now)
+	if err != nil {
+		return fmt.Errorf("failed to get cancellation fees: [%w]", err)
+	}
+
+	rideInfo, err := integrations.CancelRide(ctx, *ride)
+	if err != nil {
+		return fmt.Errorf("failed to cancel ride: [%w]", err)
+	}
+
+	// the fees returned by the provider take precedence over the fees quoted with its policy
+	if rideInfo.CancellationFees <= 0 {
+		rideInfo.CancellationFees = quote.Amount
+	}
+
+	status := rideInfo.Status
+	if len(status) == 0 {
+		status = provider.Cancelled
+	}
+
+	if err := machine.Transition(ride, status, provider.SourceUser, now); err != nil {
+		return fmt.Errorf("invalid ride status: %w", err)
+	}
+
+	ride.CancellationFees = rideInfo.CancellationFees
+	ride.UpdatedAt = now.String()
+
+	return updateRide(ctx, cfg, ride, now)
+}
+
+// settleCancellation capture the cancellation fees of the ride on its pre-authorized payment or release it when
+// there is no fee
+func settleCancellation(cfg *config.App, ride *models.Ride) error {
+	if ride.CancellationFees > 0 {
+		if err := stripe.CapturePayment(cfg.Env.Stripe.Key, ride.Payment.PreAuthID, ride.CancellationFees, "cancellation-"+ride.ID+"-"+ride.Payment.PreAuthID); err != nil {
+			return fmt.Errorf("failed to capture cancellation fees: [%w]", err)
+		}
+		ride.Payment.Status = string(stripe.PaymentIntentStatusSucceeded)
+		ride.Payment.CapturedPrice = ride.CancellationFees
+		ride.PriceStatus = models.PriceStatusCaptured
+		return nil
+	}
+
+	if ok := stripe.CancelPayment(cfg.Env.Stripe.Key, ride.Payment.PreAuthID, stripe.CancellationReasonRequestedByCustomer); !ok {
+		return fmt.Errorf("failed to release payment %v", ride.Payment.PreAuthID)
+	}
+	ride.Payment.Status = string(stripe.PaymentIntentStatusCanceled)
+	ride.PriceStatus = models.PriceStatusCancelled
+
+	return nil
+}
+
+// GetCancellationFees quote the fees the user will pay if the ride is cancelled at the given time
+func GetCancellationFees(ctx context.Context, data models.CancellationFeesDTO, cfg *config.App, now time.Time) (models.CancellationQuote, error) {
+	ride, err := models.FindOne[models.Ride](ctx, cfg.DBClient, models.RideCollection, bson.D{{Key: "_id", Value: data.RideID}, {Key: "userID", Value: data.UserID}})
+	if err != nil {
+		return models.CancellationQuote{}, fmt.Errorf("ride with id %v not found: %w", data.RideID, err)
+	}
+
+	if !provider.NewStatusMachine().CanTransition(ride.Status, provider.Cancelled) {
+		return models.CancellationQuote{}, fmt.Errorf("ride with status %v can't be cancelled", ride.Status)
+	}
+
+	integrations, err := aggregatorIntegrations(ctx, cfg, ride.Aggregator)
+	if err != nil {
+		return models.CancellationQuote{}, err
+	}
+
+	quote, err := integrations.GetCancellationFees(ctx, *ride, now)
+	if err != nil {
+		return models.CancellationQuote{}, fmt.Errorf("failed to get cancellation fees: [%w]", err)
+	}
+
+	return quote, nil
+}
+
+// ongoingStatus list all the non-terminal ride status that can still be updated by the provider. Scheduled rides
+// are followed by ProcessReservations until a driver is assigned or their pickup date is reached.
+var ongoingStatus = bson.A{provider.Processing, provider.Accepted, provider.Arriving, provider.InProgress}
+
+// RefreshRide fetch the latest state of every ongoing ride from its provider and save the changes.
+// Providers are polled concurrently, the number of rides refreshed at the same time is bounded by the
+// RIDE_REFRESH_CONCURRENCY env variable. It returns the number of rides that were updated.
+func RefreshRide(ctx context.Context, cfg *config.App, now time.Time) (int, error) {
+	rides, err := models.Find[models.Ride](ctx, cfg.DBClient, models.RideCollection, bson.D{{Key: "status", Value: bson.D{{Key: "$in", Value: ongoingStatus}}}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to find ongoing rides: [%w]", err)
+	}
+
+	concurrency := cfg.Env.Refresh.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	aggIntegrations, err := integrationsByAggregator(ctx, cfg, rides)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		updated int
+		errs    []error
+	)
+
+	sem := make(chan struct{}, concurrency)
+
+	for _, ride := range rides {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(ride models.Ride) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			changed, err := refreshRide(ctx, cfg, aggIntegrations[ride.Aggregator], &ride, now)
+
+			mu.Lock()
+			{
+				if err != nil {
+					errs = append(errs, fmt.Errorf("ride %v: %w", ride.ID, err))
+				}
+				if changed {
+					updated++
+				}
+			}
+			mu.Unlock()
+		}(ride)
+	}
+
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return updated, fmt.Errorf("failed to refresh %d of %d rides: %v", len(errs), len(rides), errs)
+	}
+
+	return updated, nil
+}
+
+// refreshRide poll the provider for the given ride and save the status, driver and eta if they changed.
+// The new positions of the driver are added to the ride tracking.
+func refreshRide(ctx context.Context, cfg *config.App, integrations provider.Integrations, ride *models.Ride, now time.Time) (bool, error) {
+	rideInfo, err := integrations.GetRide(ctx, *ride)
+	if err != nil {
+		return false, err
+	}
+
+	ride.Tracking.LastPolledAt = now
+
+	return applyRideInfo(ctx, cfg, ride, rideInfo, provider.SourceProviderPoll, now)
+}
+
+// applyRideInfo update the ride with the latest state given by its provider and save it if it changed
+func applyRideInfo(ctx context.Context, cfg *config.App, ride *models.Ride, rideInfo models.ProviderRide, source string, now time.Time) (bool, error) {
+	changed := false
+	previous, history := ride.Status, len(ride.StatusHistory)
+
+	if len(rideInfo.Status) > 0 && rideInfo.Status != ride.Status {
+		if err := provider.NewStatusMachine().Transition(ride, rideInfo.Status, source, now); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+
+	// the ride is only saved once the payment is captured, so a failed capture is retried on the next refresh
+	if ride.Status == provider.Completed {
+		if err := captureRide(ctx, cfg, ride, rideInfo.Price, now); err != nil {
+			return false, err
+		}
+		issueCompletedInvoice(ctx, cfg, ride, now)
+	}
+
+	if rideInfo.Driver != ride.Driver {
+		ride.Driver = rideInfo.Driver
+		changed = true
+	}
+
+	position := models.Position{
+		Latitude:  rideInfo.Driver.DriverLatitude,
+		Longitude: rideInfo.Driver.DriverLongitude,
+		Heading:   rideInfo.Driver.DriverHeading,
+		Date:      now,
+	}
+	if ride.Tracking.Record(position, cfg.Env.Tracking.MaxPositions) {
+		changed = true
+	}
+
+	if rideInfo.ETA != ride.ETA {
+		ride.ETA = rideInfo.ETA
+		changed = true
+	}
+
+	if !changed {
+		return false, nil
+	}
+
+	ride.UpdatedAt = now.String()
+
+	return saveRideInfo(ctx, cfg, ride, previous, history, now)
+}
+
+// saveRideInfo save the status, driver, eta and tracking of the ride and add its new status changes to its history,
+// only while the ride still has the status it was read with. A ride updated concurrently, e.g. by a webhook or a
+// cancellation, isn't overwritten and is updated again on the next refresh. The saved ride is pushed to its user.
+func saveRideInfo(ctx context.Context, cfg *config.App, ride *models.Ride, previous string, history int, now time.Time) (bool, error) {
+	filter := bson.D{{Key: "_id", Value: ride.ID}, {Key: "status", Value: previous}}
+	fields := bson.D{
+		{Key: "status", Value: ride.Status},
+		{Key: "driver", Value: ride.Driver},
+		{Key: "ETA", Value: ride.ETA},
+		{Key: "tracking", Value: ride.Tracking},
+		{Key: "updatedAt", Value: ride.UpdatedAt},
+	}
+
+	// the history of a ride without status change may be saved as null, it is set as a whole as it can't be pushed to
+	changes := ride.StatusHistory[history:]
+	if len(changes) > 0 && history == 0 {
+		fields = append(fields, bson.E{Key: "statusHistory", Value: changes})
+	}
+
+	update := bson.D{{Key: "$set", Value: fields}}
+	if len(changes) > 0 && history > 0 {
+		update = append(update, bson.E{Key: "$push", Value: bson.D{
+			{Key: "statusHistory", Value: bson.D{{Key: "$each", Value: changes}}},
+		}})
+	}
+
+	saved, err := models.UpdateWith(ctx, cfg.DBClient, models.RideCollection, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to update ride: %v", err)
+	}
+	if !saved {
+		log.Printf("ride %v: status changed from %v while it was updated, the update is skipped", ride.ID, previous)
+		return false, nil
+	}
+
+	broadcastRide(ctx, cfg, *ride, now)
+	return true, nil
+}
@@ -0,0 +1,308 @@
+package provider_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	core "vtc/business/v1/core/provider"
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/provider"
+	"vtc/business/v1/sys/provider/fake"
+)
+
+func Test_CancelRide(t *testing.T) {
+	t.Log("Given the need to cancel a ride and settle its payment")
+	{
+		ctx := context.Background()
+		now := time.Now()
+		atTime(now)
+
+		backend.SetScenario(provider.MySamName, fake.Scenario{
+			Price:            20,
+			CancellationFees: 5,
+			Progression:      []fake.Step{{After: 0, Status: provider.Processing}},
+		})
+		payments.reset()
+
+		ride := bookRide(t, provider.MySamName, provider.Processing, now)
+
+		cancelled, err := core.CancelRide(ctx, models.CancelRideDTO{RideID: ride.ID, UserID: userID}, &cfg, now)
+		if err != nil {
+			t.Fatalf("\t%s\t Test: \tShould cancel the ride: %v", failure, err)
+		}
+		if cancelled.Status != provider.Cancelled || cancelled.CancellationFees != 5 || cancelled.PriceStatus != models.PriceStatusCaptured {
+			t.Fatalf("\t%s\t Test: \tShould cancel the ride with the provider fees, receive %+v", failure, cancelled)
+		}
+		t.Logf("\t%s\t Test: \tShould cancel the ride with the provider fees", success)
+
+		captures := payments.callsOf("capture")
+		if len(captures) != 1 || captures[0].IntentID != ride.Payment.PreAuthID || captures[0].Amount != 500 || captures[0].Key != "cancellation-"+ride.ID+"-"+ride.Payment.PreAuthID {
+			t.Fatalf("\t%s\t Test: \tShould capture the fees on the pre-authorization, receive %+v", failure, captures)
+		}
+		t.Logf("\t%s\t Test: \tShould capture the fees on the pre-authorization", success)
+
+		if saved := findRide(t, ride.ID); saved.Status != provider.Cancelled || saved.Payment.CapturedPrice != 5 {
+			t.Fatalf("\t%s\t Test: \tShould save the cancellation, receive %+v", failure, saved)
+		}
+		t.Logf("\t%s\t Test: \tShould save the cancellation", success)
+	}
+
+	t.Log("Given the need to cancel a ride without fees")
+	{
+		ctx := context.Background()
+		now := time.Now()
+		atTime(now)
+
+		backend.SetScenario(provider.MySamName, fake.Scenario{
+			Price:       20,
+			Progression: []fake.Step{{After: 0, Status: provider.Processing}},
+		})
+		payments.reset()
+
+		ride := bookRide(t, provider.MySamName, provider.Processing, now)
+
+		cancelled, err := core.CancelRide(ctx, models.CancelRideDTO{RideID: ride.ID, UserID: userID}, &cfg, now)
+		if err != nil || cancelled.PriceStatus != models.PriceStatusCancelled {
+			t.Fatalf("\t%s\t Test: \tShould cancel the ride, receive %+v: %v", failure, cancelled, err)
+		}
+
+		cancels := payments.callsOf("cancel")
+		if len(cancels) != 1 || cancels[0].IntentID != ride.Payment.PreAuthID || len(payments.callsOf("capture")) != 0 {
+			t.Fatalf("\t%s\t Test: \tShould release the pre-authorization, receive %+v", failure, cancels)
+		}
+		t.Logf("\t%s\t Test: \tShould release the pre-authorization", success)
+	}
+
+	t.Log("Given the need to retry a failed settlement")
+	{
+		ctx := context.Background()
+		now := time.Now()
+		atTime(now)
+
+		backend.SetScenario(provider.MySamName, fake.Scenario{
+			Price:            20,
+			CancellationFees: 5,
+			Progression:      []fake.Step{{After: 0, Status: provider.Processing}},
+		})
+		payments.reset()
+		payments.failOn("capture")
+
+		ride := bookRide(t, provider.MySamName, provider.Processing, now)
+
+		if _, err := core.CancelRide(ctx, models.CancelRideDTO{RideID: ride.ID, UserID: userID}, &cfg, now); err == nil {
+			t.Fatalf("\t%s\t Test: \tShould fail to settle the payment", failure)
+		}
+
+		saved := findRide(t, ride.ID)
+		if saved.Status != provider.Cancelled || saved.PriceStatus != models.PriceStatusSettlementFailed || len(saved.Payment.LastError) == 0 {
+			t.Fatalf("\t%s\t Test: \tShould save the cancellation and mark the failed settlement, receive %+v", failure, saved)
+		}
+		t.Logf("\t%s\t Test: \tShould save the cancellation and mark the failed settlement", success)
+
+		payments.reset()
+
+		cancelled, err := core.CancelRide(ctx, models.CancelRideDTO{RideID: ride.ID, UserID: userID}, &cfg, now)
+		if err != nil || cancelled.PriceStatus != models.PriceStatusCaptured || len(cancelled.Payment.LastError) != 0 {
+			t.Fatalf("\t%s\t Test: \tShould settle the payment on retry, receive %+v: %v", failure, cancelled, err)
+		}
+		if captures := payments.callsOf("capture"); len(captures) != 1 || captures[0].Amount != 500 {
+			t.Fatalf("\t%s\t Test: \tShould capture the fees once, receive %+v", failure, captures)
+		}
+		t.Logf("\t%s\t Test: \tShould settle the payment on retry", success)
+	}
+}
+
+func Test_RefreshRide(t *testing.T) {
+	t.Log("Given the need to follow an ongoing ride")
+	{
+		ctx := context.Background()
+		now := time.Now()
+		atTime(now)
+
+		resetRides(t)
+		payments.reset()
+		backend.SetScenario(provider.MySamName, fake.Scenario{
+			Price: 20,
+			Progression: []fake.Step{
+				{After: 0, Status: provider.Processing},
+				{After: time.Minute, Status: provider.Accepted},
+			},
+		})
+
+		ride := bookRide(t, provider.MySamName, provider.Processing, now)
+
+		later := now.Add(2 * time.Minute)
+		atTime(later)
+
+		updated, err := core.RefreshRide(ctx, &cfg, later)
+		if err != nil || updated != 1 {
+			t.Fatalf("\t%s\t Test: \tShould update the ride, receive %v updates: %v", failure, updated, err)
+		}
+
+		saved := findRide(t, ride.ID)
+		if saved.Status != provider.Accepted || saved.Driver.DriverName != "John Doe" || !saved.Tracking.LastPolledAt.Equal(later.Truncate(time.Millisecond)) {
+			t.Fatalf("\t%s\t Test: \tShould save the status and the driver, receive %+v", failure, saved)
+		}
+		if len(saved.StatusHistory) != 1 || saved.StatusHistory[0].Source != provider.SourceProviderPoll {
+			t.Fatalf("\t%s\t Test: \tShould record the status change, receive %+v", failure, saved.StatusHistory)
+		}
+		if saved.PriceStatus != models.PriceStatusPending || len(payments.callsOf("capture")) != 0 {
+			t.Fatalf("\t%s\t Test: \tShould not capture an ongoing ride, receive %v", failure, saved.PriceStatus)
+		}
+		t.Logf("\t%s\t Test: \tShould save the status and the driver", success)
+	}
+
+	t.Log("Given the need to capture the price of a completed ride")
+	{
+		ctx := context.Background()
+		now := time.Now()
+		atTime(now)
+
+		resetRides(t)
+		payments.reset()
+		backend.SetScenario(provider.MySamName, fake.Scenario{
+			Price: 30,
+			Progression: []fake.Step{
+				{After: 0, Status: provider.InProgress},
+				{After: 10 * time.Minute, Status: provider.Completed},
+			},
+		})
+
+		ride := bookRide(t, provider.MySamName, provider.InProgress, now)
+
+		later := now.Add(15 * time.Minute)
+		atTime(later)
+
+		if _, err := core.RefreshRide(ctx, &cfg, later); err != nil {
+			t.Fatalf("\t%s\t Test: \tShould refresh the ride: %v", failure, err)
+		}
+
+		saved := findRide(t, ride.ID)
+		if saved.Status != provider.Completed || saved.PriceStatus != models.PriceStatusCaptured || saved.ProviderPrice != 30 || saved.DisplayPriceNumeric != 33 {
+			t.Fatalf("\t%s\t Test: \tShould capture the final price with the aggregator margin, receive %+v", failure, saved)
+		}
+		t.Logf("\t%s\t Test: \tShould capture the final price with the aggregator margin", success)
+
+		captures := payments.callsOf("capture")
+		if len(captures) != 1 || captures[0].Amount != 2500 || captures[0].Key != "capture-"+ride.ID+"-"+ride.Payment.PreAuthID {
+			t.Fatalf("\t%s\t Test: \tShould capture the whole pre-authorization, receive %+v", failure, captures)
+		}
+		t.Logf("\t%s\t Test: \tShould capture the whole pre-authorization", success)
+
+		charges := payments.callsOf("charge")
+		if len(charges) != 1 || charges[0].Amount != 800 || charges[0].Key != "supplement-"+ride.ID+"-"+ride.Payment.PreAuthID {
+			t.Fatalf("\t%s\t Test: \tShould charge the supplement off session, receive %+v", failure, charges)
+		}
+		if saved.Payment.CapturedPrice != 25 || saved.Payment.SupplementPrice != 8 || saved.Payment.SupplementID != charges[0].IntentID || saved.Payment.CapturedAt.IsZero() {
+			t.Fatalf("\t%s\t Test: \tShould save the captured amounts, receive %+v", failure, saved.Payment)
+		}
+		t.Logf("\t%s\t Test: \tShould charge the supplement off session", success)
+	}
+
+	t.Log("Given the need to keep the supplement that couldn't be charged")
+	{
+		ctx := context.Background()
+		now := time.Now()
+		atTime(now)
+
+		resetRides(t)
+		payments.reset()
+		payments.failOn("charge")
+		backend.SetScenario(provider.MySamName, fake.Scenario{
+			Price: 30,
+			Progression: []fake.Step{
+				{After: 0, Status: provider.InProgress},
+				{After: 10 * time.Minute, Status: provider.Completed},
+			},
+		})
+
+		ride := bookRide(t, provider.MySamName, provider.InProgress, now)
+
+		later := now.Add(15 * time.Minute)
+		atTime(later)
+
+		if _, err := core.RefreshRide(ctx, &cfg, later); err != nil {
+			t.Fatalf("\t%s\t Test: \tShould refresh the ride: %v", failure, err)
+		}
+
+		saved := findRide(t, ride.ID)
+		if saved.PriceStatus != models.PriceStatusPartiallyCaptured || saved.Payment.CapturedPrice != 25 || saved.Payment.OutstandingPrice != 8 || len(saved.Payment.LastError) == 0 {
+			t.Fatalf("\t%s\t Test: \tShould keep the supplement as outstanding, receive %+v", failure, saved.Payment)
+		}
+		t.Logf("\t%s\t Test: \tShould keep the supplement as outstanding", success)
+	}
+
+	t.Log("Given the need to keep a ride whose pre-authorization expired pending")
+	{
+		ctx := context.Background()
+		now := time.Now()
+		atTime(now)
+
+		resetRides(t)
+		payments.reset()
+		backend.SetScenario(provider.MySamName, fake.Scenario{
+			Price: 20,
+			Progression: []fake.Step{
+				{After: 0, Status: provider.InProgress},
+				{After: 10 * time.Minute, Status: provider.Completed},
+			},
+		})
+
+		ride := bookRide(t, provider.MySamName, provider.InProgress, now)
+		payments.cancelIntent(ride.Payment.PreAuthID)
+
+		later := now.Add(15 * time.Minute)
+		atTime(later)
+
+		if _, err := core.RefreshRide(ctx, &cfg, later); err == nil {
+			t.Fatalf("\t%s\t Test: \tShould fail to capture the canceled pre-authorization", failure)
+		}
+
+		saved := findRide(t, ride.ID)
+		if saved.PriceStatus != models.PriceStatusPending || saved.Payment.CapturedPrice != 0 || !saved.Payment.CapturedAt.IsZero() || len(payments.callsOf("capture")) != 0 {
+			t.Fatalf("\t%s\t Test: \tShould keep the ride pending, receive %+v", failure, saved.Payment)
+		}
+		t.Logf("\t%s\t Test: \tShould keep the ride pending", success)
+	}
+}
+
+func Test_BackfillRideSummaries(t *testing.T) {
+	t.Log("Given the need to backfill the pickup date and the addresses of the old rides")
+	{
+		ctx := context.Background()
+		now := time.Now()
+
+		resetRides(t)
+
+		s := search(now.Add(-time.Hour))
+		old := models.Ride{ID: uuid.NewString(), UserID: userID, Aggregator: aggregator, Status: provider.Completed, Search: s, CreatedAt: now.String()}
+		saveRide(t, &old)
+
+		// the offer of this ride is gone, its pickup date is kept and it has no address to take
+		planned := models.Ride{ID: uuid.NewString(), UserID: userID, Aggregator: aggregator, OfferID: uuid.NewString(), Status: provider.Completed, PickupDate: now, CreatedAt: now.String()}
+		saveRide(t, &planned)
+
+		updated, err := core.BackfillRideSummaries(ctx, &cfg, 1)
+		if err != nil || updated != 1 {
+			t.Fatalf("\t%s\t Test: \tShould backfill the ride missing its summary, receive %v updates: %v", failure, updated, err)
+		}
+
+		saved := findRide(t, old.ID)
+		if !saved.PickupDate.Equal(s.StartDate.Truncate(time.Millisecond)) || saved.StartAddress != s.StartAddress || saved.EndAddress != s.EndAddress {
+			t.Fatalf("\t%s\t Test: \tShould take the summary from the search of the ride, receive %+v", failure, saved)
+		}
+		t.Logf("\t%s\t Test: \tShould take the summary from the search of the ride", success)
+
+		if saved := findRide(t, planned.ID); !saved.PickupDate.Equal(now.Truncate(time.Millisecond)) {
+			t.Fatalf("\t%s\t Test: \tShould keep the pickup date of the ride, receive %v", failure, saved.PickupDate)
+		}
+		t.Logf("\t%s\t Test: \tShould keep the pickup date of the ride", success)
+
+		if updated, err := core.BackfillRideSummaries(ctx, &cfg, 1); err != nil || updated != 0 {
+			t.Fatalf("\t%s\t Test: \tShould not update the rides again, receive %v updates: %v", failure, updated, err)
+		}
+		t.Logf("\t%s\t Test: \tShould not update the rides again", success)
+	}
+}
@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/provider"
+	"vtc/foundation/config"
+)
+
+// trackedStatus list the ride status during which a driver is on the way or on board
+var trackedStatus = map[string]bool{provider.Accepted: true, provider.Arriving: true, provider.InProgress: true}
+
+// TrackRide return the latest location of the driver of a ride alongside its recent path. While a driver is
+// assigned the provider is polled on demand, the last poll being reused for TRACKING_CACHE_SECONDS to protect
+// the provider rate limits. When the provider can't be polled, the last known location is returned and flagged as
+// stale, its UpdatedAt telling the client how old it is.
+func TrackRide(ctx context.Context, data models.TrackRideDTO, cfg *config.App, now time.Time) (models.RideTracking, error) {
+	ride, err := models.FindOne[models.Ride](ctx, cfg.DBClient, models.RideCollection, bson.D{{Key: "_id", Value: data.RideID}, {Key: "userID", Value: data.UserID}})
+	if err != nil {
+		return models.RideTracking{}, fmt.Errorf("ride with id %v not found: %w", data.RideID, err)
+	}
+
+	cache := time.Duration(cfg.Env.Tracking.CacheSeconds) * time.Second
+
+	stale := false
+
+	if trackedStatus[ride.Status] && now.Sub(ride.Tracking.LastPolledAt) >= cache {
+		polled, err := pollRide(ctx, cfg, *ride, now)
+		if err != nil {
+			log.Printf("ride %v: failed to poll the provider, returning the last known location: %v", ride.ID, err)
+			stale = true
+		} else {
+			ride = &polled
+		}
+	}
+
+	tracking := models.RideTracking{
+		RideID:    ride.ID,
+		Status:    ride.Status,
+		Latitude:  ride.Driver.DriverLatitude,
+		Longitude: ride.Driver.DriverLongitude,
+		Heading:   ride.Driver.DriverHeading,
+		ETA:       ride.ETA,
+		UpdatedAt: ride.Tracking.LastPolledAt,
+		Path:      ride.Tracking.Positions,
+		Stale:     stale,
+	}
+
+	// rides tracked before the poll date was recorded report the date of their last known position
+	if n := len(ride.Tracking.Positions); n > 0 && ride.Tracking.LastPolledAt.IsZero() {
+		tracking.UpdatedAt = ride.Tracking.Positions[n-1].Date
+	}
+
+	return tracking, nil
+}
+
+// pollRide refresh the ride from its provider and return it. The given ride is left untouched when the poll fails.
+func pollRide(ctx context.Context, cfg *config.App, ride models.Ride, now time.Time) (models.Ride, error) {
+	integrations, err := aggregatorIntegrations(ctx, cfg, ride.Aggregator)
+	if err != nil {
+		return models.Ride{}, err
+	}
+
+	changed, err := refreshRide(ctx, cfg, integrations, &ride, now)
+	if err != nil {
+		return models.Ride{}, fmt.Errorf("failed to refresh ride: [%w]", err)
+	}
+
+	// the poll date is saved even when nothing changed so the cache is shared by all the callers
+	if !changed {
+		if err := models.UpdateOne[models.Ride](ctx, cfg.DBClient, models.RideCollection, ride.ID, &ride); err != nil {
+			return models.Ride{}, fmt.Errorf("failed to update ride: %v", err)
+		}
+	}
+
+	return ride, nil
+}
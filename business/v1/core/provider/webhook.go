@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/provider"
+	"vtc/foundation/config"
+)
+
+// webhookIndex make sure the webhook deliveries ttl index is created once per lambda container
+var webhookIndex struct {
+	sync.Mutex
+	created bool
+}
+
+// HandleWebhook apply the ride status change pushed by a provider. The deliveries are deduplicated by event id,
+// a redelivered event is acknowledged without being applied again. A delivery that failed to be applied is
+// forgotten so the retry of the provider is applied.
+func HandleWebhook(ctx context.Context, cfg *config.App, name string, headers map[string]string, body []byte, now time.Time) error {
+	ev, err := provider.ParseWebhook(cfg, name, headers, body)
+	if err != nil {
+		return err
+	}
+
+	ensureWebhookIndex(ctx, cfg)
+
+	delivery := models.WebhookDelivery{
+		ID:         fmt.Sprintf("%v:%v", name, ev.ID),
+		Provider:   name,
+		EventID:    ev.ID,
+		RideRef:    ev.RideRef,
+		Status:     ev.Status,
+		ReceivedAt: now,
+		ExpiresAt:  now.Add(time.Duration(cfg.Env.Webhooks.RetentionDays) * 24 * time.Hour),
+	}
+
+	if err := models.InsertOne[models.WebhookDelivery](ctx, cfg.DBClient, models.WebhookCollection, &delivery); err != nil {
+		if errors.Is(err, models.ErrDuplicate) {
+			log.Printf("ignoring the redelivered %v event %v", name, ev.ID)
+			return nil
+		}
+		return fmt.Errorf("failed to save webhook delivery: [%w]", err)
+	}
+
+	if err := applyWebhook(ctx, cfg, name, ev, now); err != nil {
+		if dErr := models.DeleteOne[models.WebhookDelivery](ctx, cfg.DBClient, models.WebhookCollection, delivery.ID); dErr != nil {
+			log.Printf("failed to forget the webhook delivery %v: %v", delivery.ID, dErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// applyWebhook update the ride the event is about. The driver, eta and price are fetched from the provider as
+// the events only carry the status, the status of the event is kept unless it arrived out of order.
+func applyWebhook(ctx context.Context, cfg *config.App, name string, ev provider.WebhookEvent, now time.Time) error {
+	// the rides booked before the provider reference was saved are identified by their provider ride id
+	filter := bson.D{
+		{Key: "providerName", Value: name},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "providerRideRef", Value: ev.RideRef}},
+			bson.D{{Key: "providerRideID", Value: ev.RideRef}},
+		}},
+	}
+
+	ride, err := models.FindOne[models.Ride](ctx, cfg.DBClient, models.RideCollection, filter)
+	if err != nil {
+		return fmt.Errorf("%v ride %v not found: %w", name, ev.RideRef, err)
+	}
+
+	integrations, err := aggregatorIntegrations(ctx, cfg, ride.Aggregator)
+	if err != nil {
+		return err
+	}
+
+	rideInfo, err := integrations.GetRide(ctx, *ride)
+	if err != nil {
+		// the final price is needed to capture the payment of a completed ride, the provider retry is awaited
+		if ev.Status == provider.Completed {
+			return fmt.Errorf("failed to get the completed ride: [%w]", err)
+		}
+		rideInfo = models.ProviderRide{Price: ride.ProviderPrice, ETA: ride.ETA, Driver: ride.Driver}
+	}
+
+	rideInfo.Status = ev.Status
+	if ev.Status != ride.Status && !provider.NewStatusMachine().CanTransition(ride.Status, ev.Status) {
+		log.Printf("ride %v: ignoring the out of order %v status received while %v", ride.ID, ev.Status, ride.Status)
+		rideInfo.Status = ""
+	}
+
+	ride.Tracking.LastPolledAt = now
+
+	if _, err := applyRideInfo(ctx, cfg, ride, rideInfo, provider.SourceWebhook, now); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ensureWebhookIndex create the ttl index removing the webhook deliveries once the retention period is over
+func ensureWebhookIndex(ctx context.Context, cfg *config.App) {
+	webhookIndex.Lock()
+	defer webhookIndex.Unlock()
+
+	if webhookIndex.created {
+		return
+	}
+
+	if err := models.CreateTTLIndex(ctx, cfg.DBClient, models.WebhookCollection, "expiresAt", 0); err != nil {
+		log.Printf("failed to create the webhook deliveries ttl index: %v", err)
+		return
+	}
+
+	webhookIndex.created = true
+}
@@ -0,0 +1,46 @@
+package models
+
+// Aggregator represent a white-label client of the api, identified by the aggregator header of each request
+type Aggregator struct {
+	ID   string `bson:"_id" json:"id"`
+	Code string `bson:"code" json:"code"`
+	Name string `bson:"name" json:"name"`
+	// MarginPercent is the margin taken by the aggregator on top of the provider price
+	MarginPercent int                  `bson:"marginPercent" json:"marginPercent"`
+	Providers     []AggregatorProvider `bson:"providers" json:"providers"`
+	Legal         LegalMentions        `bson:"legal" json:"legal"`
+	CreatedAt     string               `bson:"createdAt" json:"createdAt"`
+	UpdatedAt     string               `bson:"updatedAt" json:"updatedAt"`
+	DeletedAt     string               `bson:"deletedAt" json:"deletedAt"`
+}
+
+// AggregatorProvider represent the configuration of a provider for an aggregator. Empty credentials and overrides
+// fall back on the default provider configuration.
+type AggregatorProvider struct {
+	Name        string              `bson:"name" json:"name"`
+	Enabled     bool                `bson:"enabled" json:"enabled"`
+	DisplayName string              `bson:"displayName" json:"displayName"`
+	LogoURL     string              `bson:"logoURL" json:"logoURL"`
+	Credentials ProviderCredentials `bson:"credentials" json:"-"`
+	// Coverage is a GeoJSON document restricting the area where the provider is offered to the aggregator users
+	Coverage string `bson:"coverage" json:"coverage,omitempty"`
+}
+
+// ProviderCredentials represent the secrets used to call a provider api
+type ProviderCredentials struct {
+	APIKey string `bson:"apiKey"`
+	Cookie string `bson:"cookie"`
+}
+
+// LegalMentions represent the legal identity of an aggregator printed on the invoices it issues
+type LegalMentions struct {
+	CompanyName string `bson:"companyName" json:"companyName"`
+	Address     string `bson:"address" json:"address"`
+	SIRET       string `bson:"siret" json:"siret"`
+	RCS         string `bson:"rcs" json:"rcs"`
+	VATNumber   string `bson:"vatNumber" json:"vatNumber"`
+	Capital     string `bson:"capital" json:"capital"`
+	// InvoicePrefix start the number of the invoices, the aggregator code is used when it is empty
+	InvoicePrefix string   `bson:"invoicePrefix" json:"invoicePrefix"`
+	Mentions      []string `bson:"mentions" json:"mentions"`
+}
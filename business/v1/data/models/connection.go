@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// List of values that RideUpdate.Type can take
+const (
+	RideUpdateType   = "ride_update"
+	NotificationType = "notification"
+)
+
+// Connection represent a websocket connection opened by a user to receive the updates of its rides
+type Connection struct {
+	ID          string    `json:"id" bson:"_id"`
+	UserID      string    `json:"userID" bson:"userID"`
+	Aggregator  string    `json:"aggregator" bson:"aggregator"`
+	ConnectedAt time.Time `json:"connectedAt" bson:"connectedAt"`
+	// ExpiresAt is the date past which api gateway has closed the connection, it is removed by a ttl index
+	ExpiresAt time.Time `json:"expiresAt" bson:"expiresAt"`
+}
+
+// RideUpdate is the message pushed to the connected users when one of their rides changed
+type RideUpdate struct {
+	Type      string    `json:"type"`
+	RideID    string    `json:"rideID"`
+	Status    string    `json:"status"`
+	Driver    Driver    `json:"driver"`
+	ETA       float64   `json:"ETA"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// RideNotification is the message pushed to the connected users to notify them about one of their rides
+type RideNotification struct {
+	Type    string    `json:"type"`
+	RideID  string    `json:"rideID"`
+	Kind    string    `json:"kind"`
+	Message string    `json:"message"`
+	Date    time.Time `json:"date"`
+}
+
+// ConnectDTO register a new websocket connection, the user is identified by the access token given on login
+type ConnectDTO struct {
+	ConnectionID string `json:"connectionID" validate:"required"`
+	Token        string `json:"token" validate:"required"`
+	Aggregator   string `json:"aggregator"`
+}
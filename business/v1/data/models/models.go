@@ -2,7 +2,9 @@ package models
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -12,9 +14,21 @@ import (
 type Collection string
 
 const (
-	UserCollection  Collection = "user"
-	RideCollection  Collection = "ride"
-	OfferCollection Collection = "offer"
+	UserCollection       Collection = "user"
+	RideCollection       Collection = "ride"
+	OfferCollection      Collection = "offer"
+	AggregatorCollection Collection = "aggregator"
+	ConnectionCollection Collection = "connection"
+	WebhookCollection    Collection = "webhook"
+	CounterCollection    Collection = "counter"
+	ReviewCollection     Collection = "review"
+)
+
+var (
+	// ErrNotFound is returned when no document match the given filter
+	ErrNotFound = errors.New("document not found")
+	// ErrDuplicate is returned when a document with the same id already exists
+	ErrDuplicate = errors.New("document already exists")
 )
 
 func Find[T any](ctx context.Context, client *mongo.Database, collectionName Collection, filter bson.D) ([]T, error) {
@@ -26,18 +40,47 @@ func Find[T any](ctx context.Context, client *mongo.Database, collectionName Col
 	return res, nil
 }
 
+// FindPage return a page of the documents matching the filter and the cursor of the next page
+func FindPage[T any](ctx context.Context, client *mongo.Database, collectionName Collection, filter bson.D, page database.Page) ([]T, string, error) {
+	res, next, err := database.FindPage[T](ctx, client, string(collectionName), filter, page)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidCursor) {
+			return nil, "", fmt.Errorf("failed to find page of %v: %w", collectionName, err)
+		}
+		return nil, "", fmt.Errorf("failed to find page of %v: %v", collectionName, err)
+	}
+
+	return res, next, nil
+}
+
 func FindOne[T any](ctx context.Context, client *mongo.Database, collectionName Collection, filter bson.D) (*T, error) {
 	var u T
 
 	if err := database.FindOne[T](ctx, client, string(collectionName), filter, &u); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("failed to find one %v: %w", collectionName, ErrNotFound)
+		}
 		return nil, fmt.Errorf("failed to find one %v: %v", collectionName, err)
 	}
 
 	return &u, nil
 }
 
+// Aggregate run the aggregation pipeline on the collection
+func Aggregate[T any](ctx context.Context, client *mongo.Database, collectionName Collection, pipeline mongo.Pipeline) ([]T, error) {
+	res, err := database.Aggregate[T](ctx, client, string(collectionName), pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate %v: %v", collectionName, err)
+	}
+
+	return res, nil
+}
+
 func InsertOne[T any](ctx context.Context, client *mongo.Database, collectionName Collection, u *T) error {
 	if err := database.InsertOne[T](ctx, client, string(collectionName), u); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("failed to insert one %v: %w", collectionName, ErrDuplicate)
+		}
 		return fmt.Errorf("failed to insert one %v: %v", collectionName, err)
 	}
 
@@ -60,6 +103,27 @@ func UpdateOne[T any](ctx context.Context, client *mongo.Database, collectionNam
 	return nil
 }
 
+// UpdateWhere set the given fields on the document matching the filter, it return false when no document matched
+func UpdateWhere[T any](ctx context.Context, client *mongo.Database, collectionName Collection, filter bson.D, u T) (bool, error) {
+	ok, err := database.UpdateOneWhere[T](ctx, client, string(collectionName), filter, u)
+	if err != nil {
+		return false, fmt.Errorf("failed to update %v: %v", collectionName, err)
+	}
+
+	return ok, nil
+}
+
+// UpdateWith apply the given update operators on the document matching the filter, it return false when no document
+// matched
+func UpdateWith(ctx context.Context, client *mongo.Database, collectionName Collection, filter, update bson.D) (bool, error) {
+	ok, err := database.UpdateOneWith(ctx, client, string(collectionName), filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to update %v: %v", collectionName, err)
+	}
+
+	return ok, nil
+}
+
 func DeleteOne[T any](ctx context.Context, client *mongo.Database, collectionName Collection, id string) error {
 	if err := database.DeleteOne(ctx, client, string(collectionName), id); err != nil {
 		return fmt.Errorf("failed to delete %v: %v", collectionName, err)
@@ -67,3 +131,26 @@ func DeleteOne[T any](ctx context.Context, client *mongo.Database, collectionNam
 
 	return nil
 }
+
+func CreateTTLIndex(ctx context.Context, client *mongo.Database, collectionName Collection, field string, expireAfter time.Duration) error {
+	if err := database.CreateTTLIndex(ctx, client, string(collectionName), field, expireAfter); err != nil {
+		return fmt.Errorf("failed to index %v: %v", collectionName, err)
+	}
+
+	return nil
+}
+
+// WithTransaction run fn in a transaction, the writes made with the context given to fn are committed together
+func WithTransaction(ctx context.Context, client *mongo.Database, fn func(ctx context.Context) error) error {
+	return database.WithTransaction(ctx, client, fn)
+}
+
+// NextSequence return the next value of the named sequence, the values of a sequence are consecutive
+func NextSequence(ctx context.Context, client *mongo.Database, name string) (int64, error) {
+	seq, err := database.Increment(ctx, client, string(CounterCollection), name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get next value of sequence %v: %v", name, err)
+	}
+
+	return seq, nil
+}
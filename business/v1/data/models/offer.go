@@ -24,6 +24,37 @@ type Offer struct {
 	CreatedAt           string  `bson:"createdAt" json:"createdAt"`
 	UpdatedAt           string  `bson:"updatedAt" json:"updatedAt"`
 	DeletedAt           string  `bson:"deletedAt" json:"deletedAt"`
+
+	// Capacity is the number of passengers the vehicle can carry, 0 when unknown
+	Capacity int `json:"capacity" bson:"capacity"`
+	// Recommended flag the offer giving the best value among the returned ones
+	Recommended bool `json:"recommended" bson:"recommended"`
+
+	// ProviderProductID identify the provider product of the offer, it is stable across quotes unlike ProviderOfferID
+	ProviderProductID string `json:"providerProductID" bson:"providerProductID"`
+
+	// ExpiresAt is the end of the validity window of the provider price, past this date the offer must be re-quoted
+	ExpiresAt time.Time `json:"expiresAt" bson:"expiresAt"`
+}
+
+// IsExpired check if the offer price is no longer guaranteed by the provider
+func (o Offer) IsExpired(now time.Time) bool {
+	return !now.Before(o.ExpiresAt)
+}
+
+// ProviderResult represent the outcome of the offer request made to a provider
+type ProviderResult struct {
+	Provider      string `json:"provider"`
+	Success       bool   `json:"success"`
+	ErrorCategory string `json:"errorCategory,omitempty"`
+	LatencyMs     int64  `json:"latencyMs"`
+	OfferCount    int    `json:"offerCount"`
+}
+
+// OfferList represent the offers found for a search alongside the outcome of each asked provider
+type OfferList struct {
+	Offers    []Offer          `json:"offers"`
+	Providers []ProviderResult `json:"providers"`
 }
 
 // Search represent a search make to fetch offer make by a user
@@ -39,21 +70,61 @@ type Search struct {
 	StartLatitude  float64 `json:"startLatitude" bson:"startLatitude"`
 	StartLongitude float64 `json:"startLongitude" bson:"startLongitude"`
 	StartCountry   string  `json:"startCountry" bson:"startCountry"`
+	StartPlaceID   string  `json:"startPlaceID" bson:"startPlaceID"`
 
 	EndAddress   string  `json:"endAddress" bson:"endAddress"`
 	EndLatitude  float64 `json:"endLatitude" bson:"endLatitude"`
 	EndLongitude float64 `json:"endLongitude" bson:"endLongitude"`
 	EndCountry   string  `json:"endCountry" bson:"endCountry"`
+	EndPlaceID   string  `json:"endPlaceID" bson:"endPlaceID"`
+
+	// Stops are the ordered intermediate points of the trip between the start and the end
+	Stops []Stop `json:"stops,omitempty" bson:"stops,omitempty"`
 
 	Distance       float64 `json:"distance" bson:"distance"`
 	NbrOfPassenger int     `json:"nbrOfPassenger" bson:"nbrOfPassenger"`
-	IsPlanned      bool    `json:"isPlanned" bson:"isPlanned"`
+	// Options are the passengers needs every offer of the search must honour
+	Options   PassengerOptions `json:"options" bson:"options"`
+	IsPlanned bool             `json:"isPlanned" bson:"isPlanned"`
+
+	// The Distance, in meters, and the Duration, in seconds, of the route are computed server side. The distance
+	// sent by the client is only kept to be cross-checked.
+	Duration         float64 `json:"duration" bson:"duration"`
+	DistanceSource   string  `json:"distanceSource" bson:"distanceSource"`
+	ClientDistance   float64 `json:"clientDistance" bson:"clientDistance"`
+	DistanceMismatch bool    `json:"distanceMismatch" bson:"distanceMismatch"`
 
 	CreatedAt string `json:"createdAt" bson:"createdAt"`
 	UpdatedAt string `json:"updatedAt" bson:"updatedAt"`
 	DeletedAt string `json:"deletedAt" bson:"deletedAt"`
 }
 
+// PassengerOptions represent the needs of the passengers, the vehicle must honour them
+type PassengerOptions struct {
+	WheelchairAccess bool `json:"wheelchairAccess" bson:"wheelchairAccess"`
+	ChildSeats       int  `json:"childSeats" bson:"childSeats" validate:"gte=0,lte=4"`
+	Luggage          int  `json:"luggage" bson:"luggage" validate:"gte=0,lte=10"`
+	Pet              bool `json:"pet" bson:"pet"`
+}
+
+// Stop represent an intermediate point of a trip
+type Stop struct {
+	Address   string  `json:"address" bson:"address"`
+	Latitude  float64 `json:"latitude" bson:"latitude"`
+	Longitude float64 `json:"longitude" bson:"longitude"`
+	Country   string  `json:"country" bson:"country"`
+	PlaceID   string  `json:"placeID" bson:"placeID"`
+}
+
+// StopDTO define an intermediate point of the searched trip
+type StopDTO struct {
+	Address   string  `json:"address" validate:"required"`
+	Latitude  float64 `json:"latitude" validate:"required,latitude"`
+	Longitude float64 `json:"longitude" validate:"required,longitude"`
+	Country   string  `json:"country" validate:"required"`
+	PlaceID   string  `json:"placeID,omitempty"`
+}
+
 // GetOfferDTO define all data needed to fetch offer from providers
 type GetOfferDTO struct {
 	UserID    string `json:"userID" validate:"required"`
@@ -63,13 +134,29 @@ type GetOfferDTO struct {
 	StartLatitude  float64 `json:"startLatitude" validate:"required,latitude"`
 	StartLongitude float64 `json:"startLongitude" validate:"required,longitude"`
 	StartCountry   string  `json:"startCountry" validate:"required"`
+	StartPlaceID   string  `json:"startPlaceID,omitempty"`
 
 	EndAddress   string  `json:"endAddress" validate:"required"`
 	EndLatitude  float64 `json:"endLatitude" validate:"required,latitude"`
 	EndLongitude float64 `json:"endLongitude" validate:"required,longitude"`
 	EndCountry   string  `json:"endCountry" validate:"required"`
+	EndPlaceID   string  `json:"endPlaceID,omitempty"`
+
+	// Stops are visited in the given order, providers unable to handle them are not asked
+	Stops []StopDTO `json:"stops,omitempty" validate:"omitempty,max=5,dive"`
 
-	Distance       float64  `json:"distance" validate:"required"`
+	Distance       float64  `json:"distance,omitempty" validate:"omitempty,gte=0"`
 	NbrOfPassenger int      `json:"nbrOfPassenger" validate:"required"`
 	ProviderList   []string `json:"providerList" validate:"required"`
+
+	// Options filter out the offers whose vehicle can't take the passengers
+	Options PassengerOptions `json:"options,omitempty"`
+
+	// SortBy order the offers, by default the best value come first
+	SortBy string `json:"sortBy,omitempty" validate:"omitempty,oneof=cheapest fastest best_value greenest"`
+
+	VehicleTypes []string `json:"vehicleTypes,omitempty" validate:"omitempty,dive,oneof=eco van 2-wheels e-scooter green shared business access"`
+	MaxPrice     float64  `json:"maxPrice,omitempty" validate:"omitempty,gt=0"`
+	MaxETA       float64  `json:"maxETA,omitempty" validate:"omitempty,gt=0"`
+	MinCapacity  int      `json:"minCapacity,omitempty" validate:"omitempty,gt=0"`
 }
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var ErrRefundExceedsCaptured = errors.New("the refund exceeds the captured amount")
+
+// RefundTolerance absorb the float rounding when comparing amounts in euros
+const RefundTolerance = 0.005
+
+// RefundPart is the amount of a refund taken from one of the payments of a ride
+type RefundPart struct {
+	PaymentIntentID string
+	Amount          float64
+}
+
+// RefundedOn return the sum of the refunds already made on the given payment
+func (p Payment) RefundedOn(paymentIntentID string) float64 {
+	var sum float64
+	for _, r := range p.Refunds {
+		if r.PaymentIntentID == paymentIntentID {
+			sum += r.Amount
+		}
+	}
+
+	return roundCents(sum)
+}
+
+// Refundable return the captured amount that wasn't refunded yet
+func (p Payment) Refundable() float64 {
+	return roundCents(p.CapturedPrice + p.SupplementPrice - p.RefundedPrice)
+}
+
+// SplitRefund split a refund of the given amount between the captured pre-authorization and the supplement, the
+// pre-authorization being refunded first. The whole refundable amount is refunded when the amount is 0. A refund
+// exceeding the refundable amount is rejected.
+func (p Payment) SplitRefund(amount float64) ([]RefundPart, error) {
+	refundable := p.Refundable()
+	if refundable < RefundTolerance {
+		return nil, fmt.Errorf("%w: nothing left to refund", ErrRefundExceedsCaptured)
+	}
+
+	amount = roundCents(amount)
+	if amount == 0 {
+		amount = refundable
+	}
+	if amount > refundable+RefundTolerance {
+		return nil, fmt.Errorf("%w: %v requested, %v refundable", ErrRefundExceedsCaptured, amount, refundable)
+	}
+
+	payments := []struct {
+		id       string
+		captured float64
+	}{
+		{p.PreAuthID, p.CapturedPrice},
+		{p.SupplementID, p.SupplementPrice},
+	}
+
+	var parts []RefundPart
+	remaining := amount
+
+	for _, payment := range payments {
+		part := roundCents(math.Min(remaining, payment.captured-p.RefundedOn(payment.id)))
+		if len(payment.id) == 0 || part < RefundTolerance {
+			continue
+		}
+
+		parts = append(parts, RefundPart{PaymentIntentID: payment.id, Amount: part})
+		remaining = roundCents(remaining - part)
+
+		if remaining < RefundTolerance {
+			break
+		}
+	}
+
+	return parts, nil
+}
+
+// roundCents round the given amount to the cent
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
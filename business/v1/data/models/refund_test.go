@@ -0,0 +1,162 @@
+package models_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"vtc/business/v1/data/models"
+)
+
+const (
+	success = "\u2713"
+	failure = "\u2717"
+)
+
+func Test_RefundedOn(t *testing.T) {
+	t.Log("Given the need to know what was already refunded on a payment")
+	{
+		p := models.Payment{
+			Refunds: []models.Refund{
+				{PaymentIntentID: "pi_preauth", Amount: 10.1},
+				{PaymentIntentID: "pi_supplement", Amount: 3},
+				{PaymentIntentID: "pi_preauth", Amount: 0.2},
+			},
+		}
+
+		tests := []struct {
+			name string
+			id   string
+			want float64
+		}{
+			{name: "pre-authorization", id: "pi_preauth", want: 10.3},
+			{name: "supplement", id: "pi_supplement", want: 3},
+			{name: "unknown payment", id: "pi_other", want: 0},
+		}
+
+		for _, tt := range tests {
+			if got := p.RefundedOn(tt.id); got != tt.want {
+				t.Fatalf("\t%s\t Test: \tShould sum the refunds of the %s: got %v, want %v", failure, tt.name, got, tt.want)
+			}
+		}
+		t.Logf("\t%s\t Test: \tShould sum the refunds of each payment rounded to the cent", success)
+	}
+}
+
+func Test_SplitRefund(t *testing.T) {
+	t.Log("Given the need to split a refund between the pre-authorization and the supplement")
+	{
+		captured := models.Payment{
+			PreAuthID:       "pi_preauth",
+			CapturedPrice:   30,
+			SupplementID:    "pi_supplement",
+			SupplementPrice: 5.5,
+		}
+
+		partiallyRefunded := captured
+		partiallyRefunded.RefundedPrice = 31
+		partiallyRefunded.Refunds = []models.Refund{
+			{PaymentIntentID: "pi_preauth", Amount: 30},
+			{PaymentIntentID: "pi_supplement", Amount: 1},
+		}
+
+		noSupplement := models.Payment{PreAuthID: "pi_preauth", CapturedPrice: 0.1 + 0.2}
+
+		tests := []struct {
+			name    string
+			payment models.Payment
+			amount  float64
+			want    []models.RefundPart
+		}{
+			{
+				name:    "refund the whole captured amount when no amount is given",
+				payment: captured,
+				amount:  0,
+				want:    []models.RefundPart{{PaymentIntentID: "pi_preauth", Amount: 30}, {PaymentIntentID: "pi_supplement", Amount: 5.5}},
+			},
+			{
+				name:    "take a partial refund from the pre-authorization first",
+				payment: captured,
+				amount:  12.34,
+				want:    []models.RefundPart{{PaymentIntentID: "pi_preauth", Amount: 12.34}},
+			},
+			{
+				name:    "take the rest of the refund from the supplement",
+				payment: captured,
+				amount:  32,
+				want:    []models.RefundPart{{PaymentIntentID: "pi_preauth", Amount: 30}, {PaymentIntentID: "pi_supplement", Amount: 2}},
+			},
+			{
+				name:    "skip the payments already refunded",
+				payment: partiallyRefunded,
+				amount:  4.5,
+				want:    []models.RefundPart{{PaymentIntentID: "pi_supplement", Amount: 4.5}},
+			},
+			{
+				name:    "absorb the float rounding of the captured amount",
+				payment: noSupplement,
+				amount:  0.3,
+				want:    []models.RefundPart{{PaymentIntentID: "pi_preauth", Amount: 0.3}},
+			},
+			{
+				name:    "accept an amount within the tolerance of the refundable amount",
+				payment: captured,
+				amount:  35.504,
+				want:    []models.RefundPart{{PaymentIntentID: "pi_preauth", Amount: 30}, {PaymentIntentID: "pi_supplement", Amount: 5.5}},
+			},
+		}
+
+		for _, tt := range tests {
+			got, err := tt.payment.SplitRefund(tt.amount)
+			if err != nil {
+				t.Fatalf("\t%s\t Test: \tShould %s: %v", failure, tt.name, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("\t%s\t Test: \tShould %s: got %+v, want %+v", failure, tt.name, got, tt.want)
+			}
+			t.Logf("\t%s\t Test: \tShould %s", success, tt.name)
+		}
+	}
+}
+
+func Test_SplitRefundRejected(t *testing.T) {
+	t.Log("Given the need to never refund more than the captured amount")
+	{
+		captured := models.Payment{
+			PreAuthID:       "pi_preauth",
+			CapturedPrice:   30,
+			SupplementID:    "pi_supplement",
+			SupplementPrice: 5.5,
+		}
+
+		refunded := captured
+		refunded.RefundedPrice = 35.5
+		refunded.Refunds = []models.Refund{
+			{PaymentIntentID: "pi_preauth", Amount: 30},
+			{PaymentIntentID: "pi_supplement", Amount: 5.5},
+		}
+
+		partiallyRefunded := captured
+		partiallyRefunded.RefundedPrice = 30
+		partiallyRefunded.Refunds = []models.Refund{{PaymentIntentID: "pi_preauth", Amount: 30}}
+
+		tests := []struct {
+			name    string
+			payment models.Payment
+			amount  float64
+		}{
+			{name: "reject an amount above the captured amount", payment: captured, amount: 35.51},
+			{name: "reject an amount above what is left to refund", payment: partiallyRefunded, amount: 6},
+			{name: "reject any refund once everything was refunded", payment: refunded, amount: 0},
+			{name: "reject a refund when nothing was captured", payment: models.Payment{PreAuthID: "pi_preauth"}, amount: 1},
+		}
+
+		for _, tt := range tests {
+			parts, err := tt.payment.SplitRefund(tt.amount)
+			if !errors.Is(err, models.ErrRefundExceedsCaptured) {
+				t.Fatalf("\t%s\t Test: \tShould %s: got %+v, %v", failure, tt.name, parts, err)
+			}
+			t.Logf("\t%s\t Test: \tShould %s", success, tt.name)
+		}
+	}
+}
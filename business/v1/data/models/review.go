@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// List of values that Review.Tags can take
+const (
+	ReviewTagCleanliness     = "cleanliness"
+	ReviewTagPunctuality     = "punctuality"
+	ReviewTagDriverBehaviour = "driver_behaviour"
+)
+
+// RideReview represent the review of a ride, it is kept apart from the ride to compute the rating averages. Its id is
+// the ride id so a ride can only be reviewed once.
+type RideReview struct {
+	ID           string    `json:"id" bson:"_id"`
+	UserID       string    `json:"userID" bson:"userID"`
+	Aggregator   string    `json:"aggregator" bson:"aggregator"`
+	ProviderName string    `json:"providerName" bson:"providerName"`
+	VehicleType  string    `json:"vehicleType" bson:"vehicleType"`
+	Rating       float64   `json:"rating" bson:"rating"`
+	Comment      string    `json:"comment" bson:"comment"`
+	Tags         []string  `json:"tags" bson:"tags"`
+	CreatedAt    time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// RatingAverage represent the average rating of the rides sharing the same provider or offer type
+type RatingAverage struct {
+	Key     string  `json:"key" bson:"_id"`
+	Average float64 `json:"average" bson:"average"`
+	Count   int     `json:"count" bson:"count"`
+}
+
+// RatingAverages represent the average ratings of the rides per provider and per offer type
+type RatingAverages struct {
+	ByProvider  []RatingAverage `json:"byProvider"`
+	ByOfferType []RatingAverage `json:"byOfferType"`
+}
+
+// ReviewRideDTO rate a completed ride
+type ReviewRideDTO struct {
+	RideID  string   `json:"rideID" validate:"required,uuid"`
+	UserID  string   `json:"userID" validate:"required,uuid"`
+	Rating  int      `json:"rating" validate:"required,min=1,max=5"`
+	Comment string   `json:"comment" validate:"omitempty,max=1000"`
+	Tags    []string `json:"tags" validate:"omitempty,max=3,unique,dive,oneof=cleanliness punctuality driver_behaviour"`
+}
+
+// RatingAveragesDTO fetch the rating averages of an aggregator, optionally restricted to the rides of a provider
+type RatingAveragesDTO struct {
+	Provider string `json:"provider"`
+}
@@ -2,6 +2,18 @@ package models
 
 import "time"
 
+// List of values that Ride.PriceStatus can take
+const (
+	PriceStatusPending           = "pending"
+	PriceStatusCaptured          = "captured"
+	PriceStatusPartiallyCaptured = "partially_captured"
+	PriceStatusCancelled         = "cancelled"
+	// PriceStatusSettlementFailed mark a cancelled ride whose fees couldn't be captured nor its payment released
+	PriceStatusSettlementFailed  = "settlement_failed"
+	PriceStatusRefunded          = "refunded"
+	PriceStatusPartiallyRefunded = "partially_refunded"
+)
+
 // Ride represent a tgs ride order by a user
 type Ride struct {
 	ID             string `json:"id" bson:"_id"`
@@ -9,6 +21,12 @@ type Ride struct {
 	OfferID        string `json:"offerID" bson:"offerID"`
 	ProviderRideID string `json:"providerRideID" bson:"providerRideID"`
 	ProviderName   string `json:"providerName" bson:"providerName"`
+	// ProviderRideRef is the provider own reference of the ride, it may differ from ProviderRideID that can carry
+	// more metadata
+	ProviderRideRef string `json:"providerRideRef" bson:"providerRideRef"`
+	VehicleType     string `json:"vehicleType" bson:"vehicleType"`
+	// DisplayProviderName is the provider name shown to the user
+	DisplayProviderName string `json:"displayProviderName" bson:"displayProviderName"`
 
 	IsPlanned        bool    `json:"isPlanned" bson:"isPlanned"`
 	ETA              float64 `json:"ETA" bson:"ETA"`
@@ -23,10 +41,22 @@ type Ride struct {
 	DisplayPriceNumeric float64 `json:"displayPriceNumeric" bson:"displayPriceNumeric"`
 	PriceStatus         string  `json:"priceStatus" bson:"priceStatus"`
 
-	Review  Review  `json:"review" bson:"review"`
-	Invoice Invoice `json:"invoice" bson:"invoice"`
-	Payment Payment `json:"payment" bson:"payment"`
-	Driver  Driver  `json:"driver" bson:"driver"`
+	Review        Review         `json:"review" bson:"review"`
+	Invoice       Invoice        `json:"invoice" bson:"invoice"`
+	Payment       Payment        `json:"payment" bson:"payment"`
+	Driver        Driver         `json:"driver" bson:"driver"`
+	StatusHistory []StatusChange `json:"statusHistory" bson:"statusHistory"`
+
+	StartAddress string `json:"startAddress" bson:"startAddress"`
+	EndAddress   string `json:"endAddress" bson:"endAddress"`
+	// Search is the trip asked by the user, it is kept to re-quote the ride once its offer is gone
+	Search Search `json:"search" bson:"search"`
+
+	// PickupDate is the date the user asked to be picked up at, Reservation track the lifecycle of a planned ride
+	PickupDate  time.Time   `json:"pickupDate" bson:"pickupDate"`
+	Reservation Reservation `json:"reservation" bson:"reservation"`
+	// Tracking keep the latest positions of the driver
+	Tracking Tracking `json:"tracking" bson:"tracking"`
 
 	CreatedAt string `json:"createdAt" bson:"createdAt"`
 	UpdatedAt string `json:"updatedAt" bson:"updatedAt"`
@@ -35,12 +65,48 @@ type Ride struct {
 
 // ProviderRide represent all the common data that provider share regarding their ride
 type ProviderRide struct {
-	Id         string
-	Status     string
-	StatusName string
-	Price      float64
-	ETA        float64
-	Driver     Driver
+	Id               string
+	Ref              string
+	Status           string
+	StatusName       string
+	Price            float64
+	ETA              float64
+	CancellationFees float64
+	Driver           Driver
+}
+
+// StatusChange represent a transition of a ride from a status to another
+type StatusChange struct {
+	From   string    `json:"from" bson:"from"`
+	To     string    `json:"to" bson:"to"`
+	Source string    `json:"source" bson:"source"`
+	Date   time.Time `json:"date" bson:"date"`
+}
+
+// CancellationQuote represent the fees a provider apply if a ride is cancelled at a given time
+type CancellationQuote struct {
+	Amount    float64   `json:"amount"`
+	Currency  string    `json:"currency"`
+	FreeUntil time.Time `json:"freeUntil"`
+	Reason    string    `json:"reason"`
+}
+
+// Reservation represent the follow-up of a planned ride until its pickup
+type Reservation struct {
+	ReminderSentAt   time.Time   `json:"reminderSentAt" bson:"reminderSentAt"`
+	LastCheckedAt    time.Time   `json:"lastCheckedAt" bson:"lastCheckedAt"`
+	ConfirmedAt      time.Time   `json:"confirmedAt" bson:"confirmedAt"`
+	PreAuthRenewedAt time.Time   `json:"preAuthRenewedAt" bson:"preAuthRenewedAt"`
+	Rebookings       []Rebooking `json:"rebookings" bson:"rebookings"`
+}
+
+// Rebooking represent the replacement of a provider that dropped a planned ride
+type Rebooking struct {
+	FromProvider       string    `json:"fromProvider" bson:"fromProvider"`
+	FromProviderRideID string    `json:"fromProviderRideID" bson:"fromProviderRideID"`
+	ToProvider         string    `json:"toProvider" bson:"toProvider"`
+	Reason             string    `json:"reason" bson:"reason"`
+	Date               time.Time `json:"date" bson:"date"`
 }
 
 // Driver represent a driver assign to a ride
@@ -49,15 +115,69 @@ type Driver struct {
 	DriverPhone     string  `json:"driverPhone" bson:"driverPhone"`
 	DriverLatitude  float64 `json:"driverLatitude" bson:"driverLatitude"`
 	DriverLongitude float64 `json:"driverLongitude" bson:"driverLongitude"`
+	// DriverHeading is the direction the driver is going to, in degrees clockwise from the north
+	DriverHeading float64 `json:"driverHeading" bson:"driverHeading"`
 
 	CarModel   string `json:"carModel" bson:"carModel"`
 	CarPhoto   string `json:"carPhoto" bson:"carPhoto"`
 	CarLicense string `json:"carLicense" bson:"carLicense"`
 }
 
+// Position represent a location of the driver at a given date
+type Position struct {
+	Latitude  float64   `json:"latitude" bson:"latitude"`
+	Longitude float64   `json:"longitude" bson:"longitude"`
+	Heading   float64   `json:"heading" bson:"heading"`
+	Date      time.Time `json:"date" bson:"date"`
+}
+
+// Tracking represent the recent path of the driver of a ride
+type Tracking struct {
+	LastPolledAt time.Time  `json:"lastPolledAt" bson:"lastPolledAt"`
+	Positions    []Position `json:"positions" bson:"positions"`
+}
+
+// Record append the position of the driver when it moved, only the max latest positions are kept.
+// It returns false when the position is unknown or the driver didn't move.
+func (t *Tracking) Record(p Position, max int) bool {
+	if p.Latitude == 0 && p.Longitude == 0 {
+		return false
+	}
+
+	if n := len(t.Positions); n > 0 {
+		last := t.Positions[n-1]
+		if last.Latitude == p.Latitude && last.Longitude == p.Longitude && last.Heading == p.Heading {
+			return false
+		}
+	}
+
+	t.Positions = append(t.Positions, p)
+	if max > 0 && len(t.Positions) > max {
+		t.Positions = t.Positions[len(t.Positions)-max:]
+	}
+
+	return true
+}
+
+// RideTracking represent the live location of the driver of a ride
+type RideTracking struct {
+	RideID    string     `json:"rideID"`
+	Status    string     `json:"status"`
+	Latitude  float64    `json:"latitude"`
+	Longitude float64    `json:"longitude"`
+	Heading   float64    `json:"heading"`
+	ETA       float64    `json:"ETA"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	Path      []Position `json:"path"`
+	// Stale is true when the provider couldn't be polled and the last known location is returned
+	Stale bool `json:"stale"`
+}
+
 // Review represent a review made by a user
 type Review struct {
-	Rating float64 `json:"rating"`
+	Rating  float64  `json:"rating" bson:"rating"`
+	Comment string   `json:"comment" bson:"comment"`
+	Tags    []string `json:"tags" bson:"tags"`
 
 	CreatedAt string `json:"createdAt" bson:"createdAt"`
 	UpdatedAt string `json:"updatedAt" bson:"updatedAt"`
@@ -66,7 +186,13 @@ type Review struct {
 
 // Invoice represent a invoice generate for a ride
 type Invoice struct {
-	Amount          float64   `json:"amount" bson:"amount"`
+	Number     string  `json:"number" bson:"number"`
+	Amount     float64 `json:"amount" bson:"amount"`
+	AmountHT   float64 `json:"amountHT" bson:"amountHT"`
+	VATPercent float64 `json:"vatPercent" bson:"vatPercent"`
+	VATAmount  float64 `json:"vatAmount" bson:"vatAmount"`
+	// Outstanding is the part of the amount that couldn't be charged when the invoice was issued
+	Outstanding     float64   `json:"outstanding" bson:"outstanding"`
 	InvoiceFileOnS3 string    `json:"invoiceFileOnS3" bson:"invoiceFileOnS3"`
 	Date            time.Time `json:"date" bson:"date"`
 	Nature          string    `json:"nature" bson:"nature"`
@@ -89,23 +215,135 @@ type Payment struct {
 	Challenge       bool      `json:"challenge" bson:"challenge"`
 	PaymentMethodID string    `json:"paymentMethodID" bson:"paymentMethodID"`
 
+	// CapturedPrice is the amount captured on the pre-authorization
+	CapturedPrice float64   `json:"capturedPrice" bson:"capturedPrice"`
+	CapturedAt    time.Time `json:"capturedAt" bson:"capturedAt"`
+	// SupplementID is the payment created when the final price exceed the pre-authorization
+	SupplementID    string  `json:"supplementID" bson:"supplementID"`
+	SupplementPrice float64 `json:"supplementPrice" bson:"supplementPrice"`
+	// OutstandingPrice is the amount that couldn't be charged to the user
+	OutstandingPrice float64 `json:"outstandingPrice" bson:"outstandingPrice"`
+	// LastError is the reason the last payment operation failed, it is cleared once the payment is settled
+	LastError string `json:"lastError" bson:"lastError"`
+	// RefundedPrice is the sum of the refunds made on the captured amounts
+	RefundedPrice float64  `json:"refundedPrice" bson:"refundedPrice"`
+	Refunds       []Refund `json:"refunds" bson:"refunds"`
+
 	CreatedAt string `json:"createdAt" bson:"createdAt"`
 	UpdatedAt string `json:"updatedAt" bson:"updatedAt"`
 	DeletedAt string `json:"deletedAt" bson:"deletedAt"`
 }
 
+// Refund represent an amount given back to the user on one of the payments of a ride
+type Refund struct {
+	ID              string    `json:"id" bson:"id"`
+	Amount          float64   `json:"amount" bson:"amount"`
+	Reason          string    `json:"reason" bson:"reason"`
+	Operator        string    `json:"operator" bson:"operator"`
+	PaymentIntentID string    `json:"paymentIntentID" bson:"paymentIntentID"`
+	StripeRefundID  string    `json:"stripeRefundID" bson:"stripeRefundID"`
+	Status          string    `json:"status" bson:"status"`
+	Date            time.Time `json:"date" bson:"date"`
+}
+
 // CreatePaymentDTO create a new payment for a ride
 type CreatePaymentDTO struct {
-	ReturnURL      string `json:"returnURL" validate:"required"`
-	OfferID        string `json:"offerID" validate:"required,uuid"`
-	UserID         string `json:"userID" validate:"required,uuid"`
-	AggregatorCode string `json:"aggregatorCode" validate:"required"`
+	ReturnURL string `json:"returnURL" validate:"required"`
+	OfferID   string `json:"offerID" validate:"required,uuid"`
+	UserID    string `json:"userID" validate:"required,uuid"`
+	// Deprecated: AggregatorCode is still accepted from the existing clients but ignored, the aggregator is the one of
+	// the request
+	AggregatorCode string `json:"aggregatorCode"`
 }
 
 // NewRideDTO order a new ride for a given provider offer
 type NewRideDTO struct {
 	OfferID        string `json:"offerID" validate:"required,uuid"`
 	UserID         string `json:"userID" validate:"required,uuid"`
-	AggregatorCode string `json:"aggregatorCode" validate:"required"`
 	StripeIntentID string `json:"stripeIntentID" validate:"required"`
+	// Deprecated: AggregatorCode is still accepted from the existing clients but ignored, the aggregator is the one of
+	// the request
+	AggregatorCode string `json:"aggregatorCode"`
+}
+
+// CancelRideDTO cancel a ride previously booked by a user
+type CancelRideDTO struct {
+	RideID string `json:"rideID" validate:"required,uuid"`
+	UserID string `json:"userID" validate:"required,uuid"`
+}
+
+// CancellationFeesDTO quote the fees applied if a ride is cancelled now
+type CancellationFeesDTO struct {
+	RideID string `json:"rideID" validate:"required,uuid"`
+	UserID string `json:"userID" validate:"required,uuid"`
+}
+
+// RefundRideDTO refund all or part of the captured price of a ride, the remaining captured amount is refunded when
+// no amount is given. The operator is the authenticated support user, it can't be given in the body.
+type RefundRideDTO struct {
+	RideID   string  `json:"rideID" validate:"required,uuid"`
+	Amount   float64 `json:"amount" validate:"omitempty,gt=0"`
+	Reason   string  `json:"reason" validate:"required,max=500"`
+	Operator string  `json:"-" validate:"required"`
+}
+
+// TrackRideDTO fetch the live location of the driver of a ride
+type TrackRideDTO struct {
+	RideID string `json:"rideID" validate:"required,uuid"`
+	UserID string `json:"userID" validate:"required,uuid"`
+}
+
+// GetInvoiceDTO fetch a download link to the invoice of a completed ride
+type GetInvoiceDTO struct {
+	RideID string `json:"rideID" validate:"required,uuid"`
+	UserID string `json:"userID" validate:"required,uuid"`
+}
+
+// InvoiceLink represent a temporary link to download the invoice of a ride
+type InvoiceLink struct {
+	RideID    string    `json:"rideID"`
+	Number    string    `json:"number"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// ListRidesDTO list the rides of a user, the rides are sorted on their pickup date
+type ListRidesDTO struct {
+	UserID   string   `json:"userID" validate:"required,uuid"`
+	Cursor   string   `json:"cursor"`
+	Statuses []string `json:"statuses" validate:"omitempty,dive,oneof=processing accepted in_progress completed arriving cancelled driver_cancelled no_driver_found scheduled onboard_cancelled"`
+	Provider string   `json:"provider"`
+	From     string   `json:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	To       string   `json:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Type     string   `json:"type" validate:"omitempty,oneof=planned immediate"`
+	Sort     string   `json:"sort" validate:"omitempty,oneof=asc desc"`
+	Limit    int      `json:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+// RideSummary represent the main information of a ride shown in the ride history
+type RideSummary struct {
+	ID                  string        `json:"id" bson:"_id"`
+	ProviderName        string        `json:"providerName" bson:"providerName"`
+	Status              string        `json:"status" bson:"status"`
+	IsPlanned           bool          `json:"isPlanned" bson:"isPlanned"`
+	PickupDate          time.Time     `json:"pickupDate" bson:"pickupDate"`
+	StartAddress        string        `json:"startAddress" bson:"startAddress"`
+	EndAddress          string        `json:"endAddress" bson:"endAddress"`
+	DisplayPrice        string        `json:"displayPrice" bson:"displayPrice"`
+	DisplayPriceNumeric float64       `json:"displayPriceNumeric" bson:"displayPriceNumeric"`
+	PriceStatus         string        `json:"priceStatus" bson:"priceStatus"`
+	Driver              DriverSummary `json:"driver" bson:"driver"`
+	CreatedAt           string        `json:"createdAt" bson:"createdAt"`
+}
+
+// DriverSummary represent the driver of a ride shown in the ride history
+type DriverSummary struct {
+	DriverName string `json:"driverName" bson:"driverName"`
+	CarModel   string `json:"carModel" bson:"carModel"`
+}
+
+// RidePage represent a page of the ride history, NextCursor is empty on the last page
+type RidePage struct {
+	Rides      []RideSummary `json:"rides"`
+	NextCursor string        `json:"nextCursor"`
 }
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// WebhookDelivery represent a webhook event received from a provider, it is kept to ignore the redeliveries
+type WebhookDelivery struct {
+	ID         string    `json:"id" bson:"_id"`
+	Provider   string    `json:"provider" bson:"provider"`
+	EventID    string    `json:"eventID" bson:"eventID"`
+	RideRef    string    `json:"rideRef" bson:"rideRef"`
+	Status     string    `json:"status" bson:"status"`
+	ReceivedAt time.Time `json:"receivedAt" bson:"receivedAt"`
+	// ExpiresAt is the end of the retention period, past this date the delivery is removed by a ttl index
+	ExpiresAt time.Time `json:"expiresAt" bson:"expiresAt"`
+}
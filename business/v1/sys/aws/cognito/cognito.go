@@ -2,13 +2,18 @@ package cognito
 
 import (
 	"crypto/sha256"
+	"errors"
 	"fmt"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
 )
 
+// ErrInvalidToken is returned when an access token is expired, revoked or wasn't issued by the pool
+var ErrInvalidToken = errors.New("invalid access token")
+
 // User represents all the user data store in cognito
 type User struct {
 	Email       string `faker:"email"`
@@ -86,6 +91,26 @@ func Login(sess *session.Session, clientID, userID, password string) (Session, e
 	}, nil
 }
 
+// GetUsername return the username of the user owning the access token, it is the sub generated on sign up. The token
+// is verified by cognito.
+func GetUsername(sess *session.Session, accessToken string) (string, error) {
+	client := cognitoidentityprovider.New(sess)
+
+	res, err := client.GetUser(&cognitoidentityprovider.GetUserInput{
+		AccessToken: aws.String(accessToken),
+	})
+
+	var aErr awserr.Error
+	switch {
+	case errors.As(err, &aErr) && aErr.Code() == cognitoidentityprovider.ErrCodeNotAuthorizedException:
+		return "", fmt.Errorf("%w: %v", ErrInvalidToken, aErr.Message())
+	case err != nil:
+		return "", fmt.Errorf("failed to get the user of the token: %v", err)
+	}
+
+	return aws.StringValue(res.Username), nil
+}
+
 // GenerateSub create a unique hash from the email, phone number and clientID that will be used as the user's id
 func GenerateSub(email, phoneNumber, clientID string) string {
 	sub := email + phoneNumber + clientID
@@ -0,0 +1,104 @@
+// Package s3 store files in an aws s3 bucket using the s3 package. A local filesystem implementation can replace
+// the bucket in tests and local development.
+// https://pkg.go.dev/github.com/aws/aws-sdk-go@v1.44.256/service/s3
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// ErrNotFound is returned when no file is stored under the given key
+var ErrNotFound = errors.New("file not found")
+
+// Storage save files under a key and share them through temporary links
+type Storage interface {
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	PresignGet(ctx context.Context, key string, expire time.Duration) (string, error)
+}
+
+// Bucket store the files in a s3 bucket
+type Bucket struct {
+	client *s3.S3
+	name   string
+}
+
+// NewBucket create a storage saving the files in the given bucket
+func NewBucket(sess *session.Session, name string) Bucket {
+	return Bucket{client: s3.New(sess), name: name}
+}
+
+// Put upload the data to the bucket under the given key, an existing file is replaced
+func (b Bucket) Put(ctx context.Context, key, contentType string, data []byte) error {
+	_, err := b.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(b.name),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload %v to bucket %v: %v", key, b.name, err)
+	}
+
+	return nil
+}
+
+// PresignGet return a link allowing to download the file until it expires
+func (b Bucket) PresignGet(ctx context.Context, key string, expire time.Duration) (string, error) {
+	req, _ := b.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(b.name),
+		Key:    aws.String(key),
+	})
+	req.SetContext(ctx)
+
+	link, err := req.Presign(expire)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign %v of bucket %v: %v", key, b.name, err)
+	}
+
+	return link, nil
+}
+
+// FileSystem store the files in a local directory, the keys are paths relative to the directory
+type FileSystem struct {
+	Dir string
+}
+
+// Put write the data to the file of the given key, the missing directories are created
+func (f FileSystem) Put(ctx context.Context, key, contentType string, data []byte) error {
+	path := filepath.Join(f.Dir, filepath.FromSlash(key))
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory of %v: %v", key, err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %v: %v", key, err)
+	}
+
+	return nil
+}
+
+// PresignGet return a file url pointing to the file, local files don't expire
+func (f FileSystem) PresignGet(ctx context.Context, key string, expire time.Duration) (string, error) {
+	path, err := filepath.Abs(filepath.Join(f.Dir, filepath.FromSlash(key)))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve path of %v: %v", key, err)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrNotFound, key)
+	}
+
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String(), nil
+}
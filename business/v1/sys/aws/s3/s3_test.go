@@ -0,0 +1,54 @@
+package s3_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"vtc/business/v1/sys/aws/s3"
+)
+
+const (
+	success = "\u2713"
+	failure = "\u2717"
+)
+
+func Test_FileSystem(t *testing.T) {
+	storage := s3.FileSystem{Dir: t.TempDir()}
+	data := []byte("%PDF-1.4")
+
+	t.Log("Given the need to store a file on the local filesystem")
+	{
+		if err := storage.Put(context.Background(), "invoices/tgs/TGS-2026-000001.pdf", "application/pdf", data); err != nil {
+			t.Fatalf("\t%s\t Test: \tShould be able to store the file: %v", failure, err)
+		}
+		t.Logf("\t%s\t Test: \tShould be able to store the file", success)
+
+		link, err := storage.PresignGet(context.Background(), "invoices/tgs/TGS-2026-000001.pdf", time.Minute)
+		if err != nil {
+			t.Fatalf("\t%s\t Test: \tShould be able to get a link to the file: %v", failure, err)
+		}
+
+		u, err := url.Parse(link)
+		if err != nil || u.Scheme != "file" {
+			t.Fatalf("\t%s\t Test: \tShould get a file url: %v", failure, link)
+		}
+
+		stored, err := os.ReadFile(u.Path)
+		if err != nil || string(stored) != string(data) {
+			t.Fatalf("\t%s\t Test: \tShould be able to read the file through its link: %v", failure, err)
+		}
+		t.Logf("\t%s\t Test: \tShould be able to read the file through its link", success)
+	}
+
+	t.Log("Given the need to get a link to a missing file")
+	{
+		if _, err := storage.PresignGet(context.Background(), "invoices/missing.pdf", time.Minute); !errors.Is(err, s3.ErrNotFound) {
+			t.Fatalf("\t%s\t Test: \tShould return a not found error: %v", failure, err)
+		}
+		t.Logf("\t%s\t Test: \tShould return a not found error", success)
+	}
+}
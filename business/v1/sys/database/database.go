@@ -128,7 +128,7 @@ func FindOne[T any](ctx context.Context, client *mongo.Database, collection stri
 
 	switch err := client.Collection(collection).FindOne(nCtx, filter).Decode(dest); err {
 	case mongo.ErrNoDocuments:
-		return fmt.Errorf("failed to find document with current filter: %v, error: %v", filter, err)
+		return fmt.Errorf("failed to find document with current filter: %v, error: %w", filter, err)
 	case nil:
 		break
 	default:
@@ -148,7 +148,7 @@ func InsertOne[T any](ctx context.Context, client *mongo.Database, collection st
 
 	res, err := client.Collection(collection).InsertOne(nCtx, data)
 	if err != nil {
-		return fmt.Errorf("failed to insert document: %v", err)
+		return fmt.Errorf("failed to insert document: %w", err)
 	}
 
 	print(res.InsertedID)
@@ -208,6 +208,112 @@ func UpdateOne[T any](ctx context.Context, client *mongo.Database, collection, i
 	return nil
 }
 
+// UpdateOneWhere set the given fields on the document matching the filter. It is used as a conditional update, the
+// returned boolean is false when no document matched the filter.
+func UpdateOneWhere[T any](ctx context.Context, client *mongo.Database, collection string, filter bson.D, data T) (bool, error) {
+	nCtx, cancel := context.WithTimeout(ctx, queryTimeout*time.Second)
+	defer cancel()
+
+	update := bson.D{{Key: "$set", Value: data}}
+
+	opt := options.Update().SetUpsert(false)
+	res, err := client.Collection(collection).UpdateOne(nCtx, filter, update, opt)
+	if err != nil {
+		return false, fmt.Errorf("failed to update document: %v", err)
+	}
+
+	return res.MatchedCount > 0, nil
+}
+
+// UpdateOneWith apply the given update operators on the document matching the filter. It is used when setting fields
+// isn't enough, e.g. to push to an array, the returned boolean is false when no document matched the filter.
+func UpdateOneWith(ctx context.Context, client *mongo.Database, collection string, filter, update bson.D) (bool, error) {
+	nCtx, cancel := context.WithTimeout(ctx, queryTimeout*time.Second)
+	defer cancel()
+
+	opt := options.Update().SetUpsert(false)
+	res, err := client.Collection(collection).UpdateOne(nCtx, filter, update, opt)
+	if err != nil {
+		return false, fmt.Errorf("failed to update document: %v", err)
+	}
+
+	return res.MatchedCount > 0, nil
+}
+
+// CreateTTLIndex create an index removing the documents once the given date field is older than expireAfter.
+// Creating an index that already exist with the same options is a no-op.
+func CreateTTLIndex(ctx context.Context, client *mongo.Database, collection, field string, expireAfter time.Duration) error {
+	nCtx, cancel := context.WithTimeout(ctx, queryTimeout*time.Second)
+	defer cancel()
+
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: field, Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32(expireAfter.Seconds())),
+	}
+
+	if _, err := client.Collection(collection).Indexes().CreateOne(nCtx, index); err != nil {
+		return fmt.Errorf("failed to create ttl index on %v: %v", field, err)
+	}
+
+	return nil
+}
+
+// Aggregate run the aggregation pipeline on the collection and decode the resulting documents
+func Aggregate[T any](ctx context.Context, client *mongo.Database, collection string, pipeline mongo.Pipeline) ([]T, error) {
+	nCtx, cancel := context.WithTimeout(ctx, queryTimeout*time.Second)
+	defer cancel()
+
+	cur, err := client.Collection(collection).Aggregate(nCtx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate collection with pipeline: %v, error: %v", pipeline, err)
+	}
+
+	var res []T
+	if err := cur.All(nCtx, &res); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal aggregation result into destination: %v", err)
+	}
+
+	return res, nil
+}
+
+// Increment atomically increment the sequence of the document with the given id and return its new value. The
+// document is created when it doesn't exist yet, its first value is 1.
+func Increment(ctx context.Context, client *mongo.Database, collection, id string) (int64, error) {
+	nCtx, cancel := context.WithTimeout(ctx, queryTimeout*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: id}}
+	update := bson.D{{Key: "$inc", Value: bson.D{{Key: "seq", Value: int64(1)}}}}
+
+	opt := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+
+	if err := client.Collection(collection).FindOneAndUpdate(nCtx, filter, update, opt).Decode(&counter); err != nil {
+		return 0, fmt.Errorf("failed to increment sequence %v: %v", id, err)
+	}
+
+	return counter.Seq, nil
+}
+
+// WithTransaction run fn in a transaction, the writes made with the context given to fn are committed together when
+// it succeed and discarded when it return an error.
+func WithTransaction(ctx context.Context, client *mongo.Database, fn func(ctx context.Context) error) error {
+	sess, err := client.Client().StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %v", err)
+	}
+	defer sess.EndSession(ctx)
+
+	_, err = sess.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+
+	return err
+}
+
 func getCustomTLSConfig(caFilePath string) (*tls.Config, error) {
 	tlsConfig := new(tls.Config)
 	certs, err := os.ReadFile(fmt.Sprintf(caFilePath))
@@ -2,6 +2,7 @@ package database_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -99,6 +100,194 @@ func Test_FindOne(t *testing.T) {
 	}
 }
 
+func Test_FindPage(t *testing.T) {
+	// the documents of the test share the same surname so they can be isolated from the others
+	surname := uuid.NewString()
+	test := []User{
+		{"C", surname, uuid.NewString()},
+		{"A", surname, uuid.NewString()},
+		{"E", surname, uuid.NewString()},
+		{"B", surname, uuid.NewString()},
+		{"D", surname, uuid.NewString()},
+	}
+	if err := database.InsertMany[User](context.Background(), client, "test", test); err != nil {
+		t.Fatalf("\t%s\t Test: \tShould be able to insert the paginated documents: %v", failure, err)
+	}
+
+	t.Log("Given the need to find documents page by page")
+	{
+		var (
+			names  string
+			cursor string
+			pages  int
+		)
+
+		for {
+			page := database.Page{SortField: "name", Limit: 2, Cursor: cursor}
+			res, next, err := database.FindPage[User](context.Background(), client, "test", bson.D{{Key: "surname", Value: surname}}, page)
+			if err != nil {
+				t.Fatalf("\t%s\t Test: \tShould be able to find a page of documents: %v", failure, err)
+			}
+
+			for _, u := range res {
+				names += u.Name
+			}
+			pages++
+
+			if len(next) == 0 {
+				break
+			}
+			cursor = next
+		}
+
+		if names != "ABCDE" || pages != 3 {
+			t.Fatalf("\t%s\t Test: \tShould get every document once in order: got %v in %d pages", failure, names, pages)
+		}
+		t.Logf("\t%s\t Test: \tShould get every document once in order", success)
+	}
+
+	t.Log("Given the need to find documents page by page in descending order")
+	{
+		page := database.Page{SortField: "name", Descending: true, Limit: 3}
+		res, next, err := database.FindPage[User](context.Background(), client, "test", bson.D{{Key: "surname", Value: surname}}, page)
+		if err != nil {
+			t.Fatalf("\t%s\t Test: \tShould be able to find a page of documents: %v", failure, err)
+		}
+		if len(res) != 3 || res[0].Name != "E" || len(next) == 0 {
+			t.Fatalf("\t%s\t Test: \tShould get the first page in descending order: %v", failure, res)
+		}
+		t.Logf("\t%s\t Test: \tShould get the first page in descending order", success)
+	}
+
+	t.Log("Given the need to reject a tampered cursor")
+	{
+		page := database.Page{SortField: "name", Limit: 2, Cursor: "not-a-cursor"}
+		if _, _, err := database.FindPage[User](context.Background(), client, "test", bson.D{}, page); !errors.Is(err, database.ErrInvalidCursor) {
+			t.Fatalf("\t%s\t Test: \tShould return an invalid cursor error: %v", failure, err)
+		}
+		t.Logf("\t%s\t Test: \tShould return an invalid cursor error", success)
+	}
+}
+
+func Test_FindPageMissingSortValue(t *testing.T) {
+	// documents missing the sort field are stored without it, not with an empty value
+	surname := uuid.NewString()
+	test := []bson.D{
+		{{Key: "_id", Value: uuid.NewString()}, {Key: "surname", Value: surname}, {Key: "name", Value: "B"}},
+		{{Key: "_id", Value: uuid.NewString()}, {Key: "surname", Value: surname}},
+		{{Key: "_id", Value: uuid.NewString()}, {Key: "surname", Value: surname}, {Key: "name", Value: "A"}},
+		{{Key: "_id", Value: uuid.NewString()}, {Key: "surname", Value: surname}},
+		{{Key: "_id", Value: uuid.NewString()}, {Key: "surname", Value: surname}},
+	}
+	if err := database.InsertMany[bson.D](context.Background(), client, "test", test); err != nil {
+		t.Fatalf("\t%s\t Test: \tShould be able to insert the paginated documents: %v", failure, err)
+	}
+
+	findAll := func(descending bool) ([]User, error) {
+		var (
+			all    []User
+			cursor string
+		)
+
+		for {
+			page := database.Page{SortField: "name", Descending: descending, Limit: 2, Cursor: cursor}
+			res, next, err := database.FindPage[User](context.Background(), client, "test", bson.D{{Key: "surname", Value: surname}}, page)
+			if err != nil {
+				return nil, err
+			}
+
+			all = append(all, res...)
+			if len(next) == 0 {
+				return all, nil
+			}
+			cursor = next
+		}
+	}
+
+	t.Log("Given the need to find documents page by page when some miss the sort field")
+	{
+		res, err := findAll(false)
+		if err != nil {
+			t.Fatalf("\t%s\t Test: \tShould be able to find a page of documents: %v", failure, err)
+		}
+		if len(res) != 5 || res[0].Name != "" || res[2].Name != "" || res[3].Name != "A" || res[4].Name != "B" {
+			t.Fatalf("\t%s\t Test: \tShould get the documents missing the sort field first in ascending order: %v", failure, res)
+		}
+		t.Logf("\t%s\t Test: \tShould get the documents missing the sort field first in ascending order", success)
+
+		res, err = findAll(true)
+		if err != nil {
+			t.Fatalf("\t%s\t Test: \tShould be able to find a page of documents: %v", failure, err)
+		}
+		if len(res) != 5 || res[0].Name != "B" || res[1].Name != "A" || res[2].Name != "" || res[4].Name != "" {
+			t.Fatalf("\t%s\t Test: \tShould get the documents missing the sort field last in descending order: %v", failure, res)
+		}
+		t.Logf("\t%s\t Test: \tShould get the documents missing the sort field last in descending order", success)
+
+		ids := map[string]bool{}
+		for _, u := range res {
+			ids[u.ID] = true
+		}
+		if len(ids) != 5 {
+			t.Fatalf("\t%s\t Test: \tShould get every document once: %v", failure, res)
+		}
+		t.Logf("\t%s\t Test: \tShould get every document once", success)
+	}
+}
+
+func Test_Aggregate(t *testing.T) {
+	surname := uuid.NewString()
+	test := []User{
+		{"Lorem", surname, uuid.NewString()},
+		{"Lorem", surname, uuid.NewString()},
+		{"Ipsum", surname, uuid.NewString()},
+	}
+	if err := database.InsertMany[User](context.Background(), client, "test", test); err != nil {
+		t.Fatalf("\t%s\t Test: \tShould be able to insert the aggregated documents: %v", failure, err)
+	}
+
+	t.Log("Given the need to aggregate documents")
+	{
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: bson.D{{Key: "surname", Value: surname}}}},
+			{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$name"}, {Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}}}}},
+			{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		}
+
+		type group struct {
+			Name  string `bson:"_id"`
+			Count int    `bson:"count"`
+		}
+
+		res, err := database.Aggregate[group](context.Background(), client, "test", pipeline)
+		if err != nil {
+			t.Fatalf("\t%s\t Test: \tShould be able to aggregate documents: %v", failure, err)
+		}
+		if len(res) != 2 || res[0].Name != "Ipsum" || res[0].Count != 1 || res[1].Count != 2 {
+			t.Fatalf("\t%s\t Test: \tShould group the documents by name: %v", failure, res)
+		}
+		t.Logf("\t%s\t Test: \tShould group the documents by name", success)
+	}
+}
+
+func Test_Increment(t *testing.T) {
+	sequence := uuid.NewString()
+
+	t.Log("Given the need to increment a sequence")
+	{
+		for want := int64(1); want <= 3; want++ {
+			got, err := database.Increment(context.Background(), client, "test_counter", sequence)
+			if err != nil {
+				t.Fatalf("\t%s\t Test: \tShould be able to increment the sequence: %v", failure, err)
+			}
+			if got != want {
+				t.Fatalf("\t%s\t Test: \tShould get consecutive values: got %d, want %d", failure, got, want)
+			}
+		}
+		t.Logf("\t%s\t Test: \tShould get consecutive values starting at 1", success)
+	}
+}
+
 func Test_InsertMany(t *testing.T) {
 	t.Log("Given the need to insert many documents")
 	{
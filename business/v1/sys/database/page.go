@@ -0,0 +1,164 @@
+package database
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page describe a page of a paginated query. The documents are sorted on the sort field then on their id so the
+// order is stable, a page start right after the document the cursor point to. The documents missing the sort field
+// come first in ascending order and last in descending order.
+type Page struct {
+	SortField  string
+	Descending bool
+	Limit      int64
+	// Cursor is the opaque cursor returned with the previous page, it is empty for the first page
+	Cursor string
+	// Projection restrict the returned fields, all the fields are returned when it is nil
+	Projection bson.D
+}
+
+// cursor is the position of the last document of a page
+type cursor struct {
+	Value interface{} `bson:"v"`
+	ID    interface{} `bson:"id"`
+}
+
+// EncodeCursor create the opaque cursor pointing to the document with the given sort value and id. A nil value
+// point to a document missing the sort field.
+func EncodeCursor(value, id interface{}) (string, error) {
+	if raw, ok := value.(bson.RawValue); ok && raw.Type == 0 {
+		value = nil
+	}
+
+	data, err := bson.Marshal(cursor{Value: value, ID: id})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %v", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor read the sort value and the id of the document an opaque cursor point to
+func decodeCursor(s string) (bson.RawValue, bson.RawValue, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return bson.RawValue{}, bson.RawValue{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	raw := bson.Raw(data)
+	if err := raw.Validate(); err != nil {
+		return bson.RawValue{}, bson.RawValue{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	value, vErr := raw.LookupErr("v")
+	id, iErr := raw.LookupErr("id")
+	if vErr != nil || iErr != nil {
+		return bson.RawValue{}, bson.RawValue{}, fmt.Errorf("%w: missing position", ErrInvalidCursor)
+	}
+
+	return value, id, nil
+}
+
+// position filter the documents sorted after the cursor, the id break the ties between equal sort values. A missing
+// sort value is stored as null, it can't be compared with $gt or $lt to the other values so the documents missing
+// the sort field are selected on their own.
+func position(page Page, after string, value, id bson.RawValue) bson.D {
+	missing := bson.D{{Key: page.SortField, Value: nil}}
+	tie := bson.D{{Key: page.SortField, Value: value}, {Key: "_id", Value: bson.D{{Key: after, Value: id}}}}
+
+	switch {
+	case value.Type == bsontype.Null && page.Descending:
+		return tie
+	case value.Type == bsontype.Null:
+		return bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: page.SortField, Value: bson.D{{Key: "$ne", Value: nil}}}},
+			tie,
+		}}}
+	case page.Descending:
+		return bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: page.SortField, Value: bson.D{{Key: after, Value: value}}}},
+			tie,
+			missing,
+		}}}
+	default:
+		return bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: page.SortField, Value: bson.D{{Key: after, Value: value}}}},
+			tie,
+		}}}
+	}
+}
+
+// FindPage executes a paginated search and return the documents of the requested page alongside the cursor of the
+// next one. The next cursor is empty on the last page.
+func FindPage[T any](ctx context.Context, client *mongo.Database, collection string, filter bson.D, page Page) ([]T, string, error) {
+	nCtx, cancel := context.WithTimeout(ctx, queryTimeout*time.Second)
+	defer cancel()
+
+	direction, after := 1, "$gt"
+	if page.Descending {
+		direction, after = -1, "$lt"
+	}
+
+	if len(page.Cursor) > 0 {
+		value, id, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+
+		filter = bson.D{{Key: "$and", Value: bson.A{filter, position(page, after, value, id)}}}
+	}
+
+	// one more document is fetched to know if there is a next page
+	opt := options.Find().
+		SetSort(bson.D{{Key: page.SortField, Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(page.Limit + 1)
+
+	if page.Projection != nil {
+		opt = opt.SetProjection(page.Projection)
+	}
+
+	cur, err := client.Collection(collection).Find(nCtx, filter, opt)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to find collection with filter: %v, error: %v", filter, err)
+	}
+
+	defer cur.Close(nCtx)
+
+	var (
+		res  []T
+		last bson.Raw
+	)
+
+	for cur.Next(nCtx) {
+		if int64(len(res)) == page.Limit {
+			// the extra document only tells a next page exist
+			next, err := EncodeCursor(last.Lookup(page.SortField), last.Lookup("_id"))
+			return res, next, err
+		}
+
+		var item T
+		if err := cur.Decode(&item); err != nil {
+			return nil, "", fmt.Errorf("failed to unmarshal item from result into destination: %v", err)
+		}
+
+		res = append(res, item)
+		last = append(bson.Raw(nil), cur.Current...)
+	}
+
+	if err := cur.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to find in collection: %v", err)
+	}
+
+	return res, "", nil
+}
@@ -0,0 +1,108 @@
+package geo
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Polygon is a list of linear rings, the first one is the outer boundary and the others are holes.
+// The coordinates are [longitude, latitude] pairs as in GeoJSON.
+type Polygon [][][2]float64
+
+// Contains check if the point is inside the polygon and outside all its holes
+func (p Polygon) Contains(pt Point) bool {
+	if len(p) == 0 || !inRing(p[0], pt) {
+		return false
+	}
+
+	for _, hole := range p[1:] {
+		if inRing(hole, pt) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// inRing check if the point is inside the ring using the ray casting algorithm
+func inRing(ring [][2]float64, pt Point) bool {
+	in := false
+
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+
+		if (yi > pt.Latitude) != (yj > pt.Latitude) &&
+			pt.Longitude < (xj-xi)*(pt.Latitude-yi)/(yj-yi)+xi {
+			in = !in
+		}
+	}
+
+	return in
+}
+
+// Coverage is the area served by a provider
+type Coverage []Polygon
+
+// Contains check if the point is inside one of the coverage polygons
+func (c Coverage) Contains(pt Point) bool {
+	for _, p := range c {
+		if p.Contains(pt) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type geoJSON struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    *geoJSON        `json:"geometry"`
+	Features    []geoJSON       `json:"features"`
+}
+
+// ParseCoverage parse a GeoJSON document into a coverage. Polygon and MultiPolygon geometries are supported,
+// either alone or inside a Feature or a FeatureCollection.
+func ParseCoverage(data []byte) (Coverage, error) {
+	var doc geoJSON
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode geojson: %v", err)
+	}
+
+	return doc.coverage()
+}
+
+func (g geoJSON) coverage() (Coverage, error) {
+	switch g.Type {
+	case "Polygon":
+		var p Polygon
+		if err := json.Unmarshal(g.Coordinates, &p); err != nil {
+			return nil, fmt.Errorf("invalid polygon coordinates: %v", err)
+		}
+		return Coverage{p}, nil
+	case "MultiPolygon":
+		var c Coverage
+		if err := json.Unmarshal(g.Coordinates, &c); err != nil {
+			return nil, fmt.Errorf("invalid multipolygon coordinates: %v", err)
+		}
+		return c, nil
+	case "Feature":
+		if g.Geometry == nil {
+			return nil, fmt.Errorf("feature without geometry")
+		}
+		return g.Geometry.coverage()
+	case "FeatureCollection":
+		var c Coverage
+		for _, f := range g.Features {
+			fc, err := f.coverage()
+			if err != nil {
+				return nil, err
+			}
+			c = append(c, fc...)
+		}
+		return c, nil
+	default:
+		return nil, fmt.Errorf("unsupported geojson type %q", g.Type)
+	}
+}
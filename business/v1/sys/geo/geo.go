@@ -0,0 +1,153 @@
+// Package geo compute the distance and duration of a route between two points. Routes come from a pluggable
+// Router, either the great-circle distance or an OSRM compatible routing service.
+package geo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"vtc/foundation/config"
+)
+
+// List of the values a Route source can take
+const (
+	SourceHaversine = "haversine"
+	SourceOSRM      = "osrm"
+)
+
+// earthRadius is the mean earth radius in meters
+const earthRadius = 6371008.8
+
+var ErrNoRoute = errors.New("no route found")
+
+// Point is a coordinate on earth
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Route is the path between two points, its distance is in meters
+type Route struct {
+	Distance float64
+	Duration time.Duration
+	Source   string
+}
+
+// Router compute the route between two points
+type Router interface {
+	Route(ctx context.Context, from, to Point) (Route, error)
+}
+
+// Haversine return the great-circle distance in meters between two points
+func Haversine(from, to Point) float64 {
+	lat1, lat2 := radians(from.Latitude), radians(to.Latitude)
+	dLat := lat2 - lat1
+	dLon := radians(to.Longitude - from.Longitude)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// HaversineRouter compute routes from the great-circle distance, the duration is estimated from an average speed
+type HaversineRouter struct {
+	// Speed is the average speed in meters per second
+	Speed float64
+}
+
+// NewHaversineRouter create a router estimating the duration with an average urban speed of 25km/h
+func NewHaversineRouter() HaversineRouter {
+	return HaversineRouter{Speed: 25 / 3.6}
+}
+
+// Route return the great-circle route between the two points
+func (r HaversineRouter) Route(_ context.Context, from, to Point) (Route, error) {
+	distance := Haversine(from, to)
+
+	var duration time.Duration
+	if r.Speed > 0 {
+		duration = time.Duration(distance / r.Speed * float64(time.Second))
+	}
+
+	return Route{Distance: distance, Duration: duration, Source: SourceHaversine}, nil
+}
+
+// fallbackRouter use the fallback router when the primary one fail
+type fallbackRouter struct {
+	primary  Router
+	fallback Router
+}
+
+// WithFallback return a router using the fallback router when the primary one fail
+func WithFallback(primary, fallback Router) Router {
+	return fallbackRouter{primary: primary, fallback: fallback}
+}
+
+func (r fallbackRouter) Route(ctx context.Context, from, to Point) (Route, error) {
+	route, err := r.primary.Route(ctx, from, to)
+	if err == nil {
+		return route, nil
+	}
+
+	route, fErr := r.fallback.Route(ctx, from, to)
+	if fErr != nil {
+		return Route{}, fmt.Errorf("primary router failed with %v, fallback router failed with %w", err, fErr)
+	}
+
+	return route, nil
+}
+
+// RouteVia return the route going through all the points in the given order, each leg being routed on its own.
+// The route is reported as an haversine one as soon as one of its legs is.
+func RouteVia(ctx context.Context, r Router, points ...Point) (Route, error) {
+	if len(points) < 2 {
+		return Route{}, fmt.Errorf("%w: a route needs at least 2 points, receive %d", ErrNoRoute, len(points))
+	}
+
+	var res Route
+	for i := 1; i < len(points); i++ {
+		leg, err := r.Route(ctx, points[i-1], points[i])
+		if err != nil {
+			return Route{}, fmt.Errorf("failed to route leg %d: %w", i, err)
+		}
+
+		res.Distance += leg.Distance
+		res.Duration += leg.Duration
+		if len(res.Source) == 0 || leg.Source == SourceHaversine {
+			res.Source = leg.Source
+		}
+	}
+
+	return res, nil
+}
+
+// Matches check if a distance given by a client is within the tolerance, in percent, of the computed one
+func Matches(computed, client float64, tolerancePercent int) bool {
+	if computed <= 0 {
+		return client <= 0
+	}
+
+	return math.Abs(client-computed)/computed*100 <= float64(tolerancePercent)
+}
+
+// New create the router configured through the environment. The OSRM api is used when configured, with the
+// great-circle distance as fallback.
+func New(cfg *config.App) Router {
+	haversine := NewHaversineRouter()
+
+	if len(cfg.Env.Geo.OSRMURL) == 0 {
+		return haversine
+	}
+
+	client := &http.Client{Timeout: time.Duration(cfg.Env.Geo.Timeout) * time.Second}
+
+	return WithFallback(NewOSRM(client, cfg.Env.Geo.OSRMURL), haversine)
+}
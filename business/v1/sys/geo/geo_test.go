@@ -0,0 +1,172 @@
+package geo_test
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"vtc/business/v1/sys/geo"
+)
+
+const (
+	success = "\u2713"
+	failure = "\u2717"
+)
+
+var (
+	gareDeLyon = geo.Point{Latitude: 48.8443, Longitude: 2.3744}
+	tourEiffel = geo.Point{Latitude: 48.8584, Longitude: 2.2945}
+)
+
+func Test_Haversine(t *testing.T) {
+	t.Log("Given the need to compute the great-circle distance between two points")
+	{
+		// distance measured on a map between the two points
+		if d := geo.Haversine(gareDeLyon, tourEiffel); math.Abs(d-6050) > 50 {
+			t.Fatalf("\t%s\t Test: \tShould compute a distance close to 6050m, receive %v", failure, d)
+		}
+		t.Logf("\t%s\t Test: \tShould compute the distance in meters", success)
+
+		if d := geo.Haversine(tourEiffel, tourEiffel); d != 0 {
+			t.Fatalf("\t%s\t Test: \tShould compute a zero distance for the same point, receive %v", failure, d)
+		}
+		t.Logf("\t%s\t Test: \tShould compute a zero distance for the same point", success)
+	}
+}
+
+func Test_OSRM(t *testing.T) {
+	t.Log("Given the need to compute a driving route through an OSRM api")
+	{
+		var path string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			w.Write([]byte(`{"code":"Ok","routes":[{"distance":7800.5,"duration":1260}]}`))
+		}))
+		defer srv.Close()
+
+		route, err := geo.NewOSRM(srv.Client(), srv.URL).Route(context.Background(), gareDeLyon, tourEiffel)
+		if err != nil {
+			t.Fatalf("\t%s\t Test: \tShould compute the route, receive %v", failure, err)
+		}
+		if route.Distance != 7800.5 || route.Duration != 21*time.Minute || route.Source != geo.SourceOSRM {
+			t.Fatalf("\t%s\t Test: \tShould return the osrm route, receive %+v", failure, route)
+		}
+		if !strings.HasPrefix(path, "/route/v1/driving/2.374400,48.844300;2.294500,48.858400") {
+			t.Fatalf("\t%s\t Test: \tShould send the coordinates as longitude,latitude, receive %v", failure, path)
+		}
+		t.Logf("\t%s\t Test: \tShould return the osrm route", success)
+	}
+
+	t.Log("Given the need to fallback on the great-circle distance when OSRM fail")
+	{
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":"InvalidQuery","message":"invalid coordinates"}`))
+		}))
+		defer srv.Close()
+
+		router := geo.WithFallback(geo.NewOSRM(srv.Client(), srv.URL), geo.NewHaversineRouter())
+
+		route, err := router.Route(context.Background(), gareDeLyon, tourEiffel)
+		if err != nil || route.Source != geo.SourceHaversine || route.Distance <= 0 || route.Duration <= 0 {
+			t.Fatalf("\t%s\t Test: \tShould return the great-circle route, receive %+v and %v", failure, route, err)
+		}
+		t.Logf("\t%s\t Test: \tShould fallback on the great-circle route", success)
+	}
+}
+
+func Test_RouteVia(t *testing.T) {
+	t.Log("Given the need to route a trip through intermediate stops")
+	{
+		louvre := geo.Point{Latitude: 48.8606, Longitude: 2.3376}
+		router := geo.NewHaversineRouter()
+
+		route, err := geo.RouteVia(context.Background(), router, gareDeLyon, louvre, tourEiffel)
+		if err != nil {
+			t.Fatalf("\t%s\t Test: \tShould route the trip: %v", failure, err)
+		}
+
+		want := geo.Haversine(gareDeLyon, louvre) + geo.Haversine(louvre, tourEiffel)
+		if math.Abs(route.Distance-want) > 1 || route.Source != geo.SourceHaversine {
+			t.Fatalf("\t%s\t Test: \tShould sum the legs distance %v, receive %+v", failure, want, route)
+		}
+		t.Logf("\t%s\t Test: \tShould sum the distance of each leg", success)
+
+		if _, err := geo.RouteVia(context.Background(), router, gareDeLyon); err == nil {
+			t.Fatalf("\t%s\t Test: \tShould reject a route with a single point", failure)
+		}
+		t.Logf("\t%s\t Test: \tShould reject a route with a single point", success)
+	}
+}
+
+func Test_Matches(t *testing.T) {
+	t.Log("Given the need to cross-check the distance sent by the client")
+	{
+		tests := []struct {
+			computed float64
+			client   float64
+			match    bool
+		}{
+			{6000, 7000, true},
+			{6000, 0, false},
+			{6000, 12000, false},
+			{0, 0, true},
+		}
+
+		for _, tt := range tests {
+			if got := geo.Matches(tt.computed, tt.client, 30); got != tt.match {
+				t.Fatalf("\t%s\t Test: \tShould match %v and %v: %v, receive %v", failure, tt.computed, tt.client, tt.match, got)
+			}
+		}
+		t.Logf("\t%s\t Test: \tShould cross-check the client distance", success)
+	}
+}
+
+func Test_Coverage(t *testing.T) {
+	t.Log("Given the need to check if a point is inside a provider coverage")
+	{
+		// a square around paris with a hole around the tour eiffel, and a square around lyon
+		doc := `{"type":"FeatureCollection","features":[
+			{"type":"Feature","geometry":{"type":"Polygon","coordinates":[
+				[[2.22,48.81],[2.47,48.81],[2.47,48.91],[2.22,48.91],[2.22,48.81]],
+				[[2.28,48.85],[2.30,48.85],[2.30,48.87],[2.28,48.87],[2.28,48.85]]
+			]}},
+			{"type":"Feature","geometry":{"type":"MultiPolygon","coordinates":[
+				[[[4.77,45.70],[4.90,45.70],[4.90,45.80],[4.77,45.80],[4.77,45.70]]]
+			]}}
+		]}`
+
+		coverage, err := geo.ParseCoverage([]byte(doc))
+		if err != nil {
+			t.Fatalf("\t%s\t Test: \tShould parse the geojson, receive %v", failure, err)
+		}
+		t.Logf("\t%s\t Test: \tShould parse the geojson", success)
+
+		tests := []struct {
+			name  string
+			point geo.Point
+			in    bool
+		}{
+			{"gare de lyon", gareDeLyon, true},
+			{"tour eiffel", tourEiffel, false},
+			{"lyon", geo.Point{Latitude: 45.7640, Longitude: 4.8357}, true},
+			{"marseille", geo.Point{Latitude: 43.2965, Longitude: 5.3698}, false},
+		}
+
+		for _, tt := range tests {
+			if got := coverage.Contains(tt.point); got != tt.in {
+				t.Fatalf("\t%s\t Test: \tShould find %v inside the coverage: %v, receive %v", failure, tt.name, tt.in, got)
+			}
+		}
+		t.Logf("\t%s\t Test: \tShould check if points are inside the coverage", success)
+
+		if _, err := geo.ParseCoverage([]byte(`{"type":"Point","coordinates":[2.3,48.8]}`)); err == nil {
+			t.Fatalf("\t%s\t Test: \tShould reject unsupported geometries", failure)
+		}
+		t.Logf("\t%s\t Test: \tShould reject unsupported geometries", success)
+	}
+}
@@ -0,0 +1,64 @@
+package geo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// OSRM compute driving routes through an OSRM compatible http api, see http://project-osrm.org/docs/v5.24.0/api
+type OSRM struct {
+	Client  *http.Client
+	BaseURL string
+	Profile string
+}
+
+// NewOSRM create a router using the driving profile of the OSRM api at the given url
+func NewOSRM(client *http.Client, baseURL string) OSRM {
+	return OSRM{Client: client, BaseURL: baseURL, Profile: "driving"}
+}
+
+// Route return the fastest route between the two points
+func (o OSRM) Route(ctx context.Context, from, to Point) (Route, error) {
+	url := fmt.Sprintf("%v/route/v1/%v/%f,%f;%f,%f?overview=false", o.BaseURL, o.Profile, from.Longitude, from.Latitude, to.Longitude, to.Latitude)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Route{}, fmt.Errorf("failed to create osrm request: %v", err)
+	}
+
+	resp, err := o.Client.Do(req)
+	if err != nil {
+		return Route{}, fmt.Errorf("failed to fetch osrm route: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var data struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Routes  []struct {
+			Distance float64 `json:"distance"`
+			Duration float64 `json:"duration"`
+		} `json:"routes"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return Route{}, fmt.Errorf("failed to decode osrm response with status %v: %v", resp.StatusCode, err)
+	}
+
+	if resp.StatusCode != http.StatusOK || data.Code != "Ok" {
+		return Route{}, fmt.Errorf("osrm returned status %v and code %v: %v", resp.StatusCode, data.Code, data.Message)
+	}
+
+	if len(data.Routes) == 0 {
+		return Route{}, ErrNoRoute
+	}
+
+	return Route{
+		Distance: data.Routes[0].Distance,
+		Duration: time.Duration(data.Routes[0].Duration * float64(time.Second)),
+		Source:   SourceOSRM,
+	}, nil
+}
@@ -0,0 +1,266 @@
+// Package invoice generate the pdf invoices of the rides. The invoices carry the mentions required by the french
+// law: a unique sequential number, the legal identity of the seller, the vat breakdown and the late payment terms.
+// https://www.economie.gouv.fr/entreprises/factures-mentions-obligatoires
+package invoice
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Seller represent the legal identity of the company issuing the invoice
+type Seller struct {
+	Name      string
+	Address   string
+	SIRET     string
+	RCS       string
+	VATNumber string
+	Capital   string
+	// Mentions are additional legal mentions printed at the bottom of the invoice
+	Mentions []string
+}
+
+// Buyer represent the customer the invoice is addressed to
+type Buyer struct {
+	Name    string
+	Address string
+}
+
+// Line represent a service invoiced, the amounts are in euros
+type Line struct {
+	Description string
+	AmountHT    float64
+	VATPercent  float64
+	VATAmount   float64
+	AmountTTC   float64
+}
+
+// Document represent an invoice to render
+type Document struct {
+	Number string
+	Date   time.Time
+	PaidAt time.Time
+	// Outstanding is the part of the total that couldn't be charged, it is still due
+	Outstanding float64
+	Seller      Seller
+	Buyer       Buyer
+	Lines       []Line
+}
+
+// descriptionWidth is the number of characters of a line description printed before wrapping
+const descriptionWidth = 55
+
+// paymentTerms are the late payment terms every invoice must mention, even when it is already paid
+var paymentTerms = []string{
+	"Pas d'escompte pour paiement anticipé.",
+	"Pénalités de retard : trois fois le taux d'intérêt légal.",
+	"Indemnité forfaitaire pour frais de recouvrement : 40 €.",
+}
+
+// Number format the invoice number of the given sequence value, e.g. TGS-2026-000042
+func Number(prefix string, year int, seq int64) string {
+	return fmt.Sprintf("%s-%d-%06d", strings.ToUpper(prefix), year, seq)
+}
+
+// NewLine create a line of the given amount, vat included, and break it down on the given vat rate. The amounts are
+// rounded to the cent and the vat is deduced from the rounded amount excluding vat so the breakdown always add up.
+func NewLine(description string, amountTTC, vatPercent float64) Line {
+	ttc := roundPrice(amountTTC)
+	ht := roundPrice(ttc / (1 + vatPercent/100))
+
+	return Line{
+		Description: description,
+		AmountHT:    ht,
+		VATPercent:  vatPercent,
+		VATAmount:   roundPrice(ttc - ht),
+		AmountTTC:   ttc,
+	}
+}
+
+// Totals return the sum of the amounts of the lines of the invoice
+func (d Document) Totals() (ht, vat, ttc float64) {
+	for _, l := range d.Lines {
+		ht += l.AmountHT
+		vat += l.VATAmount
+		ttc += l.AmountTTC
+	}
+
+	return roundPrice(ht), roundPrice(vat), roundPrice(ttc)
+}
+
+// Validate check the document carry all the mandatory mentions
+func (d Document) Validate() error {
+	if len(d.Number) == 0 {
+		return fmt.Errorf("invoice is missing mandatory mentions: number")
+	}
+
+	return d.ValidateDraft()
+}
+
+// ValidateDraft check a document not numbered yet carry all the other mandatory mentions, so a number is only given
+// to an invoice that can be issued
+func (d Document) ValidateDraft() error {
+	var missing []string
+
+	if d.Date.IsZero() {
+		missing = append(missing, "date")
+	}
+	if len(d.Seller.Name) == 0 || len(d.Seller.Address) == 0 {
+		missing = append(missing, "seller identity")
+	}
+	if len(d.Seller.SIRET) == 0 {
+		missing = append(missing, "seller siret")
+	}
+	if len(d.Buyer.Name) == 0 {
+		missing = append(missing, "buyer name")
+	}
+	if len(d.Lines) == 0 {
+		missing = append(missing, "lines")
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("invoice %v is missing mandatory mentions: %v", d.Number, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// Render write the invoice as a single page pdf
+func Render(d Document) ([]byte, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+
+	p := newPage()
+
+	// seller identity
+	p.text(fontBold, 14, 50, 790, d.Seller.Name)
+	y := 772.0
+	for _, line := range sellerMentions(d.Seller) {
+		p.text(fontRegular, 9, 50, y, line)
+		y -= 12
+	}
+
+	// invoice identity
+	p.text(fontBold, 16, 350, 790, "FACTURE")
+	p.text(fontRegular, 10, 350, 772, "N° "+d.Number)
+	p.text(fontRegular, 10, 350, 758, "Date : "+formatDate(d.Date))
+
+	// buyer
+	p.text(fontBold, 10, 350, 720, "Facturé à")
+	p.text(fontRegular, 10, 350, 706, d.Buyer.Name)
+	if len(d.Buyer.Address) > 0 {
+		p.text(fontRegular, 10, 350, 692, d.Buyer.Address)
+	}
+
+	// lines
+	y = 640
+	p.text(fontBold, 10, 50, y, "Désignation")
+	p.text(fontBold, 10, 330, y, "Montant HT")
+	p.text(fontBold, 10, 410, y, "TVA")
+	p.text(fontBold, 10, 480, y, "Montant TTC")
+	p.line(50, y-6, 545, y-6)
+	y -= 22
+
+	for _, l := range d.Lines {
+		p.text(fontRegular, 9, 330, y, formatAmount(l.AmountHT))
+		p.text(fontRegular, 9, 410, y, fmt.Sprintf("%v %% : %s", l.VATPercent, formatAmount(l.VATAmount)))
+		p.text(fontRegular, 9, 480, y, formatAmount(l.AmountTTC))
+		for _, text := range wrap(l.Description, descriptionWidth) {
+			p.text(fontRegular, 9, 50, y, text)
+			y -= 12
+		}
+		y -= 4
+	}
+
+	// totals
+	ht, vat, ttc := d.Totals()
+	p.line(330, y+4, 545, y+4)
+	y -= 12
+	p.text(fontRegular, 10, 330, y, "Total HT")
+	p.text(fontRegular, 10, 480, y, formatAmount(ht))
+	y -= 14
+	p.text(fontRegular, 10, 330, y, "Total TVA")
+	p.text(fontRegular, 10, 480, y, formatAmount(vat))
+	y -= 14
+	p.text(fontBold, 10, 330, y, "Total TTC")
+	p.text(fontBold, 10, 480, y, formatAmount(ttc))
+
+	if !d.PaidAt.IsZero() {
+		y -= 30
+		if d.Outstanding > 0 {
+			paid := roundPrice(ttc - d.Outstanding)
+			p.text(fontRegular, 10, 50, y, fmt.Sprintf("Payée par carte bancaire le %s : %s.", formatDate(d.PaidAt), formatAmount(paid)))
+			y -= 14
+			p.text(fontBold, 10, 50, y, "Reste à payer : "+formatAmount(d.Outstanding)+".")
+		} else {
+			p.text(fontRegular, 10, 50, y, "Payée par carte bancaire le "+formatDate(d.PaidAt)+".")
+		}
+	}
+
+	// legal mentions
+	y = 110
+	for _, m := range append(append([]string{}, d.Seller.Mentions...), paymentTerms...) {
+		p.text(fontRegular, 8, 50, y, m)
+		y -= 11
+	}
+
+	return p.render(), nil
+}
+
+// sellerMentions return the legal identity lines of the seller, the empty ones are skipped
+func sellerMentions(s Seller) []string {
+	var lines []string
+
+	add := func(label, value string) {
+		if len(value) > 0 {
+			lines = append(lines, label+value)
+		}
+	}
+
+	add("", s.Address)
+	add("SIRET : ", s.SIRET)
+	add("RCS ", s.RCS)
+	add("Capital social : ", s.Capital)
+	add("N° TVA intracommunautaire : ", s.VATNumber)
+
+	return lines
+}
+
+// wrap split the text in lines of at most width characters, a word longer than the width is kept whole
+func wrap(text string, width int) []string {
+	var (
+		lines   []string
+		current string
+	)
+
+	for _, word := range strings.Fields(text) {
+		if len(current) > 0 && len([]rune(current))+1+len([]rune(word)) > width {
+			lines = append(lines, current)
+			current = ""
+		}
+		if len(current) > 0 {
+			current += " "
+		}
+		current += word
+	}
+
+	return append(lines, current)
+}
+
+// formatAmount format an amount in euros the french way, e.g. 1234.5 is 1234,50 €
+func formatAmount(amount float64) string {
+	return strings.Replace(fmt.Sprintf("%.2f €", amount), ".", ",", 1)
+}
+
+// formatDate format a date the french way
+func formatDate(t time.Time) string {
+	return t.Format("02/01/2006")
+}
+
+// roundPrice round the given price to the cent
+func roundPrice(price float64) float64 {
+	return math.Round(price*100) / 100
+}
@@ -0,0 +1,125 @@
+package invoice_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"vtc/business/v1/sys/invoice"
+)
+
+const (
+	success = "\u2713"
+	failure = "\u2717"
+)
+
+func Test_Number(t *testing.T) {
+	t.Log("Given the need to number the invoices sequentially")
+	{
+		if n := invoice.Number("tgs", 2026, 42); n != "TGS-2026-000042" {
+			t.Fatalf("\t%s\t Test: \tShould format the number with the prefix, year and sequence: got %v", failure, n)
+		}
+		t.Logf("\t%s\t Test: \tShould format the number with the prefix, year and sequence", success)
+	}
+}
+
+func Test_NewLine(t *testing.T) {
+	t.Log("Given the need to break down the vat of a ride price")
+	{
+		tests := []struct {
+			ttc, ht, vat float64
+		}{
+			{ttc: 22, ht: 20, vat: 2},
+			{ttc: 15.99, ht: 14.54, vat: 1.45},
+			{ttc: 0.01, ht: 0.01, vat: 0},
+		}
+
+		for _, tt := range tests {
+			l := invoice.NewLine("Course", tt.ttc, 10)
+			if l.AmountHT != tt.ht || l.VATAmount != tt.vat || l.AmountTTC != tt.ttc {
+				t.Fatalf("\t%s\t Test: \tShould break down %v into %v + %v: got %v + %v", failure, tt.ttc, tt.ht, tt.vat, l.AmountHT, l.VATAmount)
+			}
+		}
+		t.Logf("\t%s\t Test: \tShould break down the price into the amount excluding vat and the vat", success)
+	}
+}
+
+func Test_Render(t *testing.T) {
+	doc := invoice.Document{
+		Number: "TGS-2026-000001",
+		Date:   time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC),
+		PaidAt: time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC),
+		Seller: invoice.Seller{
+			Name:      "The Good Seat",
+			Address:   "1 rue de la Paix, 75002 Paris",
+			SIRET:     "12345678900012",
+			VATNumber: "FR12345678900",
+		},
+		Buyer: invoice.Buyer{Name: "Jean Dupont"},
+		Lines: []invoice.Line{invoice.NewLine("Course VTC (Paris)", 22, 10)},
+	}
+
+	t.Log("Given the need to render an invoice")
+	{
+		data, err := invoice.Render(doc)
+		if err != nil {
+			t.Fatalf("\t%s\t Test: \tShould be able to render the invoice: %v", failure, err)
+		}
+
+		if !bytes.HasPrefix(data, []byte("%PDF-1.4")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+			t.Fatalf("\t%s\t Test: \tShould render a pdf document", failure)
+		}
+		t.Logf("\t%s\t Test: \tShould render a pdf document", success)
+
+		for _, mention := range []string{"TGS-2026-000001", "SIRET : 12345678900012", "22,00 \x80", "Course VTC \\(Paris\\)", "P\xe9nalit\xe9s"} {
+			if !bytes.Contains(data, []byte(mention)) {
+				t.Fatalf("\t%s\t Test: \tShould print the mention %q", failure, mention)
+			}
+		}
+		t.Logf("\t%s\t Test: \tShould print the mandatory mentions", success)
+	}
+
+	t.Log("Given the need to mark the balance of a partially paid invoice")
+	{
+		partial := doc
+		partial.Outstanding = 4.5
+
+		data, err := invoice.Render(partial)
+		if err != nil {
+			t.Fatalf("\t%s\t Test: \tShould be able to render the invoice: %v", failure, err)
+		}
+
+		for _, mention := range []string{"17,50 \x80", "Reste \xe0 payer : 4,50 \x80"} {
+			if !bytes.Contains(data, []byte(mention)) {
+				t.Fatalf("\t%s\t Test: \tShould print the paid amount and the outstanding balance %q", failure, mention)
+			}
+		}
+		t.Logf("\t%s\t Test: \tShould print the paid amount and the outstanding balance", success)
+	}
+
+	t.Log("Given the need to check an invoice before numbering it")
+	{
+		draft := doc
+		draft.Number = ""
+
+		if err := draft.ValidateDraft(); err != nil {
+			t.Fatalf("\t%s\t Test: \tShould accept a complete draft without number: %v", failure, err)
+		}
+		if err := draft.Validate(); err == nil {
+			t.Fatalf("\t%s\t Test: \tShould refuse to issue an invoice without number", failure)
+		}
+		t.Logf("\t%s\t Test: \tShould only require the number to issue the invoice", success)
+	}
+
+	t.Log("Given the need to refuse an invoice without its mandatory mentions")
+	{
+		doc.Seller.SIRET = ""
+		if err := doc.ValidateDraft(); err == nil {
+			t.Fatalf("\t%s\t Test: \tShould refuse a draft without the seller siret", failure)
+		}
+		if _, err := invoice.Render(doc); err == nil {
+			t.Fatalf("\t%s\t Test: \tShould refuse an invoice without the seller siret", failure)
+		}
+		t.Logf("\t%s\t Test: \tShould refuse an invoice without the seller siret", success)
+	}
+}
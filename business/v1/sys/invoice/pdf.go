@@ -0,0 +1,94 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	fontRegular = "F1"
+	fontBold    = "F2"
+)
+
+// page is a minimal pdf writer drawing text and lines on a single A4 page with the standard helvetica fonts.
+// The text is encoded in WinAnsi so the french accents and the euro sign are rendered without embedding a font.
+type page struct {
+	content bytes.Buffer
+}
+
+func newPage() *page {
+	return &page{}
+}
+
+// text draw the text with its baseline starting at the given position, in points from the bottom left corner
+func (p *page) text(font string, size, x, y float64, s string) {
+	fmt.Fprintf(&p.content, "BT /%s %v Tf %v %v Td (%s) Tj ET\n", font, size, x, y, escape(s))
+}
+
+// line draw a thin line between the two positions
+func (p *page) line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(&p.content, "0.5 w %v %v m %v %v l S\n", x1, y1, x2, y2)
+}
+
+// render write the pdf document, the cross-reference table point to the byte offset of every object
+func (p *page) render() []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources << /Font << /F1 4 0 R /F2 5 0 R >> >> /Contents 6 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", p.content.Len(), p.content.String()),
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return buf.Bytes()
+}
+
+// winAnsi map the characters outside of latin-1 available in the WinAnsi encoding
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '–': 0x96, '—': 0x97,
+	'Œ': 0x8C, 'œ': 0x9C, 'Ÿ': 0x9F,
+}
+
+// escape encode the text in WinAnsi and escape the characters delimiting pdf strings, the characters that can't
+// be encoded are replaced by a question mark
+func escape(s string) string {
+	var b strings.Builder
+
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r < 0x80 && r >= 0x20:
+			b.WriteByte(byte(r))
+		case r >= 0xA0 && r <= 0xFF:
+			b.WriteByte(byte(r))
+		default:
+			if c, ok := winAnsi[r]; ok {
+				b.WriteByte(c)
+				continue
+			}
+			b.WriteByte('?')
+		}
+	}
+
+	return b.String()
+}
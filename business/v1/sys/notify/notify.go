@@ -0,0 +1,44 @@
+// Package notify send notifications to the users about their rides
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// List of values that Notification.Kind can take
+const (
+	KindReservationReminder = "reservation_reminder"
+	KindRideRebooked        = "ride_rebooked"
+	KindReservationFailed   = "reservation_failed"
+)
+
+// Notification is a message sent to a user about one of its rides
+type Notification struct {
+	UserID  string    `json:"userID"`
+	RideID  string    `json:"rideID"`
+	Kind    string    `json:"kind"`
+	Message string    `json:"message"`
+	Date    time.Time `json:"date"`
+}
+
+// Notifier deliver notifications to the users
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// LogNotifier write the notifications to the logs, it is used when no delivery channel is available
+type LogNotifier struct{}
+
+// Notify log the notification
+func (LogNotifier) Notify(_ context.Context, n Notification) error {
+	b, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("notification: %s", b)
+	return nil
+}
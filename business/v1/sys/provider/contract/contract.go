@@ -0,0 +1,245 @@
+// Package contract provide the conformance checks every provider integration must pass. A provider is run
+// against a scripted fake backend and its results are checked against the behaviour expected by the core.
+package contract
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/provider"
+	"vtc/business/v1/sys/provider/fake"
+)
+
+const (
+	success = "\u2713"
+	failure = "\u2717"
+)
+
+// Subject is a provider implementation checked against the contract
+type Subject struct {
+	// Name of the provider, the offers must carry it and the backend scenarios are set for it
+	Name string
+	// Backend is the scripted server the provider talk to
+	Backend *fake.Server
+	// New create the provider targeting the given backend server
+	New func(srv *httptest.Server) provider.IProvider
+}
+
+// progression is played by the backend during the ride lifecycle check
+var progression = fake.Scenario{
+	Price:            20,
+	CancellationFees: 5,
+	Progression: []fake.Step{
+		{After: 0, Status: provider.Processing},
+		{After: time.Minute, Status: provider.Accepted},
+		{After: 3 * time.Minute, Status: provider.Arriving},
+		{After: 5 * time.Minute, Status: provider.InProgress},
+		{After: 15 * time.Minute, Status: provider.Completed},
+	},
+}
+
+// Run check that the subject conform to the provider contract
+func Run(t *testing.T, s Subject) {
+	checks := []struct {
+		name  string
+		check func(t *testing.T, s Subject, p provider.IProvider, now time.Time)
+	}{
+		{"offers", checkOffers},
+		{"no cars", checkNoCars},
+		{"bad request", checkBadRequest},
+		{"ride lifecycle", checkLifecycle},
+		{"cancellation", checkCancellation},
+	}
+
+	for _, c := range checks {
+		t.Run(s.Name+"/"+c.name, func(t *testing.T) {
+			now := time.Now()
+			s.Backend.Now = func() time.Time { return now }
+			s.Backend.SetScenario(s.Name, progression)
+
+			srv := httptest.NewServer(s.Backend.Handler())
+			defer srv.Close()
+
+			c.check(t, s, s.New(srv), now)
+		})
+	}
+}
+
+func search(now time.Time) models.Search {
+	return models.Search{
+		ID:             "contract-search",
+		UserID:         "contract-user",
+		Aggregator:     "contract",
+		StartAddress:   "Gare de Lyon, Paris",
+		StartCountry:   "FR",
+		StartLatitude:  48.8443,
+		StartLongitude: 2.3744,
+		EndAddress:     "Tour Eiffel, Paris",
+		EndCountry:     "FR",
+		EndLatitude:    48.8584,
+		EndLongitude:   2.2945,
+		NbrOfPassenger: 1,
+		StartDate:      now,
+	}
+}
+
+func checkOffers(t *testing.T, s Subject, p provider.IProvider, now time.Time) {
+	t.Log("Given the need to get offers from a provider")
+	{
+		sr := search(now)
+
+		offers, err := p.GetOffers(context.Background(), provider.UserInfo{}, sr, now)
+		if err != nil {
+			t.Fatalf("\t%s\t Test: \tShould get offers, receive %v", failure, err)
+		}
+		if len(offers) == 0 {
+			t.Fatalf("\t%s\t Test: \tShould get at least one offer", failure)
+		}
+
+		for _, o := range offers {
+			switch {
+			case len(o.ID) == 0:
+				t.Fatalf("\t%s\t Test: \tShould set the offer id", failure)
+			case o.Provider != s.Name:
+				t.Fatalf("\t%s\t Test: \tShould set the provider to %v, receive %q", failure, s.Name, o.Provider)
+			case o.Search.ID != sr.ID || o.UserID != sr.UserID || o.Aggregator != sr.Aggregator:
+				t.Fatalf("\t%s\t Test: \tShould keep the search inside the offer, receive %+v", failure, o.Search)
+			case o.ProviderPrice <= 0:
+				t.Fatalf("\t%s\t Test: \tShould set a positive provider price, receive %v", failure, o.ProviderPrice)
+			case len(o.ProviderOfferID) == 0 || len(o.ProviderOfferName) == 0:
+				t.Fatalf("\t%s\t Test: \tShould set the provider offer id and name", failure)
+			case !o.ExpiresAt.After(now):
+				t.Fatalf("\t%s\t Test: \tShould set a validity window ending after now, receive %v", failure, o.ExpiresAt)
+			case !contains(provider.VehicleTypes, o.VehicleType):
+				t.Fatalf("\t%s\t Test: \tShould map %v onto a shared vehicle type, receive %q", failure, o.ProviderOfferName, o.VehicleType)
+			}
+		}
+		t.Logf("\t%s\t Test: \tShould return complete offers", success)
+	}
+}
+
+func checkNoCars(t *testing.T, s Subject, p provider.IProvider, now time.Time) {
+	t.Log("Given the need to handle a provider without available cars")
+	{
+		s.Backend.SetScenario(s.Name, fake.Scenarios["no_cars"])
+
+		offers, err := p.GetOffers(context.Background(), provider.UserInfo{}, search(now), now)
+		if err != nil || len(offers) != 0 {
+			t.Fatalf("\t%s\t Test: \tShould return no offer and no error, receive %v offers and %v", failure, len(offers), err)
+		}
+		t.Logf("\t%s\t Test: \tShould return no offer", success)
+	}
+}
+
+func checkBadRequest(t *testing.T, s Subject, p provider.IProvider, now time.Time) {
+	t.Log("Given the need to surface the provider errors")
+	{
+		s.Backend.SetScenario(s.Name, fake.Scenarios["bad_request"])
+
+		_, err := p.GetOffers(context.Background(), provider.UserInfo{}, search(now), now)
+		if category := provider.CategorizeError(err); category != provider.ErrCategoryBadRequest {
+			t.Fatalf("\t%s\t Test: \tShould return a %v error, receive %v (%v)", failure, provider.ErrCategoryBadRequest, category, err)
+		}
+		t.Logf("\t%s\t Test: \tShould return a categorized error", success)
+	}
+}
+
+func checkLifecycle(t *testing.T, s Subject, p provider.IProvider, now time.Time) {
+	t.Log("Given the need to follow a ride through its lifecycle")
+	{
+		ride := book(t, p, now)
+		machine := provider.NewStatusMachine()
+
+		for _, step := range progression.Progression {
+			at := now.Add(step.After)
+			s.Backend.Now = func() time.Time { return at }
+
+			pr, err := p.GetRide(context.Background(), ride)
+			if err != nil {
+				t.Fatalf("\t%s\t Test: \tShould get the ride after %v, receive %v", failure, step.After, err)
+			}
+			if !contains(provider.Statuses, pr.Status) {
+				t.Fatalf("\t%s\t Test: \tShould map %q onto a shared status, receive %q", failure, pr.StatusName, pr.Status)
+			}
+			if err := machine.Transition(&ride, pr.Status, provider.SourceProviderPoll, at); err != nil {
+				t.Fatalf("\t%s\t Test: \tShould follow the legal transitions, receive %v", failure, err)
+			}
+			if pr.Id != ride.ProviderRideID {
+				t.Fatalf("\t%s\t Test: \tShould keep the provider ride id, receive %q", failure, pr.Id)
+			}
+		}
+
+		if ride.Status != provider.Completed {
+			t.Fatalf("\t%s\t Test: \tShould complete the ride, receive %v", failure, ride.Status)
+		}
+		t.Logf("\t%s\t Test: \tShould follow the ride until its completion", success)
+	}
+}
+
+func checkCancellation(t *testing.T, s Subject, p provider.IProvider, now time.Time) {
+	t.Log("Given the need to cancel a ride")
+	{
+		ride := book(t, p, now)
+
+		quote, err := p.GetCancellationFees(context.Background(), ride, now)
+		if err != nil || quote.Amount < 0 || len(quote.Currency) == 0 {
+			t.Fatalf("\t%s\t Test: \tShould quote the cancellation fees, receive %+v and %v", failure, quote, err)
+		}
+		t.Logf("\t%s\t Test: \tShould quote the cancellation fees", success)
+
+		for i := 0; i < 2; i++ {
+			pr, err := p.CancelRide(context.Background(), ride)
+			if err != nil {
+				t.Fatalf("\t%s\t Test: \tShould cancel the ride on call %v, receive %v", failure, i+1, err)
+			}
+			if pr.Status != provider.Cancelled {
+				t.Fatalf("\t%s\t Test: \tShould return a cancelled ride on call %v, receive %v", failure, i+1, pr.Status)
+			}
+		}
+		t.Logf("\t%s\t Test: \tShould cancel a ride idempotently", success)
+	}
+}
+
+// book request a ride from the first offer of the provider
+func book(t *testing.T, p provider.IProvider, now time.Time) models.Ride {
+	sr := search(now)
+
+	offers, err := p.GetOffers(context.Background(), provider.UserInfo{}, sr, now)
+	if err != nil || len(offers) == 0 {
+		t.Fatalf("\t%s\t Test: \tShould get an offer to book, receive %v", failure, err)
+	}
+
+	pr, err := p.RequestRide(context.Background(), offers[0], provider.UserInfo{}, sr, now)
+	if err != nil {
+		t.Fatalf("\t%s\t Test: \tShould request a ride, receive %v", failure, err)
+	}
+	if len(pr.Id) == 0 {
+		t.Fatalf("\t%s\t Test: \tShould return the provider ride id", failure)
+	}
+	if !contains(provider.Statuses, pr.Status) {
+		t.Fatalf("\t%s\t Test: \tShould map %q onto a shared status, receive %q", failure, pr.StatusName, pr.Status)
+	}
+
+	ride := models.Ride{
+		ProviderRideID: pr.Id,
+		ProviderName:   offers[0].Provider,
+		ProviderPrice:  offers[0].ProviderPrice,
+		CreatedAt:      now.String(),
+	}
+	provider.NewStatusMachine().Transition(&ride, pr.Status, provider.SourceUser, now)
+
+	return ride
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,35 @@
+package provider_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"vtc/business/v1/sys/provider"
+	"vtc/business/v1/sys/provider/contract"
+	"vtc/business/v1/sys/provider/fake"
+	"vtc/foundation/config"
+)
+
+func Test_Contract(t *testing.T) {
+	backend := fake.New(fake.Nominal)
+
+	contract.Run(t, contract.Subject{
+		Name:    provider.MySamName,
+		Backend: backend,
+		New: func(srv *httptest.Server) provider.IProvider {
+			var cfg config.App
+			cfg.Env.Providers.MySam.BaseURL = srv.URL + fake.MySamPath
+			return provider.NewMySam(srv.Client(), &cfg)
+		},
+	})
+
+	contract.Run(t, contract.Subject{
+		Name:    provider.UberName,
+		Backend: backend,
+		New: func(srv *httptest.Server) provider.IProvider {
+			var cfg config.App
+			cfg.Env.Providers.Uber.BaseURL = srv.URL + fake.UberPath
+			return provider.NewUber(srv.Client(), &cfg)
+		},
+	})
+}
@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// List of values that ProviderResult.ErrorCategory can take
+const (
+	ErrCategoryTimeout     = "timeout"
+	ErrCategoryAuth        = "auth"
+	ErrCategoryBadRequest  = "bad_request"
+	ErrCategoryUnavailable = "unavailable"
+	ErrCategoryNotServed   = "not_served"
+	ErrCategoryNoStops     = "stops_unsupported"
+)
+
+// StatusError is returned when a provider answer with an unexpected http status
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return e.Message
+}
+
+func newStatusError(statusCode int, format string, args ...any) error {
+	return &StatusError{StatusCode: statusCode, Message: fmt.Sprintf(format, args...)}
+}
+
+// CategorizeError return the category of an error returned by a provider
+func CategorizeError(err error) string {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return ErrCategoryTimeout
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return ErrCategoryAuth
+		case http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity:
+			return ErrCategoryBadRequest
+		case http.StatusGatewayTimeout, http.StatusRequestTimeout:
+			return ErrCategoryTimeout
+		}
+	}
+
+	return ErrCategoryUnavailable
+}
@@ -0,0 +1,36 @@
+package provider_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"vtc/business/v1/sys/provider"
+)
+
+func Test_CategorizeError(t *testing.T) {
+	t.Log("Given the need to categorize the errors returned by providers")
+	{
+		tests := []struct {
+			err      error
+			category string
+		}{
+			{fmt.Errorf("failed: %w", context.DeadlineExceeded), provider.ErrCategoryTimeout},
+			{&provider.StatusError{StatusCode: http.StatusUnauthorized}, provider.ErrCategoryAuth},
+			{fmt.Errorf("wrapped: %w", &provider.StatusError{StatusCode: http.StatusForbidden}), provider.ErrCategoryAuth},
+			{&provider.StatusError{StatusCode: http.StatusBadRequest}, provider.ErrCategoryBadRequest},
+			{&provider.StatusError{StatusCode: http.StatusGatewayTimeout}, provider.ErrCategoryTimeout},
+			{&provider.StatusError{StatusCode: http.StatusServiceUnavailable}, provider.ErrCategoryUnavailable},
+			{errors.New("connection refused"), provider.ErrCategoryUnavailable},
+		}
+
+		for _, tt := range tests {
+			if got := provider.CategorizeError(tt.err); got != tt.category {
+				t.Fatalf("\t%s\t Test: \tShould categorize %v as %v, receive %v", failure, tt.err, tt.category, got)
+			}
+		}
+		t.Logf("\t%s\t Test: \tShould categorize provider errors", success)
+	}
+}
@@ -0,0 +1,238 @@
+// Package fake provide a local server answering the MySam and Uber endpoints used by the provider integrations,
+// so the providers can be exercised offline. Its behaviour is driven by scenarios.
+package fake
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"vtc/business/v1/sys/provider"
+)
+
+// Path prefix of each provider api, the provider base url is the server url followed by the prefix
+const (
+	MySamPath = "/mysam"
+	UberPath  = "/uber"
+)
+
+// Step is a status reached by a ride once the given duration is elapsed since its creation
+type Step struct {
+	After  time.Duration
+	Status string
+}
+
+// Scenario describe how the fake providers answer
+type Scenario struct {
+	// NoCars return no offer at all
+	NoCars bool
+	// FailWith is the status code returned by every endpoint when set
+	FailWith int
+	// Latency delay every answer
+	Latency time.Duration
+	// Progression is the list of statuses a ride goes through, using the common provider statuses
+	Progression []Step
+	// Price of the offers and of the completed rides
+	Price float64
+	// CancellationFees returned by the providers when a ride is cancelled
+	CancellationFees float64
+}
+
+// Nominal is the default scenario, a driver is found quickly and the ride is completed after 10 minutes
+var Nominal = Scenario{
+	Price: 25,
+	Progression: []Step{
+		{After: 0, Status: provider.Processing},
+		{After: 30 * time.Second, Status: provider.Accepted},
+		{After: 2 * time.Minute, Status: provider.Arriving},
+		{After: 4 * time.Minute, Status: provider.InProgress},
+		{After: 10 * time.Minute, Status: provider.Completed},
+	},
+}
+
+// Scenarios list the named scenarios that can be selected when running the fake server as a binary
+var Scenarios = map[string]Scenario{
+	"nominal":     Nominal,
+	"no_cars":     {NoCars: true},
+	"bad_request": {FailWith: http.StatusBadRequest},
+	"unavailable": {FailWith: http.StatusServiceUnavailable},
+	"slow":        {Price: Nominal.Price, Progression: Nominal.Progression, Latency: 10 * time.Second},
+	"no_driver": {
+		Price: Nominal.Price,
+		Progression: []Step{
+			{After: 0, Status: provider.Processing},
+			{After: time.Minute, Status: provider.NoDriverFound},
+		},
+	},
+}
+
+type ride struct {
+	id        string
+	createdAt time.Time
+	cancelled bool
+}
+
+// Server fake the providers api
+type Server struct {
+	// Now is the clock used to compute the rides progression, it can be replaced to move the time forward
+	Now func() time.Time
+
+	mu        sync.Mutex
+	scenarios map[string]Scenario
+	rides     map[string]*ride
+	received  map[string][]byte
+	nextID    int
+}
+
+// New create a fake server applying the given scenario to all the providers
+func New(sc Scenario) *Server {
+	return &Server{
+		Now:       time.Now,
+		scenarios: map[string]Scenario{provider.MySamName: sc, provider.UberName: sc},
+		rides:     map[string]*ride{},
+		received:  map[string][]byte{},
+	}
+}
+
+// SetScenario change the scenario of a single provider
+func (s *Server) SetScenario(name string, sc Scenario) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scenarios[name] = sc
+}
+
+// LastRequest return the json body of the last request received on the given path, e.g. UberPath+"/createRide"
+func (s *Server) LastRequest(path string) map[string]any {
+	s.mu.Lock()
+	body := s.received[path]
+	s.mu.Unlock()
+
+	var v map[string]any
+	json.Unmarshal(body, &v)
+
+	return v
+}
+
+// Handler return the handler serving both provider apis
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(MySamPath+"/estimation/all", s.serve(provider.MySamName, s.mySamOffers))
+	mux.HandleFunc(MySamPath+"/trips/new", s.serve(provider.MySamName, s.mySamCreateRide))
+	mux.HandleFunc(MySamPath+"/trips/", s.serve(provider.MySamName, s.mySamTrip))
+
+	mux.HandleFunc(UberPath+"/getProductEstimates", s.serve(provider.UberName, s.uberOffers))
+	mux.HandleFunc(UberPath+"/createRide", s.serve(provider.UberName, s.uberCreateRide))
+	mux.HandleFunc(UberPath+"/getRide", s.serve(provider.UberName, s.uberGetRide))
+	mux.HandleFunc(UberPath+"/cancelRide", s.serve(provider.UberName, s.uberCancelRide))
+
+	return mux
+}
+
+// serve apply the provider scenario latency and failure before calling the endpoint handler
+func (s *Server) serve(name string, h func(w http.ResponseWriter, r *http.Request, sc Scenario)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		s.mu.Lock()
+		sc := s.scenarios[name]
+		s.received[r.URL.Path] = body
+		s.mu.Unlock()
+
+		if sc.Latency > 0 {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(sc.Latency):
+			}
+		}
+
+		if sc.FailWith != 0 {
+			w.WriteHeader(sc.FailWith)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("fake %v scenario error", name)})
+			return
+		}
+
+		h(w, r, sc)
+	}
+}
+
+// newRide register a new ride and return its id
+func (s *Server) newRide() *ride {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	r := &ride{id: strconv.Itoa(s.nextID), createdAt: s.Now()}
+	s.rides[r.id] = r
+
+	return r
+}
+
+// findRide return the ride with the given id
+func (s *Server) findRide(id string) (*ride, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.rides[id]
+	return r, ok
+}
+
+// cancelRide mark the ride with the given id as cancelled
+func (s *Server) cancelRide(id string) (*ride, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.rides[id]
+	if ok {
+		r.cancelled = true
+	}
+
+	return r, ok
+}
+
+// status return the common provider status reached by the ride
+func (s *Server) status(r *ride, sc Scenario) string {
+	if r.cancelled {
+		return provider.Cancelled
+	}
+
+	elapsed := s.Now().Sub(r.createdAt)
+	status := provider.Processing
+
+	for _, step := range sc.Progression {
+		if elapsed >= step.After {
+			status = step.Status
+		}
+	}
+
+	return status
+}
+
+// hasDriver check if a driver is assigned to a ride having the given status
+func hasDriver(status string) bool {
+	switch status {
+	case provider.Accepted, provider.Arriving, provider.InProgress, provider.Completed:
+		return true
+	default:
+		return false
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// trimPrefix return the path of the request without the given prefix
+func trimPrefix(r *http.Request, prefix string) string {
+	return strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
+}
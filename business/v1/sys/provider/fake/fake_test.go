@@ -0,0 +1,146 @@
+package fake_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/provider"
+	"vtc/business/v1/sys/provider/fake"
+	"vtc/foundation/config"
+)
+
+const (
+	success = "\u2713"
+	failure = "\u2717"
+)
+
+// newProviders create the mysam and uber integrations targeting the fake server
+func newProviders(srv *httptest.Server) []provider.IProvider {
+	var cfg config.App
+	cfg.Env.Providers.MySam.BaseURL = srv.URL + fake.MySamPath
+	cfg.Env.Providers.Uber.BaseURL = srv.URL + fake.UberPath
+
+	return []provider.IProvider{
+		provider.NewMySam(srv.Client(), &cfg),
+		provider.NewUber(srv.Client(), &cfg),
+	}
+}
+
+func Test_Progression(t *testing.T) {
+	t.Log("Given the need to follow a ride through the fake providers")
+	{
+		// mysam has no arriving status, the progression only use the statuses shared by both providers
+		sc := fake.Scenario{
+			Price: 20,
+			Progression: []fake.Step{
+				{After: 0, Status: provider.Processing},
+				{After: time.Minute, Status: provider.Accepted},
+				{After: 5 * time.Minute, Status: provider.InProgress},
+				{After: 15 * time.Minute, Status: provider.Completed},
+			},
+		}
+
+		now := time.Now()
+		f := fake.New(sc)
+		f.Now = func() time.Time { return now }
+
+		srv := httptest.NewServer(f.Handler())
+		defer srv.Close()
+
+		for _, p := range newProviders(srv) {
+			ctx := context.Background()
+			s := models.Search{NbrOfPassenger: 1}
+
+			offers, err := p.GetOffers(ctx, provider.UserInfo{}, s, now)
+			if err != nil || len(offers) == 0 {
+				t.Fatalf("\t%s\t Test: \tShould get offers, receive %v offers and error %v", failure, len(offers), err)
+			}
+			t.Logf("\t%s\t Test: \tShould get offers from %v", success, offers[0].Provider)
+
+			pr, err := p.RequestRide(ctx, offers[0], provider.UserInfo{}, s, now)
+			if err != nil {
+				t.Fatalf("\t%s\t Test: \tShould request a ride, receive %v", failure, err)
+			}
+
+			ride := models.Ride{ProviderRideID: pr.Id, ProviderPrice: offers[0].ProviderPrice}
+			for _, step := range sc.Progression {
+				at := now.Add(step.After)
+				f.Now = func() time.Time { return at }
+
+				pr, err := p.GetRide(ctx, ride)
+				if err != nil {
+					t.Fatalf("\t%s\t Test: \tShould get the ride, receive %v", failure, err)
+				}
+				if pr.Status != step.Status {
+					t.Fatalf("\t%s\t Test: \tShould reach status %v after %v, receive %v", failure, step.Status, step.After, pr.Status)
+				}
+			}
+			t.Logf("\t%s\t Test: \tShould follow the status progression", success)
+
+			if _, err := p.CancelRide(ctx, ride); err != nil {
+				t.Fatalf("\t%s\t Test: \tShould cancel the ride, receive %v", failure, err)
+			}
+			t.Logf("\t%s\t Test: \tShould cancel the ride", success)
+
+			f.Now = func() time.Time { return now }
+		}
+	}
+}
+
+func Test_Scenarios(t *testing.T) {
+	t.Log("Given the need to play failing scenarios")
+	{
+		tests := []struct {
+			name     string
+			scenario fake.Scenario
+			offers   int
+			category string
+		}{
+			{"no_cars", fake.Scenarios["no_cars"], 0, ""},
+			{"bad_request", fake.Scenarios["bad_request"], 0, provider.ErrCategoryBadRequest},
+			{"unavailable", fake.Scenarios["unavailable"], 0, provider.ErrCategoryUnavailable},
+		}
+
+		for _, tt := range tests {
+			srv := httptest.NewServer(fake.New(tt.scenario).Handler())
+
+			for _, p := range newProviders(srv) {
+				offers, err := p.GetOffers(context.Background(), provider.UserInfo{}, models.Search{}, time.Now())
+				if len(offers) != tt.offers {
+					t.Fatalf("\t%s\t Test %v: \tShould get %v offers, receive %v", failure, tt.name, tt.offers, len(offers))
+				}
+				if len(tt.category) > 0 && provider.CategorizeError(err) != tt.category {
+					t.Fatalf("\t%s\t Test %v: \tShould fail with a %v error, receive %v", failure, tt.name, tt.category, err)
+				}
+				if len(tt.category) == 0 && err != nil {
+					t.Fatalf("\t%s\t Test %v: \tShould not fail, receive %v", failure, tt.name, err)
+				}
+			}
+			t.Logf("\t%s\t Test %v: \tShould play the scenario", success, tt.name)
+
+			srv.Close()
+		}
+	}
+
+	t.Log("Given the need to play a slow provider")
+	{
+		srv := httptest.NewServer(fake.New(fake.Scenario{Latency: 200 * time.Millisecond}).Handler())
+		defer srv.Close()
+
+		client := srv.Client()
+		client.Timeout = 20 * time.Millisecond
+
+		var cfg config.App
+		cfg.Env.Providers.MySam.BaseURL = srv.URL + fake.MySamPath
+		mysam := provider.NewMySam(client, &cfg)
+
+		_, err := mysam.GetOffers(context.Background(), provider.UserInfo{}, models.Search{}, time.Now())
+		if err == nil {
+			t.Fatalf("\t%s\t Test: \tShould time out", failure)
+		}
+		t.Logf("\t%s\t Test: \tShould time out on a slow provider", success)
+	}
+}
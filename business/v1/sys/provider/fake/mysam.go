@@ -0,0 +1,98 @@
+package fake
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"vtc/business/v1/sys/provider"
+)
+
+// mySamStatus map the common provider statuses to the mysam ones
+var mySamStatus = map[string]string{
+	provider.Processing:    "WAITING",
+	provider.Accepted:      "ASSIGNED",
+	provider.Arriving:      "ASSIGNED",
+	provider.InProgress:    "STARTED",
+	provider.Completed:     "FINISHED",
+	provider.Cancelled:     "CANCELED",
+	provider.NoDriverFound: "NO_DRIVER_AVAILABLE",
+}
+
+func (s *Server) mySamOffers(w http.ResponseWriter, r *http.Request, sc Scenario) {
+	offers := []provider.MySamOffer{}
+
+	if !sc.NoCars {
+		for i, vehicle := range []string{"CAR", "VAN", "LUXE"} {
+			var offer provider.MySamOffer
+			offer.Estimation.Id = i + 1
+			offer.Estimation.VehicleType = vehicle
+			offer.Estimation.TripType = "IMMEDIATE"
+			offer.Estimation.StartDate = s.Now().UnixMilli()
+			offer.Estimation.Created = s.Now().UnixMilli()
+			offer.Estimation.Duration = float64(300 * (i + 1))
+			offer.Estimation.Price = sc.Price * float64(i+1)
+			offers = append(offers, offer)
+		}
+	}
+
+	writeJSON(w, offers)
+}
+
+func (s *Server) mySamCreateRide(w http.ResponseWriter, r *http.Request, sc Scenario) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	ride := s.newRide()
+	writeJSON(w, s.mySamRide(ride, sc))
+}
+
+// mySamTrip answer both trips/{id} and trips/{id}/cancel
+func (s *Server) mySamTrip(w http.ResponseWriter, r *http.Request, sc Scenario) {
+	path := trimPrefix(r, MySamPath+"/trips")
+
+	if strings.HasSuffix(path, "/cancel") {
+		ride, found := s.cancelRide(strings.TrimSuffix(path, "/cancel"))
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		res := s.mySamRide(ride, sc)
+		res.CancellationFees = sc.CancellationFees
+		writeJSON(w, res)
+		return
+	}
+
+	ride, found := s.findRide(path)
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, s.mySamRide(ride, sc))
+}
+
+func (s *Server) mySamRide(r *ride, sc Scenario) provider.MySamRide {
+	id, _ := strconv.Atoi(r.id)
+	status := s.status(r, sc)
+
+	res := provider.MySamRide{
+		Id:             id,
+		Status:         mySamStatus[status],
+		StartDate:      r.createdAt.UnixMilli(),
+		EstimatedPrice: sc.Price,
+	}
+
+	if status == provider.Completed {
+		res.FinalPrice = sc.Price
+	}
+
+	if hasDriver(status) {
+		res.Driver = &provider.MySamDriver{FirstName: "John", LastName: "Doe", MobilePhoneNumber: "+33600000000"}
+	}
+
+	return res
+}
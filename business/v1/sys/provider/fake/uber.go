@@ -0,0 +1,125 @@
+package fake
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"vtc/business/v1/sys/provider"
+)
+
+// uberStatus map the common provider statuses to the uber ones
+var uberStatus = map[string]string{
+	provider.Processing:      "processing",
+	provider.Accepted:        "accepted",
+	provider.Arriving:        "arriving",
+	provider.InProgress:      "in_progress",
+	provider.Completed:       "completed",
+	provider.Cancelled:       "rider_canceled",
+	provider.DriverCancelled: "driver_canceled",
+	provider.NoDriverFound:   "no_drivers_available",
+	provider.Scheduled:       "scheduled",
+}
+
+func (s *Server) uberOffers(w http.ResponseWriter, r *http.Request, sc Scenario) {
+	res := provider.UberResponseOffer{Status: "success"}
+
+	if sc.NoCars {
+		res.Data.FaresUnavailable = true
+		res.Data.ProductEstimates = []provider.ProductEstimates{}
+		writeJSON(w, res)
+		return
+	}
+
+	for i, name := range []string{"UberX", "Green", "UberXL"} {
+		res.Data.ProductEstimates = append(res.Data.ProductEstimates, provider.ProductEstimates{
+			Product: provider.Product{
+				ProductID:                      "product-" + name,
+				DisplayName:                    name,
+				Capacity:                       4 + 2*(i/2),
+				VVID:                           i + 1,
+				CancellationFee:                sc.CancellationFees,
+				CancellationGracePeriodSeconds: 120,
+			},
+			Estimate: provider.Estimate{
+				FareID:                  "fare-" + name,
+				PickupEstimateInMinutes: 3 * (i + 1),
+				Fare:                    provider.Fare{FareValue: sc.Price * float64(i+1), CurrencyCode: "eur"},
+			},
+		})
+	}
+
+	// a product unknown to the integration, it must be filtered out
+	res.Data.ProductEstimates = append(res.Data.ProductEstimates, provider.ProductEstimates{
+		Product:  provider.Product{ProductID: "product-Helicopter", DisplayName: "Helicopter"},
+		Estimate: provider.Estimate{FareID: "fare-Helicopter", Fare: provider.Fare{FareValue: 999, CurrencyCode: "eur"}},
+	})
+
+	writeJSON(w, res)
+}
+
+func (s *Server) uberCreateRide(w http.ResponseWriter, r *http.Request, sc Scenario) {
+	ride := s.newRide()
+	writeJSON(w, s.uberRide(ride, sc))
+}
+
+func (s *Server) uberGetRide(w http.ResponseWriter, r *http.Request, sc Scenario) {
+	var body struct {
+		RideUUID string `json:"rideUUID"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	ride, found := s.findRide(body.RideUUID)
+	if !found {
+		writeJSON(w, provider.UberResponseRide{Status: "success"})
+		return
+	}
+
+	writeJSON(w, s.uberRide(ride, sc))
+}
+
+func (s *Server) uberCancelRide(w http.ResponseWriter, r *http.Request, sc Scenario) {
+	var body struct {
+		RideUUID string `json:"rideUUID"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if _, found := s.cancelRide(body.RideUUID); !found {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, map[string]string{"status": "success"})
+}
+
+func (s *Server) uberRide(r *ride, sc Scenario) provider.UberResponseRide {
+	status := s.status(r, sc)
+
+	var data provider.UberRideData
+	data.UUID = r.id
+	data.RideDetails.Status = uberStatus[status]
+	data.RideDetails.Pickup.Eta = 180
+
+	if status == provider.Completed {
+		data.RideDetails.ClientFareNumeric = sc.Price
+	}
+
+	if hasDriver(status) {
+		data.AcceptedAt = r.createdAt.String()
+		data.Driver = provider.Driver{Name: "John Doe", PhoneNumber: "+33600000000"}
+		data.Vehicle = provider.Vehicle{CarName: "Toyota Prius", LicensePlate: "AA-123-AA"}
+		data.RideDetails.DriverLocation.Latitude = 48.85
+		data.RideDetails.DriverLocation.Longitude = 2.35
+		data.RideDetails.DriverLocation.Bearing = 90
+	}
+
+	res := provider.UberResponseRide{Status: "success"}
+	res.Data.Rides = []provider.UberRideData{data}
+
+	return res
+}
@@ -0,0 +1,62 @@
+package provider
+
+import (
+	"time"
+
+	"vtc/business/v1/data/models"
+)
+
+// List of values that CancellationQuote.Reason can take
+const (
+	FeeReasonNoDriverAssigned  = "no_driver_assigned"
+	FeeReasonGracePeriod       = "grace_period"
+	FeeReasonLateCancellation  = "late_cancellation"
+	FeeReasonNoCancellationFee = "no_cancellation_fee"
+)
+
+// FeePolicy describe how a provider charge the cancellation of a ride. The cancellation is free until a driver is
+// assigned and during the grace period that follow the assignment, the amount is charged afterwards.
+type FeePolicy struct {
+	Amount      float64
+	Currency    string
+	GracePeriod time.Duration
+}
+
+// Quote compute the fees applied if the given ride is cancelled at the given time
+func (f FeePolicy) Quote(ride models.Ride, now time.Time) models.CancellationQuote {
+	quote := models.CancellationQuote{Currency: f.Currency}
+
+	if f.Amount <= 0 {
+		quote.Reason = FeeReasonNoCancellationFee
+		return quote
+	}
+
+	assignedAt, ok := driverAssignedAt(ride)
+	if !ok {
+		quote.Reason = FeeReasonNoDriverAssigned
+		return quote
+	}
+
+	quote.FreeUntil = assignedAt.Add(f.GracePeriod)
+	if now.Before(quote.FreeUntil) {
+		quote.Reason = FeeReasonGracePeriod
+		return quote
+	}
+
+	quote.Amount = f.Amount
+	quote.Reason = FeeReasonLateCancellation
+
+	return quote
+}
+
+// driverAssignedAt return the date a driver was first assigned to the ride using the ride status history
+func driverAssignedAt(ride models.Ride) (time.Time, bool) {
+	for _, change := range ride.StatusHistory {
+		switch change.To {
+		case Accepted, Arriving, InProgress:
+			return change.Date, true
+		}
+	}
+
+	return time.Time{}, false
+}
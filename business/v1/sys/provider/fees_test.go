@@ -0,0 +1,53 @@
+package provider_test
+
+import (
+	"testing"
+	"time"
+
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/provider"
+)
+
+func Test_FeePolicyQuote(t *testing.T) {
+	t.Log("Given the need to quote the cancellation fees of a ride")
+	{
+		policy := provider.FeePolicy{Amount: 10, Currency: "eur", GracePeriod: 5 * time.Minute}
+		assignedAt := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+		assigned := models.Ride{
+			Status: provider.Accepted,
+			StatusHistory: []models.StatusChange{
+				{To: provider.Processing, Date: assignedAt.Add(-time.Minute)},
+				{From: provider.Processing, To: provider.Accepted, Date: assignedAt},
+			},
+		}
+
+		tests := []struct {
+			name   string
+			policy provider.FeePolicy
+			ride   models.Ride
+			now    time.Time
+			amount float64
+			reason string
+		}{
+			{"no driver assigned", policy, models.Ride{Status: provider.Processing}, assignedAt, 0, provider.FeeReasonNoDriverAssigned},
+			{"inside grace period", policy, assigned, assignedAt.Add(time.Minute), 0, provider.FeeReasonGracePeriod},
+			{"after grace period", policy, assigned, assignedAt.Add(10 * time.Minute), 10, provider.FeeReasonLateCancellation},
+			{"without fee", provider.FeePolicy{Currency: "eur"}, assigned, assignedAt.Add(10 * time.Minute), 0, provider.FeeReasonNoCancellationFee},
+		}
+
+		for _, tt := range tests {
+			q := tt.policy.Quote(tt.ride, tt.now)
+			if q.Amount != tt.amount || q.Reason != tt.reason || q.Currency != "eur" {
+				t.Fatalf("\t%s\t Test: \tShould quote %v %v when %s, receive %+v", failure, tt.amount, tt.reason, tt.name, q)
+			}
+		}
+		t.Logf("\t%s\t Test: \tShould quote the fees depending on the driver assignment", success)
+
+		q := policy.Quote(assigned, assignedAt)
+		if !q.FreeUntil.Equal(assignedAt.Add(5 * time.Minute)) {
+			t.Fatalf("\t%s\t Test: \tShould return the end of the grace period, receive %v", failure, q.FreeUntil)
+		}
+		t.Logf("\t%s\t Test: \tShould return the end of the grace period", success)
+	}
+}
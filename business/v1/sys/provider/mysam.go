@@ -21,34 +21,43 @@ var (
 )
 
 type MySam struct {
-	Client        *http.Client
-	BaseURL       string
-	APIKey        string
-	OfferMapping  map[string]string
-	StatusMapping map[string]string
-	LogoURL       string
+	Client          *http.Client
+	Policy          Policy
+	BaseURL         string
+	APIKey          string
+	OfferMapping    map[string]string
+	CapacityMapping map[string]int
+	AmenityMapping  map[string]Amenities
+	StatusMapping   map[string]string
+	LogoURL         string
+	FeePolicy       FeePolicy
+	OfferValidity   time.Duration
+	WebhookSecret   string
 }
 
 type MySamRide struct {
-	Id             int          `json:"id"`
-	FromAddress    MySamAddress `json:"fromAddress"`
-	ToAddress      MySamAddress `json:"toAddress"`
-	Status         string       `json:"status"`
-	StartDate      int64        `json:"startDate"`
-	EstimatedPrice float64      `json:"estimatedPrice"`
-	FinalPrice     float64      `json:"finalPrice"`
-	Driver         *struct {
-		FirstName         string `json:"firstName,omitempty"`
-		LastName          string `json:"lastName,omitempty"`
-		MobilePhoneNumber string `json:"mobilePhoneNumber,omitempty"`
-		DriverDetails     *struct {
-			VehicleModel string `json:"vehicleModel,omitempty"`
-		} `json:"driverDetails,omitempty"`
-		Location *struct {
-			Latitude  float64 `json:"latitude,omitempty"`
-			Longitude float64 `json:"longitude,omitempty"`
-		} `json:"location,omitempty"`
-	} `json:"driver,omitempty"`
+	Id               int          `json:"id"`
+	FromAddress      MySamAddress `json:"fromAddress"`
+	ToAddress        MySamAddress `json:"toAddress"`
+	Status           string       `json:"status"`
+	StartDate        int64        `json:"startDate"`
+	EstimatedPrice   float64      `json:"estimatedPrice"`
+	FinalPrice       float64      `json:"finalPrice"`
+	CancellationFees float64      `json:"cancellationFees"`
+	Driver           *MySamDriver `json:"driver,omitempty"`
+}
+
+type MySamDriver struct {
+	FirstName         string `json:"firstName,omitempty"`
+	LastName          string `json:"lastName,omitempty"`
+	MobilePhoneNumber string `json:"mobilePhoneNumber,omitempty"`
+	DriverDetails     *struct {
+		VehicleModel string `json:"vehicleModel,omitempty"`
+	} `json:"driverDetails,omitempty"`
+	Location *struct {
+		Latitude  float64 `json:"latitude,omitempty"`
+		Longitude float64 `json:"longitude,omitempty"`
+	} `json:"location,omitempty"`
 }
 
 type MySamOffer struct {
@@ -74,17 +83,48 @@ type MySamAddress struct {
 }
 
 func NewMySam(client *http.Client, cfg *config.App) MySam {
-	return MySam{
+	p := MySam{
 		Client:  client,
+		Policy:  newPolicy(cfg),
 		BaseURL: "https://api.demo.mysam.fr/api",
 		APIKey:  cfg.Env.Providers.MySam.APIKey,
-		LogoURL: "https://mysam.fr/wp-content/uploads/2019/06/LOGO_MYSAM.png",
+
+		WebhookSecret: cfg.Env.Providers.MySam.WebhookSecret,
+		LogoURL:       "https://mysam.fr/wp-content/uploads/2019/06/LOGO_MYSAM.png",
+
+		FeePolicy: FeePolicy{
+			Amount:      float64(cfg.Env.Providers.MySam.CancellationFee),
+			Currency:    "eur",
+			GracePeriod: time.Duration(cfg.Env.Providers.MySam.CancellationGraceMinutes) * time.Minute,
+		},
+
+		// mysam estimations don't carry any expiry, their price is guaranteed for a few minutes
+		OfferValidity: 10 * time.Minute,
 
 		OfferMapping: map[string]string{
 			"CAR":   ECO,
 			"VAN":   VAN,
 			"LUXE":  Business,
 			"PRIME": Business,
+			"TPMR":  Access,
+		},
+
+		CapacityMapping: map[string]int{
+			"CAR":   4,
+			"VAN":   7,
+			"LUXE":  4,
+			"PRIME": 4,
+			"TPMR":  4,
+		},
+
+		// mysam doesn't take any option on its estimations besides the disability, the vehicles that can't
+		// honour the other ones are filtered out
+		AmenityMapping: map[string]Amenities{
+			"CAR":   {ChildSeats: 1, Luggage: 3},
+			"VAN":   {ChildSeats: 2, Luggage: 7},
+			"LUXE":  {ChildSeats: 1, Luggage: 3},
+			"PRIME": {ChildSeats: 1, Luggage: 3},
+			"TPMR":  {Wheelchair: true, Luggage: 2},
 		},
 
 		StatusMapping: map[string]string{
@@ -97,6 +137,13 @@ func NewMySam(client *http.Client, cfg *config.App) MySam {
 			"NO_DRIVER_AVAILABLE": NoDriverFound,
 		},
 	}
+
+	// the base url can be overridden to target a fake or a staging api
+	if len(cfg.Env.Providers.MySam.BaseURL) > 0 {
+		p.BaseURL = cfg.Env.Providers.MySam.BaseURL
+	}
+
+	return p
 }
 
 func (p MySam) GetOffers(ctx context.Context, _ UserInfo, s models.Search, now time.Time) ([]models.Offer, error) {
@@ -115,7 +162,7 @@ func (p MySam) GetOffers(ctx context.Context, _ UserInfo, s models.Search, now t
 		ToLongitude:           s.EndLongitude,
 		NBPassengers:          s.NbrOfPassenger,
 		StartDate:             time.Now().String(),
-		SignificantDisability: false,
+		SignificantDisability: s.Options.WheelchairAccess,
 	}
 
 	if s.IsPlanned {
@@ -133,7 +180,7 @@ func (p MySam) GetOffers(ctx context.Context, _ UserInfo, s models.Search, now t
 		return nil, fmt.Errorf("%w: %v", ErrFailedToCreateRequest, err)
 	}
 
-	resp, err := p.Client.Do(req)
+	resp, err := p.do(req, true)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", MySAMFailedToFetchApi, err)
 	}
@@ -144,7 +191,7 @@ func (p MySam) GetOffers(ctx context.Context, _ UserInfo, s models.Search, now t
 	case http.StatusBadRequest:
 		var data any
 		json.NewDecoder(resp.Body).Decode(&data)
-		return nil, fmt.Errorf("failed to fetch offer for mysam, receive following error: %v", data)
+		return nil, newStatusError(resp.StatusCode, "failed to fetch offer for mysam, receive following error: %v", data)
 	case http.StatusOK:
 		var offers []MySamOffer
 
@@ -155,13 +202,16 @@ func (p MySam) GetOffers(ctx context.Context, _ UserInfo, s models.Search, now t
 
 		var res []models.Offer
 		for _, offer := range offers {
+			if !p.AmenityMapping[offer.Estimation.VehicleType].Honours(s.Options) {
+				continue
+			}
 			res = append(res, p.convertProviderOffer(offer, s, now))
 		}
 		return res, nil
 	default:
 		var data any
 		json.NewDecoder(resp.Body).Decode(&data)
-		return nil, fmt.Errorf("unsupported status response, receive status %v and response body %v", resp.StatusCode, data)
+		return nil, newStatusError(resp.StatusCode, "unsupported status response, receive status %v and response body %v", resp.StatusCode, data)
 	}
 }
 
@@ -213,7 +263,7 @@ func (p MySam) RequestRide(ctx context.Context, o models.Offer, u UserInfo, s mo
 		return models.ProviderRide{}, fmt.Errorf("%w: %v", ErrFailedToCreateRequest, err)
 	}
 
-	resp, err := p.Client.Do(req)
+	resp, err := p.do(req, false)
 	if err != nil {
 		return models.ProviderRide{}, fmt.Errorf("%w: %v", MySAMFailedToFetchApi, err)
 	}
@@ -232,11 +282,11 @@ func (p MySam) RequestRide(ctx context.Context, o models.Offer, u UserInfo, s mo
 	case http.StatusBadRequest:
 		var data any
 		json.NewDecoder(resp.Body).Decode(&data)
-		return models.ProviderRide{}, fmt.Errorf("failed to fetch offer for mysam, receive following error: %v", data)
+		return models.ProviderRide{}, newStatusError(resp.StatusCode, "failed to fetch offer for mysam, receive following error: %v", data)
 	default:
 		var data any
 		json.NewDecoder(resp.Body).Decode(&data)
-		return models.ProviderRide{}, fmt.Errorf("unsupported status response, receive status %v and response body %v", resp.StatusCode, data)
+		return models.ProviderRide{}, newStatusError(resp.StatusCode, "unsupported status response, receive status %v and response body %v", resp.StatusCode, data)
 	}
 
 }
@@ -249,7 +299,7 @@ func (p MySam) GetRide(ctx context.Context, ride models.Ride) (models.ProviderRi
 		return models.ProviderRide{}, fmt.Errorf("%w: %v", ErrFailedToCreateRequest, err)
 	}
 
-	resp, err := p.Client.Do(req)
+	resp, err := p.do(req, true)
 	if err != nil {
 		return models.ProviderRide{}, fmt.Errorf("%w: %v", MySAMFailedToFetchApi, err)
 	}
@@ -258,13 +308,13 @@ func (p MySam) GetRide(ctx context.Context, ride models.Ride) (models.ProviderRi
 	if resp.StatusCode != http.StatusOK {
 		var data any
 		json.NewDecoder(resp.Body).Decode(&data)
-		return models.ProviderRide{}, fmt.Errorf("no ride found: %v", data)
+		return models.ProviderRide{}, newStatusError(resp.StatusCode, "no ride found: %v", data)
 	}
 
 	var updatedRide MySamRide
 	decoder := json.NewDecoder(resp.Body)
 
-	if err := decoder.Decode(&ride); err != nil {
+	if err := decoder.Decode(&updatedRide); err != nil {
 		return models.ProviderRide{}, fmt.Errorf("failed to unmarshal ride: [%w]", err)
 	}
 
@@ -289,11 +339,17 @@ func (p MySam) GetRide(ctx context.Context, ride models.Ride) (models.ProviderRi
 
 	}
 
+	// the final price is only known once the ride is finished
+	price := updatedRide.EstimatedPrice
+	if updatedRide.FinalPrice > 0 {
+		price = updatedRide.FinalPrice
+	}
+
 	return models.ProviderRide{
 		Status:     p.StatusMapping[updatedRide.Status],
 		Id:         ride.ProviderRideID,
 		StatusName: updatedRide.Status,
-		Price:      updatedRide.EstimatedPrice,
+		Price:      price,
 		ETA:        ride.ETA,
 		Driver:     driver,
 	}, nil
@@ -307,10 +363,17 @@ func (p MySam) CancelRide(ctx context.Context, ride models.Ride) (models.Provide
 		return models.ProviderRide{}, fmt.Errorf("%w: %v", ErrFailedToCreateRequest, err)
 	}
 
-	resp, err := p.Client.Do(req)
+	resp, err := p.do(req, false)
 	if err != nil {
 		return models.ProviderRide{}, fmt.Errorf("failed to update ride: [%w]", err)
 	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var data any
+		json.NewDecoder(resp.Body).Decode(&data)
+		return models.ProviderRide{}, newStatusError(resp.StatusCode, "failed to cancel ride, receive status %v and response body %v", resp.StatusCode, data)
+	}
 
 	var updatedRide MySamRide
 	decoder := json.NewDecoder(resp.Body)
@@ -320,29 +383,36 @@ func (p MySam) CancelRide(ctx context.Context, ride models.Ride) (models.Provide
 	}
 
 	return models.ProviderRide{
-		Status:     p.StatusMapping[updatedRide.Status],
-		Id:         ride.ProviderRideID,
-		StatusName: updatedRide.Status,
-		Price:      updatedRide.EstimatedPrice,
-		ETA:        ride.ETA,
-		Driver:     ride.Driver,
+		Status:           p.StatusMapping[updatedRide.Status],
+		Id:               ride.ProviderRideID,
+		StatusName:       updatedRide.Status,
+		Price:            updatedRide.EstimatedPrice,
+		ETA:              ride.ETA,
+		CancellationFees: updatedRide.CancellationFees,
+		Driver:           ride.Driver,
 	}, nil
 }
 
-func (p MySam) GetCancellationFees() {
+func (p MySam) GetCancellationFees(_ context.Context, ride models.Ride, now time.Time) (models.CancellationQuote, error) {
+	return p.FeePolicy.Quote(ride, now), nil
+}
 
+// MaxStops return 0 as mysam only book direct trips
+func (p MySam) MaxStops() int {
+	return 0
 }
 
 func (p MySam) convertProviderOffer(offer MySamOffer, s models.Search, now time.Time) models.Offer {
 	return models.Offer{
 		ID:                  validate.GenerateID(),
 		StartDate:           p.convertMySamTime(offer.Estimation.StartDate).String(),
-		Provider:            "mySam",
+		Provider:            MySamName,
 		ETA:                 offer.Estimation.Duration,
 		ProviderOfferID:     fmt.Sprint(offer.Estimation.Id),
 		LogoURL:             p.LogoURL,
 		VehicleType:         p.OfferMapping[offer.Estimation.VehicleType],
 		ProviderOfferName:   offer.Estimation.VehicleType,
+		ProviderProductID:   offer.Estimation.VehicleType,
 		ProviderPrice:       offer.Estimation.Price,
 		DisplayPrice:        fmt.Sprintf("%f %s", offer.Estimation.Price, "€"),
 		DisplayPriceNumeric: offer.Estimation.Price,
@@ -355,12 +425,15 @@ func (p MySam) convertProviderOffer(offer MySamOffer, s models.Search, now time.
 		CreatedAt:           now.String(),
 		UpdatedAt:           now.String(),
 		DeletedAt:           "",
+		Capacity:            p.CapacityMapping[offer.Estimation.VehicleType],
+		ExpiresAt:           now.Add(p.OfferValidity),
 	}
 }
 
 func (p MySam) convertProviderRide(ride MySamRide, u UserInfo, o models.Offer, now time.Time) models.ProviderRide {
 	return models.ProviderRide{
-		Id:         string(rune(ride.Id)),
+		Id:         fmt.Sprint(ride.Id),
+		Ref:        fmt.Sprint(ride.Id),
 		Status:     p.StatusMapping[ride.Status],
 		StatusName: ride.Status,
 		Price:      ride.EstimatedPrice,
@@ -377,6 +450,11 @@ func (p MySam) endpoint(path string) string {
 	return fmt.Sprintf("%v/%v", p.BaseURL, path)
 }
 
+// do send the request through the provider resilience policy, only idempotent requests are retried
+func (p MySam) do(req *http.Request, idempotent bool) (*http.Response, error) {
+	return do(p.Client, MySamName, p.Policy, req, idempotent)
+}
+
 func (p MySam) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
 	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
@@ -0,0 +1,49 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+
+	"vtc/business/v1/data/models"
+)
+
+// Amenities describe the passenger options a vehicle of a provider can honour
+type Amenities struct {
+	Wheelchair bool
+	ChildSeats int
+	Luggage    int
+	Pets       bool
+}
+
+// Honours check if the vehicle can take the passengers with the given options. A vehicle without known amenities
+// only honour a search without options.
+func (a Amenities) Honours(o models.PassengerOptions) bool {
+	if o.WheelchairAccess && !a.Wheelchair {
+		return false
+	}
+	if o.Pet && !a.Pets {
+		return false
+	}
+
+	return o.ChildSeats <= a.ChildSeats && o.Luggage <= a.Luggage
+}
+
+// optionsNote describe the passenger options for the driver, it is empty when there are none
+func optionsNote(o models.PassengerOptions) string {
+	var needs []string
+
+	if o.WheelchairAccess {
+		needs = append(needs, "wheelchair access")
+	}
+	if o.ChildSeats > 0 {
+		needs = append(needs, fmt.Sprintf("%d child seat(s)", o.ChildSeats))
+	}
+	if o.Luggage > 0 {
+		needs = append(needs, fmt.Sprintf("%d luggage", o.Luggage))
+	}
+	if o.Pet {
+		needs = append(needs, "pet")
+	}
+
+	return strings.Join(needs, ", ")
+}
@@ -0,0 +1,89 @@
+package provider_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/provider"
+	"vtc/foundation/config"
+)
+
+func Test_Amenities(t *testing.T) {
+	t.Log("Given the need to only offer vehicles honouring the passenger options")
+	{
+		car := provider.Amenities{ChildSeats: 1, Luggage: 3}
+
+		tests := []struct {
+			name    string
+			options models.PassengerOptions
+			honours bool
+		}{
+			{"no option", models.PassengerOptions{}, true},
+			{"within capacity", models.PassengerOptions{ChildSeats: 1, Luggage: 3}, true},
+			{"too many luggage", models.PassengerOptions{Luggage: 4}, false},
+			{"too many child seats", models.PassengerOptions{ChildSeats: 2}, false},
+			{"wheelchair", models.PassengerOptions{WheelchairAccess: true}, false},
+			{"pet", models.PassengerOptions{Pet: true}, false},
+		}
+
+		for _, tt := range tests {
+			if got := car.Honours(tt.options); got != tt.honours {
+				t.Fatalf("\t%s\t Test: \tShould honour %v: %v, receive %v", failure, tt.name, tt.honours, got)
+			}
+		}
+		t.Logf("\t%s\t Test: \tShould check the options against the vehicle amenities", success)
+	}
+}
+
+func Test_MySamOptions(t *testing.T) {
+	t.Log("Given the need to send the passenger options to mysam")
+	{
+		received := map[string]any{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewDecoder(r.Body).Decode(&received)
+
+			var offers []provider.MySamOffer
+			for i, vehicle := range []string{"CAR", "VAN", "TPMR"} {
+				var offer provider.MySamOffer
+				offer.Estimation.Id = i + 1
+				offer.Estimation.VehicleType = vehicle
+				offers = append(offers, offer)
+			}
+			json.NewEncoder(w).Encode(offers)
+		}))
+		defer srv.Close()
+
+		var cfg config.App
+		cfg.Env.Providers.MySam.BaseURL = srv.URL
+
+		mySam := provider.NewMySam(srv.Client(), &cfg)
+		search := models.Search{Options: models.PassengerOptions{WheelchairAccess: true}}
+
+		offers, err := mySam.GetOffers(context.Background(), provider.UserInfo{}, search, time.Now())
+		if err != nil {
+			t.Fatalf("\t%s\t Test: \tShould be able to fetch offers: %v", failure, err)
+		}
+		if received["significantDisability"] != true {
+			t.Fatalf("\t%s\t Test: \tShould ask for a significant disability, receive %v", failure, received)
+		}
+		t.Logf("\t%s\t Test: \tShould map the wheelchair access to the significant disability", success)
+
+		if len(offers) != 1 || offers[0].VehicleType != provider.Access {
+			t.Fatalf("\t%s\t Test: \tShould only return the accessible vehicle, receive %+v", failure, offers)
+		}
+		t.Logf("\t%s\t Test: \tShould filter out the vehicles without wheelchair access", success)
+
+		search.Options = models.PassengerOptions{Luggage: 5}
+
+		offers, _ = mySam.GetOffers(context.Background(), provider.UserInfo{}, search, time.Now())
+		if len(offers) != 1 || offers[0].ProviderOfferName != "VAN" {
+			t.Fatalf("\t%s\t Test: \tShould only return the van for 5 luggage, receive %+v", failure, offers)
+		}
+		t.Logf("\t%s\t Test: \tShould filter out the vehicles too small for the luggage", success)
+	}
+}
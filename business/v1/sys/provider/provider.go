@@ -4,10 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"sort"
 	"sync"
 	"time"
 	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/geo"
 	"vtc/foundation/config"
 	"vtc/foundation/lambda"
 )
@@ -15,6 +18,13 @@ import (
 var (
 	ErrFailedToMarshalRequest = errors.New("failed to marshal request body")
 	ErrFailedToCreateRequest  = errors.New("failed to create new request")
+	ErrUnknownProvider        = errors.New("unknown provider")
+)
+
+// List of the supported providers
+const (
+	MySamName = "mysam"
+	UberName  = "uber"
 )
 
 const (
@@ -28,6 +38,9 @@ const (
 	Access    = "access"
 )
 
+// VehicleTypes list all the vehicle types an offer can have
+var VehicleTypes = []string{ECO, VAN, TwoWheels, EScooter, Green, Shared, Business, Access}
+
 const (
 	Processing       = "processing"
 	Accepted         = "accepted"
@@ -41,6 +54,11 @@ const (
 	OnboardCancelled = "onboard_cancelled"
 )
 
+// Statuses list all the status a ride can have
+var Statuses = []string{
+	Processing, Accepted, InProgress, Completed, Arriving, Cancelled, DriverCancelled, NoDriverFound, Scheduled, OnboardCancelled,
+}
+
 // UserInfo represent all the info needed to get offer and request ride
 type UserInfo struct {
 	ID          string
@@ -56,74 +74,256 @@ type IProvider interface {
 	RequestRide(ctx context.Context, o models.Offer, u UserInfo, s models.Search, now time.Time) (models.ProviderRide, error)
 	GetRide(ctx context.Context, ride models.Ride) (models.ProviderRide, error)
 	CancelRide(ctx context.Context, ride models.Ride) (models.ProviderRide, error)
-	GetCancellationFees()
+	GetCancellationFees(ctx context.Context, ride models.Ride, now time.Time) (models.CancellationQuote, error)
+	// MaxStops return the number of intermediate stops the provider can handle on a trip, 0 when it can't handle any
+	MaxStops() int
 }
 
 type Integrations struct {
 	providers map[string]IProvider
+	overrides map[string]models.AggregatorProvider
+	coverages map[string]geo.Coverage
 }
 
+// New create the integrations of all the providers configured through the environment
 func New(cfg *config.App) Integrations {
-	client := &http.Client{Timeout: time.Duration(cfg.Env.Providers.Timeout) * time.Second}
-	return Integrations{
-		providers: map[string]IProvider{
-			"mysam": NewMySam(client, cfg),
-		},
+	providers := map[string]IProvider{
+		MySamName: NewMySam(newClient(cfg, cfg.Env.Providers.MySam.Timeout), cfg),
+	}
+
+	// uber is only available when a session cookie is configured
+	if len(cfg.Env.Providers.Uber.Cookie) > 0 {
+		providers[UberName] = NewUber(newClient(cfg, cfg.Env.Providers.Uber.Timeout), cfg)
+	}
+
+	coverages := map[string]geo.Coverage{}
+	for name := range providers {
+		if c, ok := loadCoverage(name, envCoverage(cfg, name)); ok {
+			coverages[name] = c
+		}
+	}
+
+	return Integrations{providers: providers, coverages: coverages}
+}
+
+// NewForAggregator create the integrations of the providers enabled for the given aggregator. The aggregator
+// credentials take precedence over the ones configured through the environment.
+func NewForAggregator(cfg *config.App, agg models.Aggregator) Integrations {
+	providers := map[string]IProvider{}
+	overrides := map[string]models.AggregatorProvider{}
+	coverages := map[string]geo.Coverage{}
+
+	for _, ap := range agg.Providers {
+		if !ap.Enabled {
+			continue
+		}
+
+		switch ap.Name {
+		case MySamName:
+			p := NewMySam(newClient(cfg, cfg.Env.Providers.MySam.Timeout), cfg)
+			if len(ap.Credentials.APIKey) > 0 {
+				p.APIKey = ap.Credentials.APIKey
+			}
+			providers[ap.Name] = p
+		case UberName:
+			p := NewUber(newClient(cfg, cfg.Env.Providers.Uber.Timeout), cfg)
+			if len(ap.Credentials.Cookie) > 0 {
+				p.Cookie = ap.Credentials.Cookie
+			}
+			if len(p.Cookie) == 0 {
+				continue
+			}
+			providers[ap.Name] = p
+		default:
+			continue
+		}
+
+		overrides[ap.Name] = ap
+
+		// the aggregator coverage take precedence over the one configured through the environment
+		coverage := envCoverage(cfg, ap.Name)
+		if len(ap.Coverage) > 0 {
+			coverage = ap.Coverage
+		}
+		if c, ok := loadCoverage(ap.Name, coverage); ok {
+			coverages[ap.Name] = c
+		}
+	}
+
+	return Integrations{providers: providers, overrides: overrides, coverages: coverages}
+}
+
+// envCoverage return the coverage of the provider configured through the environment
+func envCoverage(cfg *config.App, name string) string {
+	switch name {
+	case MySamName:
+		return cfg.Env.Providers.MySam.Coverage
+	case UberName:
+		return cfg.Env.Providers.Uber.Coverage
+	default:
+		return ""
+	}
+}
+
+// loadCoverage parse the GeoJSON coverage of a provider. An invalid coverage is logged and ignored, the provider
+// then serve every trip rather than being silently disabled.
+func loadCoverage(name, geojson string) (geo.Coverage, bool) {
+	if len(geojson) == 0 {
+		return nil, false
+	}
+
+	c, err := geo.ParseCoverage([]byte(geojson))
+	if err != nil {
+		log.Printf("ignoring the invalid coverage of provider %v: %v", name, err)
+		return nil, false
+	}
+
+	return c, true
+}
+
+// Serves check if the provider cover the start, the stops and the end of the searched trip.
+// A provider without coverage serve every trip.
+func (p Integrations) Serves(name string, s models.Search) bool {
+	c, ok := p.coverages[name]
+	if !ok {
+		return true
+	}
+
+	start := geo.Point{Latitude: s.StartLatitude, Longitude: s.StartLongitude}
+	end := geo.Point{Latitude: s.EndLatitude, Longitude: s.EndLongitude}
+
+	for _, stop := range s.Stops {
+		if !c.Contains(geo.Point{Latitude: stop.Latitude, Longitude: stop.Longitude}) {
+			return false
+		}
+	}
+
+	return c.Contains(start) && c.Contains(end)
+}
+
+// HandlesStops check if the provider can handle all the intermediate stops of the searched trip
+func (p Integrations) HandlesStops(name string, s models.Search) bool {
+	integration, err := p.provider(name)
+	if err != nil {
+		return false
+	}
+
+	return len(s.Stops) <= integration.MaxStops()
+}
+
+// newClient create the http client of a provider, a provider timeout override the default one when set
+func newClient(cfg *config.App, timeout int) *http.Client {
+	if timeout <= 0 {
+		timeout = cfg.Env.Providers.Timeout
+	}
+
+	return &http.Client{Timeout: time.Duration(timeout) * time.Second}
+}
+
+// newPolicy create the resilience policy applied to the providers calls
+func newPolicy(cfg *config.App) Policy {
+	return Policy{
+		MaxRetries:       cfg.Env.Providers.MaxRetries,
+		BaseBackoff:      time.Duration(cfg.Env.Providers.BackoffMs) * time.Millisecond,
+		FailureThreshold: cfg.Env.Providers.BreakerThreshold,
+		Cooldown:         time.Duration(cfg.Env.Providers.BreakerCooldown) * time.Second,
+	}
+}
+
+// Names return the name of all the available providers
+func (p Integrations) Names() []string {
+	names := make([]string, 0, len(p.providers))
+	for name := range p.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Validate check that all the given providers are available
+func (p Integrations) Validate(names []string) error {
+	for _, name := range names {
+		if _, err := p.provider(name); err != nil {
+			return err
+		}
 	}
+
+	return nil
 }
 
-func (p Integrations) GetOffers(ctx context.Context, u UserInfo, s models.Search, now time.Time) ([]models.Offer, error) {
+// GetOffers fetch concurrently the offers of all the asked providers. A failing provider doesn't fail the whole
+// search, the outcome of each provider is returned alongside the offers. An unknown provider is rejected upfront.
+func (p Integrations) GetOffers(ctx context.Context, u UserInfo, s models.Search, now time.Time) ([]models.Offer, []models.ProviderResult, error) {
+	if err := p.Validate(s.AskedProvider); err != nil {
+		return nil, nil, err
+	}
+
 	var res []models.Offer
+	results := make([]models.ProviderResult, len(s.AskedProvider))
 
 	var wg sync.WaitGroup
-	wg.Add(len(s.AskedProvider))
-
 	var mu sync.Mutex
-	errorCh := make(chan error, len(s.AskedProvider))
 
-	for _, provider := range s.AskedProvider {
-		go func(provider string) {
+	for i, provider := range s.AskedProvider {
+		// skip the providers not covering the trip instead of waiting for their failure
+		if !p.Serves(provider, s) {
+			results[i] = models.ProviderResult{Provider: provider, ErrorCategory: ErrCategoryNotServed}
+			continue
+		}
+
+		// the providers unable to handle the stops would quote a different trip
+		if !p.HandlesStops(provider, s) {
+			results[i] = models.ProviderResult{Provider: provider, ErrorCategory: ErrCategoryNoStops}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, provider string) {
 			defer wg.Done()
 
-			integrations, ok := p.providers[provider]
-			// check if the asked provider exist
-			if !ok {
-				errorCh <- fmt.Errorf("provider %s doesn't exist", provider)
-				return
+			//fetch offer from the given provider
+			start := time.Now()
+			offers, err := p.providers[provider].GetOffers(ctx, u, s, now)
+
+			// each goroutine own its index so the write doesn't need the lock
+			results[i] = models.ProviderResult{
+				Provider:   provider,
+				Success:    err == nil,
+				LatencyMs:  time.Since(start).Milliseconds(),
+				OfferCount: len(offers),
 			}
 
-			//fetch offer from the given provider
-			offers, err := integrations.GetOffers(ctx, u, s, now)
 			if err != nil {
-				errorCh <- err
+				results[i].ErrorCategory = CategorizeError(err)
+				captureError(ctx, fmt.Errorf("failed to fetch offers for provider %v: %w", provider, err))
 				return
 			}
 
+			p.applyOverrides(provider, offers)
+
 			// push all offers into result array
 			mu.Lock()
 			{
 				res = append(res, offers...)
 			}
 			mu.Unlock()
-		}(provider)
+		}(i, provider)
 	}
 
 	// Wait for all goroutines to finish.
 	wg.Wait()
 
-	// Close the error channel to indicate that all errors have been collected.
-	close(errorCh)
-
-	for err := range errorCh {
-		trace, _ := lambda.GetRequestTrace(ctx)
-		lambda.CaptureError(trace, http.StatusInternalServerError, err)
-	}
-
-	return res, nil
+	return res, results, nil
 }
 
 func (p Integrations) RequestRide(ctx context.Context, o models.Offer, u UserInfo, s models.Search, now time.Time) (models.ProviderRide, error) {
-	ride, err := p.providers[o.Provider].RequestRide(ctx, o, u, s, now)
+	integration, err := p.provider(o.Provider)
+	if err != nil {
+		return models.ProviderRide{}, err
+	}
+
+	ride, err := integration.RequestRide(ctx, o, u, s, now)
 	if err != nil {
 		return models.ProviderRide{}, fmt.Errorf("failed to request ride for the given provider %v: %w", o.Provider, err)
 	}
@@ -131,6 +331,81 @@ func (p Integrations) RequestRide(ctx context.Context, o models.Offer, u UserInf
 	return ride, err
 }
 
+func (p Integrations) GetRide(ctx context.Context, ride models.Ride) (models.ProviderRide, error) {
+	integration, err := p.provider(ride.ProviderName)
+	if err != nil {
+		return models.ProviderRide{}, err
+	}
+
+	updatedRide, err := integration.GetRide(ctx, ride)
+	if err != nil {
+		return models.ProviderRide{}, fmt.Errorf("failed to get ride for the given provider %v: %w", ride.ProviderName, err)
+	}
+
+	return updatedRide, nil
+}
+
 func (p Integrations) CancelRide(ctx context.Context, ride models.Ride) (models.ProviderRide, error) {
-	return p.providers[ride.ProviderName].CancelRide(ctx, ride)
+	integration, err := p.provider(ride.ProviderName)
+	if err != nil {
+		return models.ProviderRide{}, err
+	}
+
+	updatedRide, err := integration.CancelRide(ctx, ride)
+	if err != nil {
+		return models.ProviderRide{}, fmt.Errorf("failed to cancel ride for the given provider %v: %w", ride.ProviderName, err)
+	}
+
+	return updatedRide, nil
+}
+
+func (p Integrations) GetCancellationFees(ctx context.Context, ride models.Ride, now time.Time) (models.CancellationQuote, error) {
+	integration, err := p.provider(ride.ProviderName)
+	if err != nil {
+		return models.CancellationQuote{}, err
+	}
+
+	quote, err := integration.GetCancellationFees(ctx, ride, now)
+	if err != nil {
+		return models.CancellationQuote{}, fmt.Errorf("failed to get cancellation fees for the given provider %v: %w", ride.ProviderName, err)
+	}
+
+	return quote, nil
+}
+
+// captureError report the error of a provider to sentry when the call is traced
+func captureError(ctx context.Context, err error) {
+	trace, terr := lambda.GetRequestTrace(ctx)
+	if terr != nil {
+		return
+	}
+
+	lambda.CaptureError(trace, http.StatusInternalServerError, err)
+}
+
+// applyOverrides replace the provider display name and logo of the offers by the ones of the aggregator
+func (p Integrations) applyOverrides(provider string, offers []models.Offer) {
+	override, ok := p.overrides[provider]
+	if !ok {
+		return
+	}
+
+	for i := range offers {
+		if len(override.DisplayName) > 0 {
+			offers[i].DisplayProviderName = override.DisplayName
+		}
+		if len(override.LogoURL) > 0 {
+			offers[i].LogoURL = override.LogoURL
+		}
+	}
+}
+
+// provider return the integration registered under the given name
+func (p Integrations) provider(name string) (IProvider, error) {
+	integration, ok := p.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownProvider, name)
+	}
+
+	return integration, nil
 }
@@ -0,0 +1,140 @@
+package provider_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/provider"
+	"vtc/foundation/config"
+)
+
+func Test_NewForAggregator(t *testing.T) {
+	t.Log("Given the need to enable providers per aggregator")
+	{
+		var cfg config.App
+
+		agg := models.Aggregator{
+			Code: "test",
+			Providers: []models.AggregatorProvider{
+				{Name: provider.MySamName, Enabled: false},
+				{Name: provider.UberName, Enabled: true, DisplayName: "Taxi", Credentials: models.ProviderCredentials{Cookie: "cookie"}},
+				{Name: "bolt", Enabled: true},
+			},
+		}
+
+		integrations := provider.NewForAggregator(&cfg, agg)
+
+		if err := integrations.Validate([]string{provider.UberName}); err != nil {
+			t.Fatalf("\t%s\t Test: \tShould accept enabled provider: %v", failure, err)
+		}
+		t.Logf("\t%s\t Test: \tShould accept enabled provider", success)
+
+		for _, name := range []string{provider.MySamName, "bolt"} {
+			if err := integrations.Validate([]string{name}); !errors.Is(err, provider.ErrUnknownProvider) {
+				t.Fatalf("\t%s\t Test: \tShould reject provider %v, receive: %v", failure, name, err)
+			}
+		}
+		t.Logf("\t%s\t Test: \tShould reject disabled and unsupported providers", success)
+
+		agg.Providers[1].Credentials.Cookie = ""
+		if err := provider.NewForAggregator(&cfg, agg).Validate([]string{provider.UberName}); !errors.Is(err, provider.ErrUnknownProvider) {
+			t.Fatalf("\t%s\t Test: \tShould reject uber without cookie, receive: %v", failure, err)
+		}
+		t.Logf("\t%s\t Test: \tShould reject uber without cookie", success)
+	}
+}
+
+// paris roughly cover the city inside the ring road
+const paris = `{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[2.22,48.81],[2.47,48.81],[2.47,48.91],[2.22,48.91],[2.22,48.81]]]}}`
+
+func Test_Coverage(t *testing.T) {
+	t.Log("Given the need to skip the providers not covering a trip")
+	{
+		var hits int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&hits, 1)
+			w.Write([]byte(`[]`))
+		}))
+		defer srv.Close()
+
+		var cfg config.App
+		cfg.Env.Providers.MySam.BaseURL = srv.URL
+		cfg.Env.Providers.MySam.Coverage = paris
+
+		integrations := provider.New(&cfg)
+
+		search := models.Search{
+			AskedProvider:  []string{provider.MySamName},
+			StartLatitude:  48.8443,
+			StartLongitude: 2.3744,
+			EndLatitude:    45.7640,
+			EndLongitude:   4.8357,
+		}
+
+		_, results, err := integrations.GetOffers(context.Background(), provider.UserInfo{}, search, time.Now())
+		if err != nil {
+			t.Fatalf("\t%s\t Test: \tShould not fail the search, receive %v", failure, err)
+		}
+		if hits != 0 || results[0].Success || results[0].ErrorCategory != provider.ErrCategoryNotServed {
+			t.Fatalf("\t%s\t Test: \tShould skip mysam with a not served result, receive %+v after %v calls", failure, results[0], hits)
+		}
+		t.Logf("\t%s\t Test: \tShould skip a provider not covering the trip", success)
+
+		search.EndLatitude, search.EndLongitude = 48.8584, 2.2945
+
+		_, results, _ = integrations.GetOffers(context.Background(), provider.UserInfo{}, search, time.Now())
+		if hits != 1 || !results[0].Success {
+			t.Fatalf("\t%s\t Test: \tShould call mysam for a trip inside its coverage, receive %+v", failure, results[0])
+		}
+		t.Logf("\t%s\t Test: \tShould call a provider covering the trip", success)
+	}
+}
+
+func Test_Stops(t *testing.T) {
+	t.Log("Given the need to only ask the providers able to handle the stops of a trip")
+	{
+		var hits int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&hits, 1)
+			w.Write([]byte(`[]`))
+		}))
+		defer srv.Close()
+
+		var cfg config.App
+		cfg.Env.Providers.MySam.BaseURL = srv.URL
+
+		integrations := provider.New(&cfg)
+
+		search := models.Search{
+			AskedProvider:  []string{provider.MySamName},
+			StartLatitude:  48.8443,
+			StartLongitude: 2.3744,
+			EndLatitude:    48.8584,
+			EndLongitude:   2.2945,
+			Stops:          []models.Stop{{Address: "Louvre, Paris", Latitude: 48.8606, Longitude: 2.3376}},
+		}
+
+		_, results, err := integrations.GetOffers(context.Background(), provider.UserInfo{}, search, time.Now())
+		if err != nil {
+			t.Fatalf("\t%s\t Test: \tShould not fail the search, receive %v", failure, err)
+		}
+		if hits != 0 || results[0].Success || results[0].ErrorCategory != provider.ErrCategoryNoStops {
+			t.Fatalf("\t%s\t Test: \tShould skip mysam with a stops unsupported result, receive %+v after %v calls", failure, results[0], hits)
+		}
+		t.Logf("\t%s\t Test: \tShould skip a provider unable to handle the stops", success)
+
+		search.Stops = nil
+
+		_, results, _ = integrations.GetOffers(context.Background(), provider.UserInfo{}, search, time.Now())
+		if hits != 1 || !results[0].Success {
+			t.Fatalf("\t%s\t Test: \tShould call mysam for a direct trip, receive %+v", failure, results[0])
+		}
+		t.Logf("\t%s\t Test: \tShould call the provider for a direct trip", success)
+	}
+}
@@ -0,0 +1,155 @@
+package provider
+
+import (
+	"math"
+	"sort"
+
+	"vtc/business/v1/data/models"
+)
+
+// List of the modes the offers can be sorted by
+const (
+	SortCheapest  = "cheapest"
+	SortFastest   = "fastest"
+	SortBestValue = "best_value"
+	SortGreenest  = "greenest"
+)
+
+// bestValuePriceWeight is the share of the price inside the best value score, the rest being the ETA
+const bestValuePriceWeight = 0.7
+
+// emissionRank order the vehicle types from the greenest to the most polluting
+var emissionRank = map[string]int{
+	EScooter:  0,
+	TwoWheels: 1,
+	Green:     2,
+	Shared:    3,
+	ECO:       4,
+	Access:    5,
+	Business:  6,
+	VAN:       7,
+}
+
+// OfferFilter restrict the offers returned to a user, a zero field doesn't filter anything
+type OfferFilter struct {
+	VehicleTypes []string
+	MaxPrice     float64
+	MaxETA       float64
+	MinCapacity  int
+}
+
+// Match check if the offer satisfy the filter. An offer with an unknown capacity doesn't match a minimum capacity.
+func (f OfferFilter) Match(o models.Offer) bool {
+	if len(f.VehicleTypes) > 0 && !contains(f.VehicleTypes, o.VehicleType) {
+		return false
+	}
+
+	if f.MaxPrice > 0 && o.DisplayPriceNumeric > f.MaxPrice {
+		return false
+	}
+
+	if f.MaxETA > 0 && o.ETA > f.MaxETA {
+		return false
+	}
+
+	return f.MinCapacity <= 0 || o.Capacity >= f.MinCapacity
+}
+
+// RankOffers filter the offers, flag the best value one as recommended and sort them with the given mode.
+// The best value mode is used when no mode is given.
+func RankOffers(offers []models.Offer, filter OfferFilter, mode string) []models.Offer {
+	res := []models.Offer{}
+	for _, o := range offers {
+		if filter.Match(o) {
+			o.Recommended = false
+			res = append(res, o)
+		}
+	}
+
+	if len(res) == 0 {
+		return res
+	}
+
+	SortOffers(res, SortBestValue)
+	res[0].Recommended = true
+
+	if len(mode) > 0 && mode != SortBestValue {
+		SortOffers(res, mode)
+	}
+
+	return res
+}
+
+// SortOffers sort the offers in place with the given mode, ties are broken by price. An unknown mode sort by price.
+func SortOffers(offers []models.Offer, mode string) {
+	var less func(a, b models.Offer) bool
+
+	switch mode {
+	case SortFastest:
+		less = func(a, b models.Offer) bool { return a.ETA < b.ETA }
+	case SortGreenest:
+		less = func(a, b models.Offer) bool { return greenness(a) < greenness(b) }
+	case SortBestValue:
+		score := bestValueScore(offers)
+		less = func(a, b models.Offer) bool { return score(a) < score(b) }
+	default:
+		// the cheapest mode only rely on the price tie break
+		less = func(a, b models.Offer) bool { return false }
+	}
+
+	sort.SliceStable(offers, func(i, j int) bool {
+		a, b := offers[i], offers[j]
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return a.DisplayPriceNumeric < b.DisplayPriceNumeric
+	})
+}
+
+// greenness return the emission rank of the offer, an unknown vehicle type come last
+func greenness(o models.Offer) int {
+	if rank, ok := emissionRank[o.VehicleType]; ok {
+		return rank
+	}
+
+	return len(emissionRank)
+}
+
+// bestValueScore return a scoring function where the lowest score is the best value. The price and the ETA are
+// normalized between the cheapest and most expensive, and the fastest and slowest offers.
+func bestValueScore(offers []models.Offer) func(o models.Offer) float64 {
+	minPrice, maxPrice := offers[0].DisplayPriceNumeric, offers[0].DisplayPriceNumeric
+	minETA, maxETA := offers[0].ETA, offers[0].ETA
+
+	for _, o := range offers[1:] {
+		minPrice, maxPrice = math.Min(minPrice, o.DisplayPriceNumeric), math.Max(maxPrice, o.DisplayPriceNumeric)
+		minETA, maxETA = math.Min(minETA, o.ETA), math.Max(maxETA, o.ETA)
+	}
+
+	normalize := func(v, lo, hi float64) float64 {
+		if hi <= lo {
+			return 0
+		}
+		return (v - lo) / (hi - lo)
+	}
+
+	return func(o models.Offer) float64 {
+		price := normalize(o.DisplayPriceNumeric, minPrice, maxPrice)
+		eta := normalize(o.ETA, minETA, maxETA)
+
+		return bestValuePriceWeight*price + (1-bestValuePriceWeight)*eta
+	}
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,68 @@
+package provider_test
+
+import (
+	"testing"
+
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/provider"
+)
+
+func Test_RankOffers(t *testing.T) {
+	t.Log("Given the need to rank the offers returned to a user")
+	{
+		offers := []models.Offer{
+			{ID: "van", VehicleType: provider.VAN, DisplayPriceNumeric: 40, ETA: 300, Capacity: 7},
+			{ID: "eco", VehicleType: provider.ECO, DisplayPriceNumeric: 20, ETA: 900, Capacity: 4},
+			{ID: "green", VehicleType: provider.Green, DisplayPriceNumeric: 25, ETA: 400, Capacity: 4},
+			{ID: "business", VehicleType: provider.Business, DisplayPriceNumeric: 60, ETA: 120},
+		}
+
+		tests := []struct {
+			name   string
+			filter provider.OfferFilter
+			mode   string
+			order  []string
+		}{
+			{"cheapest", provider.OfferFilter{}, provider.SortCheapest, []string{"eco", "green", "van", "business"}},
+			{"fastest", provider.OfferFilter{}, provider.SortFastest, []string{"business", "van", "green", "eco"}},
+			{"greenest", provider.OfferFilter{}, provider.SortGreenest, []string{"green", "eco", "business", "van"}},
+			{"best value", provider.OfferFilter{}, "", []string{"green", "eco", "van", "business"}},
+			{"vehicle type", provider.OfferFilter{VehicleTypes: []string{provider.VAN, provider.ECO}}, provider.SortCheapest, []string{"eco", "van"}},
+			{"max price", provider.OfferFilter{MaxPrice: 30}, provider.SortCheapest, []string{"eco", "green"}},
+			{"max eta", provider.OfferFilter{MaxETA: 400}, provider.SortCheapest, []string{"green", "van", "business"}},
+			{"min capacity", provider.OfferFilter{MinCapacity: 5}, provider.SortCheapest, []string{"van"}},
+		}
+
+		for _, tt := range tests {
+			res := provider.RankOffers(offers, tt.filter, tt.mode)
+
+			var order []string
+			for _, o := range res {
+				order = append(order, o.ID)
+			}
+
+			if len(order) != len(tt.order) {
+				t.Fatalf("\t%s\t Test %v: \tShould return %v, receive %v", failure, tt.name, tt.order, order)
+			}
+			for i := range order {
+				if order[i] != tt.order[i] {
+					t.Fatalf("\t%s\t Test %v: \tShould return %v, receive %v", failure, tt.name, tt.order, order)
+				}
+			}
+			t.Logf("\t%s\t Test %v: \tShould filter and sort the offers", success, tt.name)
+		}
+
+		res := provider.RankOffers(offers, provider.OfferFilter{}, provider.SortFastest)
+
+		var recommended []string
+		for _, o := range res {
+			if o.Recommended {
+				recommended = append(recommended, o.ID)
+			}
+		}
+		if len(recommended) != 1 || recommended[0] != "green" {
+			t.Fatalf("\t%s\t Test: \tShould recommend the best value offer only, receive %v", failure, recommended)
+		}
+		t.Logf("\t%s\t Test: \tShould recommend the best value offer whatever the sort mode", success)
+	}
+}
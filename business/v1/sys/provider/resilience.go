@@ -0,0 +1,277 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("provider circuit breaker is open")
+
+// List of values that a CircuitBreaker state can take
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// Policy define how the calls made to a provider are retried and short-circuited.
+// The zero value disable both retries and the circuit breaker.
+type Policy struct {
+	MaxRetries       int
+	BaseBackoff      time.Duration
+	FailureThreshold int
+	Cooldown         time.Duration
+}
+
+// CircuitBreaker stop calling a provider after too many consecutive failures. Once the cooldown is elapsed a
+// single trial call is let through, its success close the breaker while its failure open it again.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	name      string
+	threshold int
+	cooldown  time.Duration
+	state     string
+	failures  int
+	openedAt  time.Time
+}
+
+// NewCircuitBreaker create a closed circuit breaker for the given provider
+func NewCircuitBreaker(name string, threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{name: name, threshold: threshold, cooldown: cooldown, state: BreakerClosed}
+}
+
+// Allow check if a call can be made to the provider at the given time
+func (b *CircuitBreaker) Allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(BreakerHalfOpen)
+		return true
+	case BreakerHalfOpen:
+		// a trial call is already in flight
+		return false
+	default:
+		return true
+	}
+}
+
+// Success record a successful call and close the breaker
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.setState(BreakerClosed)
+}
+
+// Failure record a failed call, the breaker is opened when the threshold is reached or when the trial call failed
+func (b *CircuitBreaker) Failure(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.openedAt = now
+		b.setState(BreakerOpen)
+	}
+}
+
+// State return the current state of the breaker
+func (b *CircuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+func (b *CircuitBreaker) setState(state string) {
+	if b.state == state {
+		return
+	}
+
+	reportBreakerState(b.name, b.state, state)
+	b.state = state
+}
+
+// reportBreakerState log the new state of a breaker using the cloudwatch embedded metric format,
+// so the change is readable in the logs and available as the CircuitBreakerOpen metric.
+func reportBreakerState(name, from, to string) {
+	open := 0
+	if to == BreakerOpen {
+		open = 1
+	}
+
+	record := map[string]any{
+		"_aws": map[string]any{
+			"Timestamp": time.Now().UnixMilli(),
+			"CloudWatchMetrics": []map[string]any{
+				{
+					"Namespace":  "vtc/providers",
+					"Dimensions": [][]string{{"Provider"}},
+					"Metrics":    []map[string]string{{"Name": "CircuitBreakerOpen", "Unit": "Count"}},
+				},
+			},
+		},
+		"Provider":           name,
+		"CircuitBreakerOpen": open,
+		"message":            fmt.Sprintf("provider %v circuit breaker moved from %v to %v", name, from, to),
+	}
+
+	b, _ := json.Marshal(record)
+	fmt.Println(string(b))
+}
+
+// breakers keep the state of the provider circuit breakers for the lifetime of the lambda container
+var breakers = struct {
+	sync.Mutex
+	m map[string]*CircuitBreaker
+}{m: map[string]*CircuitBreaker{}}
+
+// breakerFor return the circuit breaker shared by all the calls made to the given provider
+func breakerFor(name string, policy Policy) *CircuitBreaker {
+	breakers.Lock()
+	defer breakers.Unlock()
+
+	b, ok := breakers.m[name]
+	if !ok {
+		b = NewCircuitBreaker(name, policy.FailureThreshold, policy.Cooldown)
+		breakers.m[name] = b
+	}
+
+	return b
+}
+
+// do send the request through the provider resilience policy. Only idempotent requests are retried, with a
+// jittered exponential backoff. Timeouts are never retried so a slow provider only cost its timeout once.
+// Timeouts, network errors and server errors count as failures for the breaker, client errors don't since they
+// don't tell anything about the provider health. The outcome is recorded on every exit so a trial call always
+// settles the breaker.
+func do(client *http.Client, name string, policy Policy, req *http.Request, idempotent bool) (resp *http.Response, err error) {
+	if policy.FailureThreshold > 0 {
+		breaker := breakerFor(name, policy)
+		if !breaker.Allow(time.Now()) {
+			return nil, fmt.Errorf("%w: %v", ErrCircuitOpen, name)
+		}
+
+		defer func() {
+			if isFailure(resp, err) {
+				breaker.Failure(time.Now())
+			} else {
+				breaker.Success()
+			}
+		}()
+	}
+
+	retries := 0
+	if idempotent {
+		retries = policy.MaxRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err = client.Do(req)
+		if !isRetryable(resp, err) || attempt >= retries {
+			return resp, err
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		// the outcome of the last attempt is kept for the breaker when the retry can't be made
+		if sErr := sleep(req, backoff(policy.BaseBackoff, attempt)); sErr != nil {
+			return nil, fmt.Errorf("provider %v retry aborted: %w", name, sErr)
+		}
+
+		if req, err = rewind(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// isFailure check if the outcome of a call is a provider failure
+func isFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	return resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// isRetryable check if a failed call can be made again, timeouts are not retried
+func isRetryable(resp *http.Response, err error) bool {
+	if isTimeout(err) {
+		return false
+	}
+
+	return isFailure(resp, err)
+}
+
+// isTimeout check if the call failed because the provider didn't answer in time
+func isTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// jitter is seeded per container so that concurrent lambdas don't retry in lockstep
+var jitter = struct {
+	sync.Mutex
+	r *rand.Rand
+}{r: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+// backoff return a random duration between 0 and base * 2^attempt
+func backoff(base time.Duration, attempt int) time.Duration {
+	max := base << attempt
+	if max <= 0 {
+		return 0
+	}
+
+	jitter.Lock()
+	defer jitter.Unlock()
+
+	return time.Duration(jitter.r.Int63n(int64(max)))
+}
+
+// sleep wait for the given duration unless the request context is done
+func sleep(req *http.Request, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-req.Context().Done():
+		return req.Context().Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// rewind return a copy of the request with a fresh body so it can be sent again
+func rewind(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if req.GetBody == nil {
+		return r, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewind request body: %v", err)
+	}
+	r.Body = body
+
+	return r, nil
+}
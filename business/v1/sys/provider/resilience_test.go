@@ -0,0 +1,183 @@
+package provider_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/provider"
+	"vtc/foundation/config"
+)
+
+func Test_CircuitBreaker(t *testing.T) {
+	t.Log("Given the need to short-circuit a failing provider")
+	{
+		now := time.Now()
+		b := provider.NewCircuitBreaker("breaker_test", 2, time.Minute)
+
+		b.Failure(now)
+		if !b.Allow(now) {
+			t.Fatalf("\t%s\t Test: \tShould allow calls below the threshold", failure)
+		}
+		b.Failure(now)
+		if b.State() != provider.BreakerOpen || b.Allow(now) {
+			t.Fatalf("\t%s\t Test: \tShould open the breaker at the threshold, receive %v", failure, b.State())
+		}
+		t.Logf("\t%s\t Test: \tShould open the breaker after consecutive failures", success)
+
+		later := now.Add(2 * time.Minute)
+		if !b.Allow(later) || b.State() != provider.BreakerHalfOpen {
+			t.Fatalf("\t%s\t Test: \tShould let a trial call through after the cooldown, receive %v", failure, b.State())
+		}
+		if b.Allow(later) {
+			t.Fatalf("\t%s\t Test: \tShould let a single trial call through", failure)
+		}
+		b.Failure(later)
+		if b.State() != provider.BreakerOpen {
+			t.Fatalf("\t%s\t Test: \tShould reopen the breaker when the trial fails, receive %v", failure, b.State())
+		}
+		t.Logf("\t%s\t Test: \tShould reopen the breaker when the trial call fails", success)
+
+		later = later.Add(2 * time.Minute)
+		b.Allow(later)
+		b.Success()
+		if b.State() != provider.BreakerClosed || !b.Allow(later) {
+			t.Fatalf("\t%s\t Test: \tShould close the breaker when the trial succeeds, receive %v", failure, b.State())
+		}
+		t.Logf("\t%s\t Test: \tShould close the breaker when the trial call succeeds", success)
+	}
+}
+
+func Test_Retry(t *testing.T) {
+	t.Log("Given the need to retry only the idempotent provider calls")
+	{
+		var hits int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&hits, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+
+		mysam := provider.NewMySam(srv.Client(), &config.App{})
+		mysam.BaseURL = srv.URL
+		mysam.Policy = provider.Policy{MaxRetries: 2, BaseBackoff: time.Millisecond}
+
+		if _, err := mysam.GetOffers(context.Background(), provider.UserInfo{}, models.Search{}, time.Now()); err == nil {
+			t.Fatalf("\t%s\t Test: \tShould fail to get offers from an unavailable provider", failure)
+		}
+		if hits != 3 {
+			t.Fatalf("\t%s\t Test: \tShould try GetOffers 3 times, receive %v", failure, hits)
+		}
+		t.Logf("\t%s\t Test: \tShould retry GetOffers", success)
+
+		hits = 0
+		if _, err := mysam.RequestRide(context.Background(), models.Offer{}, provider.UserInfo{}, models.Search{}, time.Now()); err == nil {
+			t.Fatalf("\t%s\t Test: \tShould fail to request a ride from an unavailable provider", failure)
+		}
+		if hits != 1 {
+			t.Fatalf("\t%s\t Test: \tShould try RequestRide once, receive %v", failure, hits)
+		}
+		t.Logf("\t%s\t Test: \tShould never retry RequestRide", success)
+	}
+}
+
+func Test_CircuitOpen(t *testing.T) {
+	t.Log("Given the need to stop calling a provider once its breaker is open")
+	{
+		var hits int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&hits, 1)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+
+		uber := provider.NewUber(srv.Client(), &config.App{})
+		uber.BaseURL = srv.URL
+		uber.Policy = provider.Policy{FailureThreshold: 1, Cooldown: time.Minute}
+
+		ride := models.Ride{ProviderRideID: "rideUUID=ride"}
+		uber.GetRide(context.Background(), ride)
+
+		_, err := uber.GetRide(context.Background(), ride)
+		if err == nil || !strings.Contains(err.Error(), provider.ErrCircuitOpen.Error()) {
+			t.Fatalf("\t%s\t Test: \tShould return an open circuit error, receive %v", failure, err)
+		}
+		if hits != 1 {
+			t.Fatalf("\t%s\t Test: \tShould not call the provider once the breaker is open, receive %v calls", failure, hits)
+		}
+		t.Logf("\t%s\t Test: \tShould short-circuit the provider", success)
+	}
+}
+
+func Test_BreakerSettled(t *testing.T) {
+	t.Log("Given the need to settle the breaker when a retry is aborted")
+	{
+		var healthy int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.LoadInt32(&healthy) == 1 {
+				w.Write([]byte("{}"))
+				return
+			}
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+
+		mysam := provider.NewMySam(srv.Client(), &config.App{})
+		mysam.BaseURL = srv.URL
+		mysam.Policy = provider.Policy{FailureThreshold: 1, Cooldown: time.Millisecond}
+
+		mysam.GetOffers(context.Background(), provider.UserInfo{}, models.Search{}, time.Now())
+		time.Sleep(5 * time.Millisecond)
+
+		// the backoff outlive the context, the retry of the trial call is aborted
+		mysam.Policy.MaxRetries, mysam.Policy.BaseBackoff = 1, time.Hour
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		_, err := mysam.GetOffers(ctx, provider.UserInfo{}, models.Search{}, time.Now())
+		cancel()
+
+		if err == nil || strings.Contains(err.Error(), provider.ErrCircuitOpen.Error()) {
+			t.Fatalf("\t%s\t Test: \tShould let a trial call through after the cooldown, receive %v", failure, err)
+		}
+		t.Logf("\t%s\t Test: \tShould let a trial call through after the cooldown", success)
+
+		time.Sleep(5 * time.Millisecond)
+		atomic.StoreInt32(&healthy, 1)
+		if _, err := mysam.GetOffers(context.Background(), provider.UserInfo{}, models.Search{}, time.Now()); err != nil && strings.Contains(err.Error(), provider.ErrCircuitOpen.Error()) {
+			t.Fatalf("\t%s\t Test: \tShould not keep the breaker half open, receive %v", failure, err)
+		}
+		t.Logf("\t%s\t Test: \tShould close the breaker once the provider recovered", success)
+	}
+}
+
+func Test_TimeoutNotRetried(t *testing.T) {
+	t.Log("Given the need to bound the time spent on a slow provider")
+	{
+		var hits int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&hits, 1)
+			time.Sleep(100 * time.Millisecond)
+		}))
+		defer srv.Close()
+
+		client := srv.Client()
+		client.Timeout = 20 * time.Millisecond
+
+		mysam := provider.NewMySam(client, &config.App{})
+		mysam.BaseURL = srv.URL
+		mysam.Policy = provider.Policy{MaxRetries: 2, BaseBackoff: time.Millisecond}
+
+		if _, err := mysam.GetOffers(context.Background(), provider.UserInfo{}, models.Search{}, time.Now()); err == nil {
+			t.Fatalf("\t%s\t Test: \tShould fail to get offers from a slow provider", failure)
+		}
+		if n := atomic.LoadInt32(&hits); n != 1 {
+			t.Fatalf("\t%s\t Test: \tShould not retry a timeout, receive %v calls", failure, n)
+		}
+		t.Logf("\t%s\t Test: \tShould not retry a timeout", success)
+	}
+}
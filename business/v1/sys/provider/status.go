@@ -0,0 +1,97 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"vtc/business/v1/data/models"
+)
+
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// List of values that StatusChange.Source can take
+const (
+	SourceProviderPoll = "provider_poll"
+	SourceWebhook      = "webhook"
+	SourceUser         = "user"
+)
+
+// StatusMachine define the legal transitions between the status of a ride
+type StatusMachine struct {
+	transitions map[string][]string
+}
+
+// NewStatusMachine return the state machine describing the lifecycle of a ride. A status missing from the
+// transitions is terminal, the ride can't change afterwards. Forward jumps are allowed since a provider poll may
+// miss intermediate status.
+func NewStatusMachine() StatusMachine {
+	return StatusMachine{
+		transitions: map[string][]string{
+			Scheduled:  {Processing, Accepted, Arriving, InProgress, Cancelled, DriverCancelled, NoDriverFound},
+			Processing: {Accepted, Arriving, InProgress, Cancelled, DriverCancelled, NoDriverFound},
+			Accepted:   {Processing, Arriving, InProgress, Completed, Cancelled, DriverCancelled, OnboardCancelled},
+			Arriving:   {Processing, InProgress, Completed, Cancelled, DriverCancelled, OnboardCancelled},
+			InProgress: {Completed, OnboardCancelled},
+		},
+	}
+}
+
+// CanTransition check if a ride can move from the status from to the status to.
+// A ride without status can take any status since it's the initial one.
+func (m StatusMachine) CanTransition(from, to string) bool {
+	if len(to) == 0 {
+		return false
+	}
+
+	if len(from) == 0 {
+		return true
+	}
+
+	for _, status := range m.transitions[from] {
+		if status == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsTerminal check if the given status is final
+func (m StatusMachine) IsTerminal(status string) bool {
+	_, ok := m.transitions[status]
+	return !ok
+}
+
+// Transition move the ride to the given status and record the change inside the ride status history.
+// Moving a ride to its current status is a no-op.
+func (m StatusMachine) Transition(ride *models.Ride, to, source string, now time.Time) error {
+	if ride.Status == to {
+		return nil
+	}
+
+	if !m.CanTransition(ride.Status, to) {
+		return fmt.Errorf("%w: from %q to %q", ErrInvalidTransition, ride.Status, to)
+	}
+
+	ride.StatusHistory = append(ride.StatusHistory, models.StatusChange{
+		From:   ride.Status,
+		To:     to,
+		Source: source,
+		Date:   now,
+	})
+	ride.Status = to
+
+	return nil
+}
+
+// StatusReachedAt return the date the ride last moved to the given status using the ride status history
+func StatusReachedAt(ride models.Ride, status string) (time.Time, bool) {
+	for i := len(ride.StatusHistory) - 1; i >= 0; i-- {
+		if ride.StatusHistory[i].To == status {
+			return ride.StatusHistory[i].Date, true
+		}
+	}
+
+	return time.Time{}, false
+}
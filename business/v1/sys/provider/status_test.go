@@ -0,0 +1,113 @@
+package provider_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/provider"
+)
+
+const (
+	success = "\u2713"
+	failure = "\u2717"
+)
+
+func Test_StatusMachine(t *testing.T) {
+	t.Log("Given the need to validate the transitions between ride status")
+	{
+		m := provider.NewStatusMachine()
+
+		tests := []struct {
+			from, to string
+			legal    bool
+		}{
+			{"", provider.Processing, true},
+			{provider.Scheduled, provider.Accepted, true},
+			{provider.Processing, provider.Accepted, true},
+			{provider.Accepted, provider.Arriving, true},
+			{provider.Arriving, provider.InProgress, true},
+			{provider.InProgress, provider.Completed, true},
+			{provider.Accepted, provider.Processing, true},
+			{provider.Processing, provider.Cancelled, true},
+			{provider.InProgress, provider.OnboardCancelled, true},
+			{provider.Completed, provider.Arriving, false},
+			{provider.Cancelled, provider.Accepted, false},
+			{provider.InProgress, provider.Cancelled, false},
+			{provider.Processing, provider.Completed, false},
+			{provider.Processing, "", false},
+		}
+
+		for _, tt := range tests {
+			if got := m.CanTransition(tt.from, tt.to); got != tt.legal {
+				t.Fatalf("\t%s\t Test: \tShould return %v for %q -> %q but receive %v", failure, tt.legal, tt.from, tt.to, got)
+			}
+		}
+		t.Logf("\t%s\t Test: \tShould accept legal transitions and reject illegal ones", success)
+
+		if !m.IsTerminal(provider.Completed) || m.IsTerminal(provider.Accepted) {
+			t.Fatalf("\t%s\t Test: \tShould detect terminal status", failure)
+		}
+		t.Logf("\t%s\t Test: \tShould detect terminal status", success)
+	}
+}
+
+func Test_StatusMachineTransition(t *testing.T) {
+	t.Log("Given the need to record every status change of a ride")
+	{
+		m := provider.NewStatusMachine()
+		now := time.Now()
+		ride := models.Ride{}
+
+		for _, status := range []string{provider.Processing, provider.Accepted, provider.Accepted, provider.Arriving} {
+			if err := m.Transition(&ride, status, provider.SourceProviderPoll, now); err != nil {
+				t.Fatalf("\t%s\t Test: \tShould be able to move ride to %v: %v", failure, status, err)
+			}
+		}
+
+		if ride.Status != provider.Arriving {
+			t.Fatalf("\t%s\t Test: \tShould update the ride status, receive %v", failure, ride.Status)
+		}
+		if len(ride.StatusHistory) != 3 {
+			t.Fatalf("\t%s\t Test: \tShould record 3 transitions, receive %d", failure, len(ride.StatusHistory))
+		}
+		if h := ride.StatusHistory[1]; h.From != provider.Processing || h.To != provider.Accepted || h.Source != provider.SourceProviderPoll || !h.Date.Equal(now) {
+			t.Fatalf("\t%s\t Test: \tShould record the transition details, receive %+v", failure, h)
+		}
+		t.Logf("\t%s\t Test: \tShould record every transition", success)
+
+		ride.Status = provider.Completed
+		err := m.Transition(&ride, provider.Arriving, provider.SourceWebhook, now)
+		if !errors.Is(err, provider.ErrInvalidTransition) {
+			t.Fatalf("\t%s\t Test: \tShould reject illegal transition, receive %v", failure, err)
+		}
+		if ride.Status != provider.Completed || len(ride.StatusHistory) != 3 {
+			t.Fatalf("\t%s\t Test: \tShould leave the ride untouched on illegal transition", failure)
+		}
+		t.Logf("\t%s\t Test: \tShould reject illegal transition", success)
+	}
+}
+
+func Test_StatusReachedAt(t *testing.T) {
+	t.Log("Given the need to know when a ride reached a status")
+	{
+		now := time.Now()
+		ride := models.Ride{}
+		m := provider.NewStatusMachine()
+
+		_ = m.Transition(&ride, provider.Processing, provider.SourceUser, now)
+		_ = m.Transition(&ride, provider.Accepted, provider.SourceProviderPoll, now.Add(time.Minute))
+		_ = m.Transition(&ride, provider.Completed, provider.SourceWebhook, now.Add(20*time.Minute))
+
+		if at, ok := provider.StatusReachedAt(ride, provider.Completed); !ok || !at.Equal(now.Add(20*time.Minute)) {
+			t.Fatalf("\t%s\t Test: \tShould return the date the ride was completed: got %v", failure, at)
+		}
+		t.Logf("\t%s\t Test: \tShould return the date the ride was completed", success)
+
+		if _, ok := provider.StatusReachedAt(ride, provider.Cancelled); ok {
+			t.Fatalf("\t%s\t Test: \tShould not find a status the ride never reached", failure)
+		}
+		t.Logf("\t%s\t Test: \tShould not find a status the ride never reached", success)
+	}
+}
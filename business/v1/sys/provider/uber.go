@@ -1,4 +1,3 @@
-// @note the implementation is not completed yet.
 package provider
 
 import (
@@ -23,13 +22,23 @@ var (
 	UberUnexpectedResponseBody = errors.New("failed to decode uber response body")
 )
 
+// uberOrganizationUUID is the uber central organization used to order the rides
+const uberOrganizationUUID = "ee840421-c340-5053-b46a-37914dd7224d"
+
+// uberMaxStops is the number of additional stops uber accept on a trip leg
+const uberMaxStops = 2
+
 type Uber struct {
-	Client        *http.Client
-	OfferMapping  map[string]string
-	StatusMapping map[string]string
-	LogoURL       string
-	Cookie        string
-	BaseURL       string
+	Client         *http.Client
+	Policy         Policy
+	OfferMapping   map[string]string
+	AmenityMapping map[string]Amenities
+	StatusMapping  map[string]string
+	LogoURL        string
+	Cookie         string
+	BaseURL        string
+	OfferValidity  time.Duration
+	WebhookSecret  string
 }
 
 type UberResponseOffer struct {
@@ -92,20 +101,20 @@ type Fare struct {
 }
 
 type UberCoordinate struct {
-	latitude  float64
-	longitude float64
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
 }
 
 type UberAddress struct {
-	id           string
-	name         string
-	addressLine1 string
-	addressLine2 string
-	fullAddress  string
-	coordinate   UberCoordinate
-	locale       string
-	provider     string
-	timeZone     string
+	ID           string         `json:"id,omitempty"`
+	Name         string         `json:"name"`
+	AddressLine1 string         `json:"addressLine1"`
+	AddressLine2 string         `json:"addressLine2"`
+	FullAddress  string         `json:"fullAddress"`
+	Coordinate   UberCoordinate `json:"coordinate"`
+	Locale       string         `json:"locale"`
+	Provider     string         `json:"provider,omitempty"`
+	TimeZone     string         `json:"timeZone"`
 }
 
 type EstimateDetails struct {
@@ -174,7 +183,7 @@ type RideDetails struct {
 	ExpenseMemo          interface{} `json:"-"`
 	NoteForDriver        interface{} `json:"-"`
 	RequesterName        string      `json:"requesterName,omitempty"`
-	ClientFareNumeric    int         `json:"clientFareNumeric,omitempty"`
+	ClientFareNumeric    float64     `json:"clientFareNumeric,omitempty"`
 	ClientFareWithoutTip string      `json:"clientFareWithoutTip,omitempty"`
 	CityID               string      `json:"cityID,omitempty"`
 	ReserveDetails       struct {
@@ -192,9 +201,9 @@ type RideDetails struct {
 	} `json:"product"`
 	Status         string `json:"status,omitempty"`
 	DriverLocation struct {
-		Bearing   int `json:"bearing,omitempty"`
-		Latitude  int `json:"latitude,omitempty"`
-		Longitude int `json:"longitude,omitempty"`
+		Bearing   int     `json:"bearing,omitempty"`
+		Latitude  float64 `json:"latitude,omitempty"`
+		Longitude float64 `json:"longitude,omitempty"`
 	} `json:"driverLocation"`
 	Destination         PickupAndDestination `json:"destination"`
 	RequestTime         int                  `json:"requestTime,omitempty"`
@@ -202,69 +211,97 @@ type RideDetails struct {
 }
 
 type PickupAndDestination struct {
-	Eta       int    `json:"eta,omitempty"`
-	Latitude  int    `json:"latitude,omitempty"`
-	Longitude int    `json:"longitude,omitempty"`
-	Timezone  string `json:"timezone,omitempty"`
-	Address   string `json:"address,omitempty"`
-	Title     string `json:"title,omitempty"`
-	Subtitle  string `json:"subtitle,omitempty"`
-	ID        string `json:"id,omitempty"`
-	Provider  string `json:"provider,omitempty"`
+	Eta       int     `json:"eta,omitempty"`
+	Latitude  float64 `json:"latitude,omitempty"`
+	Longitude float64 `json:"longitude,omitempty"`
+	Timezone  string  `json:"timezone,omitempty"`
+	Address   string  `json:"address,omitempty"`
+	Title     string  `json:"title,omitempty"`
+	Subtitle  string  `json:"subtitle,omitempty"`
+	ID        string  `json:"id,omitempty"`
+	Provider  string  `json:"provider,omitempty"`
 }
 
 func NewUber(client *http.Client, cfg *config.App) Uber {
-	return Uber{
+	u := Uber{
 		Client:  client,
+		Policy:  newPolicy(cfg),
 		BaseURL: "https://central.uber.com/v2/api",
 		Cookie:  cfg.Env.Providers.Uber.Cookie,
-		LogoURL: "https://helios-i.mashable.com/imagery/articles/03y6VwlrZqnsuvnwR8CtGAL/hero-image.fill.size_1200x675.v1623372852.jpg",
 
-		OfferMapping:  map[string]string{},
-		StatusMapping: map[string]string{},
+		WebhookSecret: cfg.Env.Providers.Uber.WebhookSecret,
+		LogoURL:       "https://helios-i.mashable.com/imagery/articles/03y6VwlrZqnsuvnwR8CtGAL/hero-image.fill.size_1200x675.v1623372852.jpg",
+
+		// used when uber doesn't return the expiry of a fare
+		OfferValidity: 2 * time.Minute,
+
+		OfferMapping: map[string]string{
+			"UberX":    ECO,
+			"Green":    Green,
+			"UberXL":   VAN,
+			"Van":      VAN,
+			"Berline":  Business,
+			"Access":   Access,
+			"Pet":      ECO,
+			"Car Seat": ECO,
+		},
+
+		// uber has no option on its estimations, the products that can't honour them are filtered out and the
+		// options are written in the note for the driver
+		AmenityMapping: map[string]Amenities{
+			"UberX":    {Luggage: 2},
+			"Green":    {Luggage: 2},
+			"UberXL":   {Luggage: 5},
+			"Van":      {Luggage: 7},
+			"Berline":  {Luggage: 3},
+			"Access":   {Wheelchair: true, Luggage: 2},
+			"Pet":      {Pets: true, Luggage: 2},
+			"Car Seat": {ChildSeats: 1, Luggage: 2},
+		},
+
+		StatusMapping: map[string]string{
+			"processing":           Processing,
+			"accepted":             Accepted,
+			"arriving":             Arriving,
+			"in_progress":          InProgress,
+			"completed":            Completed,
+			"rider_canceled":       Cancelled,
+			"driver_canceled":      DriverCancelled,
+			"no_drivers_available": NoDriverFound,
+			"scheduled":            Scheduled,
+		},
 	}
+
+	// the base url can be overridden to target a fake or a staging api
+	if len(cfg.Env.Providers.Uber.BaseURL) > 0 {
+		u.BaseURL = cfg.Env.Providers.Uber.BaseURL
+	}
+
+	return u
 }
 
 func (u Uber) GetOffers(ctx context.Context, _ UserInfo, s models.Search, now time.Time) ([]models.Offer, error) {
 
 	reqBody := struct {
-		pickup           UberAddress
-		dropoff          UberAddress
-		capacity         int
-		scheduling       int64
-		rideSessionUuid  string
-		organizationUuid string
+		Pickup           UberAddress   `json:"pickup"`
+		Dropoff          UberAddress   `json:"dropoff"`
+		AdditionalStops  []UberAddress `json:"additionalStops,omitempty"`
+		Capacity         int           `json:"capacity"`
+		Scheduling       int64         `json:"scheduling,omitempty"`
+		RideSessionUuid  string        `json:"rideSessionUuid"`
+		OrganizationUuid string        `json:"organizationUuid"`
 	}{
-		pickup: UberAddress{
-			id:           "EiZQbC4gZGUgbCdFcXVlcnJlLCA4MzAwMCBUb3Vsb24sIEZyYW5jZSIuKiwKFAoSCSVBdgYSG8kSEVOj8oaQ_JWqEhQKEgn9sjV7AhvJEhEwyI_9pRkIBA",
-			name:         s.StartAddress,
-			addressLine1: s.StartAddress,
-			addressLine2: s.StartAddress,
-			fullAddress:  s.StartAddress,
-			coordinate:   UberCoordinate{s.StartLatitude, s.StartLongitude},
-			locale:       s.StartCountry,
-			provider:     "google_places",
-			timeZone:     "Europe/Paris",
-		},
-		dropoff: UberAddress{
-			id:           "ChIJg-EqoxobyRIRJtIEOeIpulo",
-			name:         s.EndAddress,
-			addressLine1: s.EndAddress,
-			addressLine2: s.EndAddress,
-			fullAddress:  s.EndAddress,
-			coordinate:   UberCoordinate{s.EndLatitude, s.EndLongitude},
-			locale:       s.EndCountry,
-			provider:     "google_places",
-			timeZone:     "Europe/Paris",
-		},
-		capacity:         1,
-		rideSessionUuid:  "1bc7f592-711a-4079-ac98-342eefd73e1f",
-		organizationUuid: "ee840421-c340-5053-b46a-37914dd7224d",
+		Pickup:           u.startAddress(s),
+		Dropoff:          u.endAddress(s),
+		AdditionalStops:  u.stopAddresses(s),
+		Capacity:         s.NbrOfPassenger,
+		RideSessionUuid:  validate.GenerateID(),
+		OrganizationUuid: uberOrganizationUUID,
 	}
 
 	if s.IsPlanned {
 		milliseconds := s.StartDate.UTC().UnixNano() / int64(time.Millisecond)
-		reqBody.scheduling = milliseconds
+		reqBody.Scheduling = milliseconds
 	}
 
 	data, err := json.Marshal(reqBody)
@@ -278,7 +315,7 @@ func (u Uber) GetOffers(ctx context.Context, _ UserInfo, s models.Search, now ti
 		return nil, fmt.Errorf("%w: %v", ErrFailedToCreateRequest, err)
 	}
 
-	resp, err := u.Client.Do(req)
+	resp, err := u.do(req, true)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", UberFailedToFetchApi, err)
 	}
@@ -289,7 +326,7 @@ func (u Uber) GetOffers(ctx context.Context, _ UserInfo, s models.Search, now ti
 	case http.StatusBadRequest:
 		var data any
 		json.NewDecoder(resp.Body).Decode(&data)
-		return nil, fmt.Errorf("failed to fetch offer for uber, receive following error: %v", data)
+		return nil, newStatusError(resp.StatusCode, "failed to fetch offer for uber, receive following error: %v", data)
 	case http.StatusOK:
 		var data UberResponseOffer
 
@@ -304,6 +341,9 @@ func (u Uber) GetOffers(ctx context.Context, _ UserInfo, s models.Search, now ti
 			if _, ok := u.OfferMapping[offer.Product.DisplayName]; !ok {
 				continue
 			}
+			if !u.AmenityMapping[offer.Product.DisplayName].Honours(s.Options) {
+				continue
+			}
 			filteredUberOffers = append(filteredUberOffers, offer)
 		}
 
@@ -315,7 +355,7 @@ func (u Uber) GetOffers(ctx context.Context, _ UserInfo, s models.Search, now ti
 	default:
 		var data any
 		json.NewDecoder(resp.Body).Decode(&data)
-		return nil, fmt.Errorf("unsupported status response, receive status %v and response body %v", resp.StatusCode, data)
+		return nil, newStatusError(resp.StatusCode, "unsupported status response, receive status %v and response body %v", resp.StatusCode, data)
 
 	}
 
@@ -348,7 +388,7 @@ func (u Uber) RequestRide(ctx context.Context, o models.Offer, ui UserInfo, s mo
 	}
 
 	type tripLeg struct {
-		AdditionalStops []interface{} `json:"-"`
+		AdditionalStops []UberAddress `json:"additionalStops,omitempty"`
 		Capacity        int           `json:"capacity"`
 		ExpenseMemo     string        `json:"expenseMemo"`
 		NoteForDriver   string        `json:"noteForDriver"`
@@ -380,32 +420,12 @@ func (u Uber) RequestRide(ctx context.Context, o models.Offer, ui UserInfo, s mo
 		},
 		AdditionalGuests: nil,
 		TripLegs: []tripLeg{
-			{AdditionalStops: nil,
-				Capacity:      1,
-				ExpenseMemo:   "",
-				NoteForDriver: s.StartAddress,
-				PickupAddress: UberAddress{
-					id:           "de93a71f-7782-4894-a114-5e357de81fa1",
-					name:         s.StartAddress,
-					addressLine1: s.StartAddress,
-					addressLine2: s.StartAddress,
-					fullAddress:  s.StartAddress,
-					coordinate:   UberCoordinate{s.StartLatitude, s.StartLongitude},
-					locale:       s.StartCountry,
-					provider:     "uber_geofences",
-					timeZone:     "Europe/Paris",
-				},
-				DropoffAddress: UberAddress{
-					id:           "EhxSdWUgZGUgUml2b2xpLCBQYXJpcywgRnJhbmNlIi4qLAoUChIJt4MohSFu5kcRUHvqO0vC-IgSFAoSCQ-34gYfbuZHEWCUjGjDggsE",
-					name:         s.EndAddress,
-					addressLine1: s.EndAddress,
-					addressLine2: s.EndAddress,
-					fullAddress:  s.EndAddress,
-					coordinate:   UberCoordinate{s.EndLatitude, s.EndLongitude},
-					locale:       s.EndCountry,
-					provider:     "uber_geofences",
-					timeZone:     "Europe/Paris",
-				},
+			{AdditionalStops: u.stopAddresses(s),
+				Capacity:       s.NbrOfPassenger,
+				ExpenseMemo:    "",
+				NoteForDriver:  u.noteForDriver(s),
+				PickupAddress:  u.startAddress(s),
+				DropoffAddress: u.endAddress(s),
 				Product: Product{
 					ProductID:         offerMetadata.Get("productID"),
 					DisplayName:       o.ProviderOfferName,
@@ -424,15 +444,16 @@ func (u Uber) RequestRide(ctx context.Context, o models.Offer, ui UserInfo, s mo
 						ScheduledThresholdMinutes:        120,
 						FreeCancellationThresholdMinutes: 60,
 					},
-					ParentProductTypeID: "6a8e56b8-914e-4b48-a387-e6ad21d9c00c",
+					ParentProductTypeID: offerMetadata.Get("parentProductTypeID"),
 				},
 				Estimate: estimate{
 					FareID: offerMetadata.Get("uberFareID"),
 					Fare: Fare{
-						Display:      fmt.Sprintf("%v %v", o.ProviderPrice, '€'),
-						ExpiresAt:    1636890197,
+						Display:      fmt.Sprintf("%v %v", o.ProviderPrice, "€"),
+						ExpiresAt:    int(o.ExpiresAt.Unix()),
 						FareID:       offerMetadata.Get("uberFareID"),
 						CurrencyCode: "eur",
+						FareValue:    o.ProviderPrice,
 					},
 					PickupEstimateInMinutes: 0,
 					Trip: Trip{
@@ -449,8 +470,8 @@ func (u Uber) RequestRide(ctx context.Context, o models.Offer, ui UserInfo, s mo
 		BypassSmsOptOutCheck: false,
 		CallEnabled:          true,
 		PolicyUuid:           "f9479684-539c-4480-bae7-b6ff5bdd64e9",
-		RideSessionUuid:      "e6fd2463-ad1f-493f-9e6f-dc67648154e7",
-		OrganizationUuid:     "ee840421-c340-5053-b46a-37914dd7224d",
+		RideSessionUuid:      validate.GenerateID(),
+		OrganizationUuid:     uberOrganizationUUID,
 	}
 
 	data, err := json.Marshal(reqBody)
@@ -458,12 +479,12 @@ func (u Uber) RequestRide(ctx context.Context, o models.Offer, ui UserInfo, s mo
 		return models.ProviderRide{}, fmt.Errorf("%w: %v", ErrFailedToMarshalRequest, err)
 	}
 
-	req, err := u.newRequest(ctx, http.MethodPost, fmt.Sprintf("createRide?localeCode=%v", s.StartCountry), bytes.NewReader(data))
+	req, err := u.newRequest(ctx, http.MethodPost, u.endpoint(fmt.Sprintf("createRide?localeCode=%v", s.StartCountry)), bytes.NewReader(data))
 	if err != nil {
 		return models.ProviderRide{}, fmt.Errorf("%w: %v", ErrFailedToCreateRequest, err)
 	}
 
-	resp, err := u.Client.Do(req)
+	resp, err := u.do(req, false)
 	if err != nil {
 		return models.ProviderRide{}, fmt.Errorf("%w: %v", UberFailedToFetchApi, err)
 	}
@@ -476,23 +497,74 @@ func (u Uber) RequestRide(ctx context.Context, o models.Offer, ui UserInfo, s mo
 		decoder := json.NewDecoder(resp.Body)
 
 		if err := decoder.Decode(&ride); err != nil {
-			return models.ProviderRide{}, fmt.Errorf("%w: %v", MySAMUnexpectedResponseBody, err)
+			return models.ProviderRide{}, fmt.Errorf("%w: %v", UberUnexpectedResponseBody, err)
+		}
+
+		if len(ride.Data.Rides) == 0 {
+			return models.ProviderRide{}, fmt.Errorf("%w: no ride created", UberUnexpectedResponseBody)
 		}
 
 		return u.convertProviderRide(ride.Data.Rides[0], o, now), nil
 	case http.StatusBadRequest:
 		var data any
 		json.NewDecoder(resp.Body).Decode(&data)
-		return models.ProviderRide{}, fmt.Errorf("failed to fetch offer for mysam, receive following error: %v", data)
+		return models.ProviderRide{}, newStatusError(resp.StatusCode, "failed to request ride for uber, receive following error: %v", data)
 	default:
 		var data any
 		json.NewDecoder(resp.Body).Decode(&data)
-		return models.ProviderRide{}, fmt.Errorf("unsupported status response, receive status %v and response body %v", resp.StatusCode, data)
+		return models.ProviderRide{}, newStatusError(resp.StatusCode, "unsupported status response, receive status %v and response body %v", resp.StatusCode, data)
 	}
 }
 
 func (u Uber) GetRide(ctx context.Context, ride models.Ride) (models.ProviderRide, error) {
-	return models.ProviderRide{}, nil
+	rideMetadata, err := url.ParseQuery(ride.ProviderRideID)
+	if err != nil {
+		return models.ProviderRide{}, fmt.Errorf("missing metadata inside provider ride id: [%w]", err)
+	}
+
+	reqBody := struct {
+		RideUUID string `json:"rideUUID"`
+	}{
+		RideUUID: rideMetadata.Get("uuid"),
+	}
+
+	data, err := json.Marshal(reqBody)
+	if err != nil {
+		return models.ProviderRide{}, fmt.Errorf("%w: %v", ErrFailedToMarshalRequest, err)
+	}
+
+	req, err := u.newRequest(ctx, http.MethodPost, u.endpoint("getRide"), bytes.NewReader(data))
+	if err != nil {
+		return models.ProviderRide{}, fmt.Errorf("%w: %v", ErrFailedToCreateRequest, err)
+	}
+
+	resp, err := u.do(req, true)
+	if err != nil {
+		return models.ProviderRide{}, fmt.Errorf("%w: %v", UberFailedToFetchApi, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var data any
+		json.NewDecoder(resp.Body).Decode(&data)
+		return models.ProviderRide{}, newStatusError(resp.StatusCode, "no ride found: %v", data)
+	}
+
+	var updatedRide UberResponseRide
+	decoder := json.NewDecoder(resp.Body)
+
+	if err := decoder.Decode(&updatedRide); err != nil {
+		return models.ProviderRide{}, fmt.Errorf("%w: %v", UberUnexpectedResponseBody, err)
+	}
+
+	if len(updatedRide.Data.Rides) == 0 {
+		return models.ProviderRide{}, fmt.Errorf("no ride found for uuid %v", reqBody.RideUUID)
+	}
+
+	res := u.convertRideData(updatedRide.Data.Rides[0], ride.ProviderPrice)
+	res.Id = ride.ProviderRideID
+
+	return res, nil
 }
 
 func (u Uber) CancelRide(ctx context.Context, ride models.Ride) (models.ProviderRide, error) {
@@ -512,46 +584,76 @@ func (u Uber) CancelRide(ctx context.Context, ride models.Ride) (models.Provider
 		return models.ProviderRide{}, fmt.Errorf("%w: %v", ErrFailedToMarshalRequest, err)
 	}
 
-	req, err := u.newRequest(ctx, http.MethodPost, "cancelRide", bytes.NewReader(data))
+	req, err := u.newRequest(ctx, http.MethodPost, u.endpoint("cancelRide"), bytes.NewReader(data))
 	if err != nil {
 		return models.ProviderRide{}, fmt.Errorf("%w: %v", ErrFailedToCreateRequest, err)
 	}
 
-	resp, err := u.Client.Do(req)
+	resp, err := u.do(req, false)
 	if err != nil {
 		return models.ProviderRide{}, fmt.Errorf("%w: %v", UberFailedToFetchApi, err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		var data any
+		json.NewDecoder(resp.Body).Decode(&data)
+		return models.ProviderRide{}, newStatusError(resp.StatusCode, "failed to cancel ride, receive status %v and response body %v", resp.StatusCode, data)
+	}
+
 	return models.ProviderRide{
 		Id:         ride.ProviderRideID,
 		Status:     Cancelled,
-		StatusName: "cancelled",
+		StatusName: "rider_canceled",
 		Price:      0,
 		ETA:        0,
 		Driver:     models.Driver{},
 	}, nil
 }
 
+func (u Uber) GetCancellationFees(_ context.Context, ride models.Ride, now time.Time) (models.CancellationQuote, error) {
+	rideMetadata, err := url.ParseQuery(ride.ProviderRideID)
+	if err != nil {
+		return models.CancellationQuote{}, fmt.Errorf("missing metadata inside provider ride id: [%w]", err)
+	}
+
+	fee, _ := strconv.ParseFloat(rideMetadata.Get("cancellationFee"), 64)
+	gracePeriod, _ := strconv.Atoi(rideMetadata.Get("cancellationGracePeriodInSeconds"))
+
+	policy := FeePolicy{
+		Amount:      fee,
+		Currency:    "eur",
+		GracePeriod: time.Duration(gracePeriod) * time.Second,
+	}
+
+	return policy.Quote(ride, now), nil
+}
+
+// MaxStops return the number of additional stops uber accept on a trip
+func (u Uber) MaxStops() int {
+	return uberMaxStops
+}
+
 func (u Uber) convertProviderOffer(offer ProductEstimates, s models.Search, now time.Time) models.Offer {
 	providerID := make(url.Values)
 
 	providerID.Set("uberFareID", offer.Estimate.FareID)
 	providerID.Set("parentProductTypeID", offer.Product.ParentProductTypeID)
-	providerID.Set("ubervvid", string(rune(offer.Product.VVID)))
+	providerID.Set("ubervvid", strconv.Itoa(offer.Product.VVID))
 	providerID.Set("productID", offer.Product.ProductID)
 	providerID.Set("cancellationFee", fmt.Sprint(offer.Product.CancellationFee))
-	providerID.Set("cancellationGracePeriodInSeconds", string(rune(offer.Product.CancellationGracePeriodSeconds)))
+	providerID.Set("cancellationGracePeriodInSeconds", strconv.Itoa(offer.Product.CancellationGracePeriodSeconds))
 
 	return models.Offer{
 		ID:                  validate.GenerateID(),
 		StartDate:           s.StartDate.String(),
-		Provider:            "uber",
+		Provider:            UberName,
 		ETA:                 float64(offer.Estimate.PickupEstimateInMinutes * 60),
 		ProviderOfferID:     providerID.Encode(),
 		LogoURL:             u.LogoURL,
 		VehicleType:         u.OfferMapping[offer.Product.DisplayName],
 		ProviderOfferName:   offer.Product.DisplayName,
+		ProviderProductID:   offer.Product.ProductID,
 		ProviderPrice:       offer.Estimate.Fare.FareValue,
 		DisplayPrice:        fmt.Sprintf("%f %s", offer.Estimate.Fare.FareValue, "€"),
 		DisplayPriceNumeric: offer.Estimate.Fare.FareValue,
@@ -563,21 +665,45 @@ func (u Uber) convertProviderOffer(offer ProductEstimates, s models.Search, now
 		Description:         "",
 		CreatedAt:           now.String(),
 		UpdatedAt:           now.String(),
+		Capacity:            offer.Product.Capacity,
+		ExpiresAt:           u.fareExpiry(offer.Estimate.Fare, now),
 	}
 }
 
-func (u Uber) convertProviderRide(ride UberRideData, o models.Offer, now time.Time) models.ProviderRide {
-	eta := float64(ride.RideDetails.Pickup.Eta)
+// fareExpiry return the date until which uber guarantee the fare, expiresAt being an unix timestamp in seconds
+func (u Uber) fareExpiry(fare Fare, now time.Time) time.Time {
+	if fare.ExpiresAt <= 0 {
+		return now.Add(u.OfferValidity)
+	}
 
+	return time.Unix(int64(fare.ExpiresAt), 0)
+}
+
+func (u Uber) convertProviderRide(ride UberRideData, o models.Offer, now time.Time) models.ProviderRide {
 	providerID := make(url.Values)
 
 	providerID.Set("uuid", ride.UUID)
 	providerID.Set("acceptedAt", ride.AcceptedAt)
 
-	price := float64(ride.RideDetails.ClientFareNumeric)
+	// keep the cancellation policy of the booked product to quote the cancellation fees later on
+	if offerMetadata, err := url.ParseQuery(o.ProviderOfferID); err == nil {
+		providerID.Set("cancellationFee", offerMetadata.Get("cancellationFee"))
+		providerID.Set("cancellationGracePeriodInSeconds", offerMetadata.Get("cancellationGracePeriodInSeconds"))
+	}
+
+	res := u.convertRideData(ride, o.ProviderPrice)
+	res.Id = providerID.Encode()
+	res.Ref = ride.UUID
+
+	return res
+}
+
+// convertRideData convert the uber ride into a provider ride, the given price is used until uber return the fare
+func (u Uber) convertRideData(ride UberRideData, price float64) models.ProviderRide {
+	eta := float64(ride.RideDetails.Pickup.Eta)
 
-	if ride.RideDetails.ClientFareNumeric == 0 {
-		price = o.ProviderPrice
+	if ride.RideDetails.ClientFareNumeric > 0 {
+		price = ride.RideDetails.ClientFareNumeric
 	}
 
 	status := u.StatusMapping[ride.RideDetails.Status]
@@ -587,19 +713,81 @@ func (u Uber) convertProviderRide(ride UberRideData, o models.Offer, now time.Ti
 	}
 
 	return models.ProviderRide{
-		Id:         providerID.Encode(),
 		Status:     status,
 		StatusName: ride.RideDetails.Status,
 		Price:      price,
 		ETA:        eta,
-		Driver:     models.Driver{},
+		Driver: models.Driver{
+			DriverName:      ride.Driver.Name,
+			DriverPhone:     ride.Driver.PhoneNumber,
+			DriverLatitude:  ride.RideDetails.DriverLocation.Latitude,
+			DriverLongitude: ride.RideDetails.DriverLocation.Longitude,
+			DriverHeading:   float64(ride.RideDetails.DriverLocation.Bearing),
+			CarModel:        ride.Vehicle.CarName,
+			CarPhoto:        ride.Vehicle.PictureUrl,
+			CarLicense:      ride.Vehicle.LicensePlate,
+		},
 	}
 }
 
+// startAddress convert the search start point into an uber address. The place id is only sent when known,
+// otherwise uber resolve the address from its coordinate.
+func (u Uber) startAddress(s models.Search) UberAddress {
+	return u.address(s.StartPlaceID, s.StartAddress, s.StartCountry, s.StartLatitude, s.StartLongitude)
+}
+
+// endAddress convert the search end point into an uber address
+func (u Uber) endAddress(s models.Search) UberAddress {
+	return u.address(s.EndPlaceID, s.EndAddress, s.EndCountry, s.EndLatitude, s.EndLongitude)
+}
+
+// noteForDriver give the pickup address to the driver, followed by the passenger options when there are some
+func (u Uber) noteForDriver(s models.Search) string {
+	if note := optionsNote(s.Options); len(note) > 0 {
+		return fmt.Sprintf("%v - %v", s.StartAddress, note)
+	}
+
+	return s.StartAddress
+}
+
+// stopAddresses convert the search intermediate stops into uber addresses, keeping their order
+func (u Uber) stopAddresses(s models.Search) []UberAddress {
+	var stops []UberAddress
+	for _, stop := range s.Stops {
+		stops = append(stops, u.address(stop.PlaceID, stop.Address, stop.Country, stop.Latitude, stop.Longitude))
+	}
+
+	return stops
+}
+
+func (u Uber) address(placeID, address, country string, latitude, longitude float64) UberAddress {
+	addr := UberAddress{
+		ID:           placeID,
+		Name:         address,
+		AddressLine1: address,
+		AddressLine2: address,
+		FullAddress:  address,
+		Coordinate:   UberCoordinate{Latitude: latitude, Longitude: longitude},
+		Locale:       country,
+		TimeZone:     "Europe/Paris",
+	}
+
+	if len(placeID) > 0 {
+		addr.Provider = "google_places"
+	}
+
+	return addr
+}
+
 func (u Uber) endpoint(path string) string {
 	return fmt.Sprintf("%v/%v", u.BaseURL, path)
 }
 
+// do send the request through the provider resilience policy, only idempotent requests are retried
+func (u Uber) do(req *http.Request, idempotent bool) (*http.Response, error) {
+	return do(u.Client, UberName, u.Policy, req, idempotent)
+}
+
 func (u Uber) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
 	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
@@ -612,7 +800,7 @@ func (u Uber) newRequest(ctx context.Context, method, url string, body io.Reader
 		"x-csrf-token":                    {"x"},
 		"sec-ch-ua-mobile":                {"?0"},
 		"user-agent":                      {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/97.0.4692.71 Safari/537.36"},
-		"x-guest-rides-organization-uuid": {"ee840421-c340-5053-b46a-37914dd7224d"},
+		"x-guest-rides-organization-uuid": {uberOrganizationUUID},
 		"x-requested-with":                {"XMLHttpRequest"},
 		"x-guest-rides-app-version":       {"1.0.0"},
 		"content-type":                    {"application/json"},
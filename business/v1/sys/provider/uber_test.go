@@ -0,0 +1,130 @@
+package provider_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"vtc/business/v1/data/models"
+	"vtc/business/v1/sys/provider"
+	"vtc/business/v1/sys/provider/fake"
+	"vtc/foundation/config"
+)
+
+func Test_Uber(t *testing.T) {
+	t.Log("Given the need to order rides through uber")
+	{
+		now := time.Now()
+		clock := now
+
+		backend := fake.New(fake.Scenario{
+			Price:            21.5,
+			CancellationFees: 5,
+			Progression: []fake.Step{
+				{After: 0, Status: provider.Processing},
+				{After: time.Minute, Status: provider.Accepted},
+			},
+		})
+		backend.Now = func() time.Time { return clock }
+
+		srv := httptest.NewServer(backend.Handler())
+		defer srv.Close()
+
+		cfg := config.App{}
+		cfg.Env.Providers.Uber.Cookie = "cookie"
+		cfg.Env.Providers.Uber.BaseURL = srv.URL + fake.UberPath
+
+		uber := provider.NewUber(srv.Client(), &cfg)
+
+		search := models.Search{
+			ID:             "search-id",
+			StartAddress:   "Rue de Rivoli, Paris",
+			StartLatitude:  48.86,
+			StartLongitude: 2.34,
+			StartCountry:   "fr",
+			StartPlaceID:   "place-start",
+			EndAddress:     "Place de l'Etoile, Paris",
+			EndLatitude:    48.87,
+			EndLongitude:   2.29,
+			EndCountry:     "fr",
+			Stops:          []models.Stop{{Address: "Place de la Concorde, Paris", Latitude: 48.865, Longitude: 2.321, Country: "fr"}},
+			NbrOfPassenger: 2,
+			StartDate:      now,
+		}
+
+		offers, err := uber.GetOffers(context.Background(), provider.UserInfo{}, search, now)
+		if err != nil {
+			t.Fatalf("\t%s\t Test: \tShould be able to fetch offers: %v", failure, err)
+		}
+		if len(offers) != 3 || offers[0].VehicleType != provider.ECO || offers[0].ProviderPrice != 21.5 || offers[0].Provider != "uber" {
+			t.Fatalf("\t%s\t Test: \tShould only return mapped offers, receive %+v", failure, offers)
+		}
+		for _, o := range offers {
+			if o.ProviderOfferName == "Helicopter" {
+				t.Fatalf("\t%s\t Test: \tShould filter out the unknown products, receive %+v", failure, o)
+			}
+		}
+		t.Logf("\t%s\t Test: \tShould be able to fetch offers", success)
+
+		received := backend.LastRequest(fake.UberPath + "/getProductEstimates")
+		pickup, _ := received["pickup"].(map[string]any)
+		coordinate, _ := pickup["coordinate"].(map[string]any)
+		if pickup["fullAddress"] != search.StartAddress || pickup["id"] != search.StartPlaceID || coordinate["latitude"] != search.StartLatitude {
+			t.Fatalf("\t%s\t Test: \tShould send the pickup address, receive %v", failure, received["pickup"])
+		}
+		t.Logf("\t%s\t Test: \tShould send the pickup and dropoff address", success)
+
+		stops, _ := received["additionalStops"].([]any)
+		if len(stops) != 1 || stops[0].(map[string]any)["fullAddress"] != search.Stops[0].Address {
+			t.Fatalf("\t%s\t Test: \tShould send the intermediate stops, receive %v", failure, received["additionalStops"])
+		}
+		t.Logf("\t%s\t Test: \tShould send the intermediate stops", success)
+
+		rideInfo, err := uber.RequestRide(context.Background(), offers[0], provider.UserInfo{FirstName: "Jane"}, search, now)
+		if err != nil {
+			t.Fatalf("\t%s\t Test: \tShould be able to request a ride: %v", failure, err)
+		}
+		if rideInfo.Status != provider.Processing || rideInfo.Price != 21.5 {
+			t.Fatalf("\t%s\t Test: \tShould return the booked ride, receive %+v", failure, rideInfo)
+		}
+		t.Logf("\t%s\t Test: \tShould be able to request a ride", success)
+
+		created := backend.LastRequest(fake.UberPath + "/createRide")
+		legs, _ := created["tripLegs"].([]any)
+		var fare map[string]any
+		if len(legs) == 1 {
+			estimate, _ := legs[0].(map[string]any)["estimate"].(map[string]any)
+			fare, _ = estimate["fare"].(map[string]any)
+		}
+		if fare["expiresAt"] != float64(offers[0].ExpiresAt.Unix()) {
+			t.Fatalf("\t%s\t Test: \tShould send the fare expiry as an unix timestamp, receive %v", failure, fare["expiresAt"])
+		}
+		t.Logf("\t%s\t Test: \tShould send the fare expiry as an unix timestamp", success)
+
+		ride := models.Ride{ProviderRideID: rideInfo.Id, ProviderPrice: rideInfo.Price, Status: rideInfo.Status}
+
+		clock = now.Add(2 * time.Minute)
+		updated, err := uber.GetRide(context.Background(), ride)
+		if err != nil {
+			t.Fatalf("\t%s\t Test: \tShould be able to get a ride: %v", failure, err)
+		}
+		if updated.Status != provider.Accepted || updated.Driver.DriverName != "John Doe" || updated.Driver.DriverLatitude != 48.85 || updated.Driver.DriverHeading != 90 || updated.Id != ride.ProviderRideID {
+			t.Fatalf("\t%s\t Test: \tShould return the updated ride, receive %+v", failure, updated)
+		}
+		t.Logf("\t%s\t Test: \tShould be able to get a ride", success)
+
+		ride.StatusHistory = []models.StatusChange{{To: provider.Accepted, Date: now}}
+		quote, err := uber.GetCancellationFees(context.Background(), ride, now.Add(3*time.Minute))
+		if err != nil || quote.Amount != 5 {
+			t.Fatalf("\t%s\t Test: \tShould quote the product cancellation fees, receive %+v: %v", failure, quote, err)
+		}
+		t.Logf("\t%s\t Test: \tShould quote the product cancellation fees", success)
+
+		cancelled, err := uber.CancelRide(context.Background(), ride)
+		if err != nil || cancelled.Status != provider.Cancelled {
+			t.Fatalf("\t%s\t Test: \tShould be able to cancel a ride, receive %+v: %v", failure, cancelled, err)
+		}
+		t.Logf("\t%s\t Test: \tShould be able to cancel a ride", success)
+	}
+}
@@ -0,0 +1,129 @@
+package provider
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"vtc/foundation/config"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrInvalidWebhook   = errors.New("invalid webhook payload")
+)
+
+// List of the headers carrying the webhook signatures
+const (
+	MySamSecretHeader   = "X-Mysam-Secret"
+	UberSignatureHeader = "X-Uber-Signature"
+)
+
+// WebhookEvent represent a ride status change pushed by a provider. RideRef is the provider own reference of the
+// ride and Status the status translated through the provider StatusMapping.
+type WebhookEvent struct {
+	ID         string
+	RideRef    string
+	Status     string
+	StatusName string
+}
+
+// WebhookParser is implemented by the providers pushing the status changes of their rides
+type WebhookParser interface {
+	ParseWebhook(headers map[string]string, body []byte) (WebhookEvent, error)
+}
+
+// ParseWebhook verify the signature of the webhook pushed by the given provider and parse its event
+func ParseWebhook(cfg *config.App, name string, headers map[string]string, body []byte) (WebhookEvent, error) {
+	var parser WebhookParser
+
+	switch name {
+	case MySamName:
+		parser = NewMySam(nil, cfg)
+	case UberName:
+		parser = NewUber(nil, cfg)
+	default:
+		return WebhookEvent{}, fmt.Errorf("%w: %v", ErrUnknownProvider, name)
+	}
+
+	return parser.ParseWebhook(headers, body)
+}
+
+// ParseWebhook verify the shared secret sent by mysam and parse the trip status change
+func (p MySam) ParseWebhook(headers map[string]string, body []byte) (WebhookEvent, error) {
+	secret := header(headers, MySamSecretHeader)
+	if len(p.WebhookSecret) == 0 || subtle.ConstantTimeCompare([]byte(secret), []byte(p.WebhookSecret)) != 1 {
+		return WebhookEvent{}, ErrInvalidSignature
+	}
+
+	var payload struct {
+		EventID string `json:"eventId"`
+		TripID  int    `json:"tripId"`
+		Status  string `json:"status"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return WebhookEvent{}, fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+
+	return newWebhookEvent(payload.EventID, fmt.Sprint(payload.TripID), payload.Status, p.StatusMapping)
+}
+
+// ParseWebhook verify the hmac signature of the uber payload and parse the ride status change
+func (u Uber) ParseWebhook(headers map[string]string, body []byte) (WebhookEvent, error) {
+	if len(u.WebhookSecret) == 0 || !validHMAC(u.WebhookSecret, body, header(headers, UberSignatureHeader)) {
+		return WebhookEvent{}, ErrInvalidSignature
+	}
+
+	var payload struct {
+		EventID    string `json:"event_id"`
+		EventType  string `json:"event_type"`
+		ResourceID string `json:"resource_id"`
+		Meta       struct {
+			Status string `json:"status"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return WebhookEvent{}, fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+
+	return newWebhookEvent(payload.EventID, payload.ResourceID, payload.Meta.Status, u.StatusMapping)
+}
+
+// newWebhookEvent check the event is complete and translate the provider status
+func newWebhookEvent(id, rideRef, statusName string, mapping map[string]string) (WebhookEvent, error) {
+	status, ok := mapping[statusName]
+	if len(id) == 0 || len(rideRef) == 0 || !ok {
+		return WebhookEvent{}, fmt.Errorf("%w: missing event id, ride or unknown status %q", ErrInvalidWebhook, statusName)
+	}
+
+	return WebhookEvent{ID: id, RideRef: rideRef, Status: status, StatusName: statusName}, nil
+}
+
+// validHMAC check the hex encoded hmac sha256 signature of the body
+func validHMAC(secret string, body []byte, signature string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// header return the value of a header whatever its case, api gateway keep the case sent by the caller
+func header(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,69 @@
+package provider_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"vtc/business/v1/sys/provider"
+	"vtc/foundation/config"
+)
+
+func Test_Webhook(t *testing.T) {
+	t.Log("Given the need to trust the ride updates pushed by the providers")
+	{
+		var cfg config.App
+		cfg.Env.Providers.MySam.WebhookSecret = "mysam-secret"
+		cfg.Env.Providers.Uber.WebhookSecret = "uber-secret"
+
+		mySamBody := []byte(`{"eventId":"evt-1","tripId":42,"status":"ASSIGNED"}`)
+
+		ev, err := provider.ParseWebhook(&cfg, provider.MySamName, map[string]string{"x-mysam-secret": "mysam-secret"}, mySamBody)
+		if err != nil {
+			t.Fatalf("\t%s\t Test: \tShould accept the mysam webhook: %v", failure, err)
+		}
+		if ev.ID != "evt-1" || ev.RideRef != "42" || ev.Status != provider.Accepted {
+			t.Fatalf("\t%s\t Test: \tShould map the mysam status, receive %+v", failure, ev)
+		}
+		t.Logf("\t%s\t Test: \tShould map the mysam event through its status mapping", success)
+
+		if _, err := provider.ParseWebhook(&cfg, provider.MySamName, map[string]string{"X-Mysam-Secret": "wrong"}, mySamBody); !errors.Is(err, provider.ErrInvalidSignature) {
+			t.Fatalf("\t%s\t Test: \tShould reject a wrong mysam secret, receive %v", failure, err)
+		}
+		t.Logf("\t%s\t Test: \tShould reject a wrong mysam secret", success)
+
+		uberBody := []byte(`{"event_id":"evt-2","event_type":"requests.status_changed","resource_id":"ride-uuid","meta":{"status":"driver_canceled"}}`)
+
+		mac := hmac.New(sha256.New, []byte("uber-secret"))
+		mac.Write(uberBody)
+		signature := hex.EncodeToString(mac.Sum(nil))
+
+		ev, err = provider.ParseWebhook(&cfg, provider.UberName, map[string]string{provider.UberSignatureHeader: signature}, uberBody)
+		if err != nil {
+			t.Fatalf("\t%s\t Test: \tShould accept the signed uber webhook: %v", failure, err)
+		}
+		if ev.ID != "evt-2" || ev.RideRef != "ride-uuid" || ev.Status != provider.DriverCancelled {
+			t.Fatalf("\t%s\t Test: \tShould map the uber status, receive %+v", failure, ev)
+		}
+		t.Logf("\t%s\t Test: \tShould verify the uber signature and map its status", success)
+
+		tampered := []byte(`{"event_id":"evt-2","event_type":"requests.status_changed","resource_id":"ride-uuid","meta":{"status":"completed"}}`)
+		if _, err := provider.ParseWebhook(&cfg, provider.UberName, map[string]string{provider.UberSignatureHeader: signature}, tampered); !errors.Is(err, provider.ErrInvalidSignature) {
+			t.Fatalf("\t%s\t Test: \tShould reject a tampered uber payload, receive %v", failure, err)
+		}
+		t.Logf("\t%s\t Test: \tShould reject a tampered uber payload", success)
+
+		cfg.Env.Providers.MySam.WebhookSecret = ""
+		if _, err := provider.ParseWebhook(&cfg, provider.MySamName, map[string]string{"X-Mysam-Secret": ""}, mySamBody); !errors.Is(err, provider.ErrInvalidSignature) {
+			t.Fatalf("\t%s\t Test: \tShould reject the webhooks when no secret is configured, receive %v", failure, err)
+		}
+		t.Logf("\t%s\t Test: \tShould reject the webhooks when no secret is configured", success)
+
+		if _, err := provider.ParseWebhook(&cfg, "bolt", nil, nil); !errors.Is(err, provider.ErrUnknownProvider) {
+			t.Fatalf("\t%s\t Test: \tShould reject an unknown provider, receive %v", failure, err)
+		}
+		t.Logf("\t%s\t Test: \tShould reject an unknown provider", success)
+	}
+}
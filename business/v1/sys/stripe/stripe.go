@@ -1,13 +1,26 @@
 package stripe
 
 import (
+	"errors"
 	"fmt"
+	"math"
 
 	"github.com/stripe/stripe-go/v74"
 	"github.com/stripe/stripe-go/v74/client"
 	model "vtc/business/v1/data/models"
 )
 
+// ErrPaymentCanceled is returned when capturing a payment that was canceled, e.g. a pre-authorization expired
+var ErrPaymentCanceled = errors.New("payment canceled")
+
+// List of values that a payment cancellation reason can take
+const (
+	CancellationReasonDuplicate           = "duplicate"
+	CancellationReasonFraudulent          = "fraudulent"
+	CancellationReasonRequestedByCustomer = "requested_by_customer"
+	CancellationReasonAbandoned           = "abandoned"
+)
+
 // List of values that PaymentIntentStatus can take
 const (
 	PaymentIntentStatusCanceled              stripe.PaymentIntentStatus = "canceled"
@@ -19,6 +32,13 @@ const (
 	PaymentIntentStatusSucceeded             stripe.PaymentIntentStatus = "succeeded"
 )
 
+// List of values that a refund reason can take
+const (
+	RefundReasonDuplicate           = "duplicate"
+	RefundReasonFraudulent          = "fraudulent"
+	RefundReasonRequestedByCustomer = "requested_by_customer"
+)
+
 type Customer struct {
 	Email       string
 	PhoneNumber string
@@ -130,7 +150,7 @@ func CreateCharge(key string, amount float64, userStripeID, paymentMethodID, ret
 	sc := client.New(key, nil)
 
 	intent, err := sc.PaymentIntents.New(&stripe.PaymentIntentParams{
-		Amount:        stripe.Int64(int64(amount) * 100),
+		Amount:        stripe.Int64(toCents(amount)),
 		Customer:      stripe.String(userStripeID),
 		PaymentMethod: stripe.String(paymentMethodID),
 		OffSession:    stripe.Bool(true),
@@ -162,9 +182,66 @@ func CreateCharge(key string, amount float64, userStripeID, paymentMethodID, ret
 	}, nil
 }
 
+// ChargeOffSession create and capture immediately a new payment on a saved payment method of the user.
+// It is used to charge an amount that couldn't be covered by a previous pre-authorization. The idempotency key make
+// retrying the same charge safe, stripe return the payment already created instead of charging twice.
+func ChargeOffSession(key string, amount float64, userStripeID, paymentMethodID, currency, idempotencyKey string) (Charge, error) {
+	sc := client.New(key, nil)
+
+	params := &stripe.PaymentIntentParams{
+		Amount:        stripe.Int64(toCents(amount)),
+		Customer:      stripe.String(userStripeID),
+		PaymentMethod: stripe.String(paymentMethodID),
+		OffSession:    stripe.Bool(true),
+		Confirm:       stripe.Bool(true),
+		CaptureMethod: stripe.String("automatic"),
+		Currency:      stripe.String(currency),
+	}
+	params.SetIdempotencyKey(idempotencyKey)
+
+	intent, err := sc.PaymentIntents.New(params)
+	if err != nil {
+		return Charge{}, fmt.Errorf("failed to create a new off session payment intent: [%w]", err)
+	}
+
+	return Charge{
+		ID:     intent.ID,
+		Status: intent.Status,
+	}, nil
+}
+
+// PreAuthorizeOffSession create a new pre-authorization on a saved payment method of the user. It is used to renew
+// a pre-authorization that would expire before the ride, the capture method is manual. The idempotency key make
+// retrying the same renewal safe, stripe return the pre-authorization already created instead of holding twice.
+func PreAuthorizeOffSession(key string, amount float64, userStripeID, paymentMethodID, currency, idempotencyKey string) (Charge, error) {
+	sc := client.New(key, nil)
+
+	params := &stripe.PaymentIntentParams{
+		Amount:        stripe.Int64(toCents(amount)),
+		Customer:      stripe.String(userStripeID),
+		PaymentMethod: stripe.String(paymentMethodID),
+		OffSession:    stripe.Bool(true),
+		Confirm:       stripe.Bool(true),
+		CaptureMethod: stripe.String("manual"),
+		Currency:      stripe.String(currency),
+	}
+	params.SetIdempotencyKey(idempotencyKey)
+
+	intent, err := sc.PaymentIntents.New(params)
+	if err != nil {
+		return Charge{}, fmt.Errorf("failed to create a new off session pre-authorization: [%w]", err)
+	}
+
+	return Charge{
+		ID:     intent.ID,
+		Status: intent.Status,
+	}, nil
+}
+
 // CapturePayment capture the given amount for the payment. If the amount is inferior to the blocked amount, the remaining
-// sum will be refund
-func CapturePayment(key, preAuthID string, amount int64) error {
+// sum will be refund. The idempotency key make retrying the same capture safe. A payment already captured is left as
+// is while a canceled one, such as an expired pre-authorization, can't be captured anymore.
+func CapturePayment(key, preAuthID string, amount float64, idempotencyKey string) error {
 	sc := client.New(key, nil)
 
 	pi, err := sc.PaymentIntents.Get(preAuthID, nil)
@@ -172,19 +249,59 @@ func CapturePayment(key, preAuthID string, amount int64) error {
 		return fmt.Errorf("failed to retrieve givent payment: [%w]", err)
 	}
 
-	if pi.Status == stripe.PaymentIntentStatusCanceled || pi.Status == stripe.PaymentIntentStatusSucceeded {
+	switch pi.Status {
+	case stripe.PaymentIntentStatusSucceeded:
 		return nil
+	case stripe.PaymentIntentStatusCanceled:
+		return fmt.Errorf("%w: payment %v can't be captured", ErrPaymentCanceled, pi.ID)
 	}
 
-	if _, err := sc.PaymentIntents.Capture(pi.ID, &stripe.PaymentIntentCaptureParams{
-		AmountToCapture: stripe.Int64(amount * 100),
-	}); err != nil {
+	params := &stripe.PaymentIntentCaptureParams{
+		AmountToCapture: stripe.Int64(toCents(amount)),
+	}
+	params.SetIdempotencyKey(idempotencyKey)
+
+	if _, err := sc.PaymentIntents.Capture(pi.ID, params); err != nil {
 		return fmt.Errorf("failed to capture payment: [%w]", err)
 	}
 
 	return nil
 }
 
+// Refund represent a refund of a captured payment
+type Refund struct {
+	ID     string
+	Status stripe.RefundStatus
+	Amount float64
+}
+
+// RefundPayment refund the given amount of a captured payment. The idempotency key make retrying the same refund
+// safe, stripe return the refund already created instead of refunding twice. The metadata are attached to the refund.
+func RefundPayment(key, paymentIntentID string, amount float64, reason, idempotencyKey string, metadata map[string]string) (Refund, error) {
+	sc := client.New(key, nil)
+
+	params := &stripe.RefundParams{
+		PaymentIntent: stripe.String(paymentIntentID),
+		Amount:        stripe.Int64(toCents(amount)),
+		Reason:        stripe.String(reason),
+	}
+	params.SetIdempotencyKey(idempotencyKey)
+	for k, v := range metadata {
+		params.AddMetadata(k, v)
+	}
+
+	r, err := sc.Refunds.New(params)
+	if err != nil {
+		return Refund{}, fmt.Errorf("failed to refund payment %v: [%w]", paymentIntentID, err)
+	}
+
+	return Refund{
+		ID:     r.ID,
+		Status: r.Status,
+		Amount: float64(r.Amount) / 100,
+	}, nil
+}
+
 // GetPaymentIntent retrieve a stripe payment intent
 func GetPaymentIntent(key, id string) (*stripe.PaymentIntent, error) {
 	sc := client.New(key, nil)
@@ -196,3 +313,8 @@ func GetPaymentIntent(key, id string) (*stripe.PaymentIntent, error) {
 
 	return pi, nil
 }
+
+// toCents convert an amount expressed in the currency unit into the smallest currency unit used by stripe
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
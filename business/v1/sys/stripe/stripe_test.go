@@ -0,0 +1,148 @@
+package stripe_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	stripeapi "github.com/stripe/stripe-go/v74"
+	"vtc/business/v1/sys/stripe"
+)
+
+const (
+	success = "\u2713"
+	failure = "\u2717"
+)
+
+// newStripe point the stripe client to a local server answering with the given handler
+func newStripe(t *testing.T, h http.HandlerFunc) {
+	srv := httptest.NewServer(h)
+
+	backend := stripeapi.GetBackendWithConfig(stripeapi.APIBackend, &stripeapi.BackendConfig{
+		URL:               stripeapi.String(srv.URL),
+		MaxNetworkRetries: stripeapi.Int64(0),
+		LeveledLogger:     &stripeapi.LeveledLogger{Level: stripeapi.LevelNull},
+	})
+	stripeapi.SetBackend(stripeapi.APIBackend, backend)
+
+	t.Cleanup(func() {
+		srv.Close()
+		stripeapi.SetBackend(stripeapi.APIBackend, nil)
+	})
+}
+
+func Test_RefundPayment(t *testing.T) {
+	t.Log("Given the need to refund a captured payment")
+	{
+		var req *http.Request
+		newStripe(t, func(w http.ResponseWriter, r *http.Request) {
+			if err := r.ParseForm(); err != nil {
+				t.Errorf("failed to parse the refund request: %v", err)
+			}
+			req = r
+
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"id": "re_123", "object": "refund", "amount": 1234, "status": "succeeded"}`))
+		})
+
+		metadata := map[string]string{"rideID": "ride-1", "operator": "support@thegoodseat.fr"}
+		r, err := stripe.RefundPayment("sk_test", "pi_123", 12.34, stripe.RefundReasonRequestedByCustomer, "refund-ride-1-0-pi_123", metadata)
+		if err != nil {
+			t.Fatalf("\t%s\t Test: \tShould refund the payment: %v", failure, err)
+		}
+		t.Logf("\t%s\t Test: \tShould refund the payment", success)
+
+		if req.Method != http.MethodPost || req.URL.Path != "/v1/refunds" {
+			t.Fatalf("\t%s\t Test: \tShould create a stripe refund: got %v %v", failure, req.Method, req.URL.Path)
+		}
+		t.Logf("\t%s\t Test: \tShould create a stripe refund", success)
+
+		form := map[string]string{
+			"payment_intent":     "pi_123",
+			"amount":             "1234",
+			"reason":             stripe.RefundReasonRequestedByCustomer,
+			"metadata[rideID]":   "ride-1",
+			"metadata[operator]": "support@thegoodseat.fr",
+		}
+		for k, v := range form {
+			if got := req.PostForm.Get(k); got != v {
+				t.Fatalf("\t%s\t Test: \tShould send %v: got %q, want %q", failure, k, got, v)
+			}
+		}
+		t.Logf("\t%s\t Test: \tShould send the amount in cents, the reason and the metadata", success)
+
+		if key := req.Header.Get("Idempotency-Key"); key != "refund-ride-1-0-pi_123" {
+			t.Fatalf("\t%s\t Test: \tShould send the idempotency key: got %q", failure, key)
+		}
+		t.Logf("\t%s\t Test: \tShould send the idempotency key", success)
+
+		if r.ID != "re_123" || r.Amount != 12.34 || r.Status != stripeapi.RefundStatusSucceeded {
+			t.Fatalf("\t%s\t Test: \tShould return the stripe refund: got %+v", failure, r)
+		}
+		t.Logf("\t%s\t Test: \tShould return the refunded amount in euros", success)
+	}
+}
+
+func Test_RefundPaymentFailed(t *testing.T) {
+	t.Log("Given the need to report a refund rejected by stripe")
+	{
+		newStripe(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": {"type": "invalid_request_error", "code": "charge_already_refunded", "message": "Charge has already been refunded."}}`))
+		})
+
+		_, err := stripe.RefundPayment("sk_test", "pi_123", 10, stripe.RefundReasonRequestedByCustomer, "refund-ride-1-1-pi_123", nil)
+
+		var stripeErr *stripeapi.Error
+		if !errors.As(err, &stripeErr) || stripeErr.Code != stripeapi.ErrorCodeChargeAlreadyRefunded {
+			t.Fatalf("\t%s\t Test: \tShould wrap the stripe error: got %v", failure, err)
+		}
+		t.Logf("\t%s\t Test: \tShould wrap the stripe error", success)
+	}
+}
+
+func Test_CapturePayment(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		captured bool
+		err      error
+	}{
+		{name: "capture a pre-authorized payment", status: "requires_capture", captured: true},
+		{name: "leave an already captured payment as is", status: "succeeded"},
+		{name: "reject a canceled payment", status: "canceled", err: stripe.ErrPaymentCanceled},
+	}
+
+	t.Log("Given the need to capture a pre-authorized payment")
+	{
+		for _, tt := range tests {
+			var capture *http.Request
+			newStripe(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPost {
+					if err := r.ParseForm(); err != nil {
+						t.Errorf("failed to parse the capture request: %v", err)
+					}
+					capture = r
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprintf(w, `{"id": "pi_123", "object": "payment_intent", "status": %q}`, tt.status)
+			})
+
+			err := stripe.CapturePayment("sk_test", "pi_123", 12.34, "capture-ride-1-pi_123")
+			if !errors.Is(err, tt.err) || (tt.err == nil && err != nil) {
+				t.Fatalf("\t%s\t Test: \tShould %s: got %v", failure, tt.name, err)
+			}
+			if tt.captured != (capture != nil) {
+				t.Fatalf("\t%s\t Test: \tShould %s: captured %v", failure, tt.name, capture != nil)
+			}
+			if capture != nil && (capture.URL.Path != "/v1/payment_intents/pi_123/capture" || capture.PostForm.Get("amount_to_capture") != "1234" || capture.Header.Get("Idempotency-Key") != "capture-ride-1-pi_123") {
+				t.Fatalf("\t%s\t Test: \tShould %s: got %v %v", failure, tt.name, capture.URL.Path, capture.PostForm)
+			}
+			t.Logf("\t%s\t Test: \tShould %s", success, tt.name)
+		}
+	}
+}
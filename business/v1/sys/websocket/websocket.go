@@ -0,0 +1,50 @@
+// Package websocket push messages to the clients connected to the api gateway websocket api
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/apigatewaymanagementapi"
+)
+
+// ErrGone is returned when the client closed the connection, the connection must be forgotten
+var ErrGone = errors.New("connection gone")
+
+// Poster send a message to a connected client
+type Poster interface {
+	Post(ctx context.Context, connectionID string, data []byte) error
+}
+
+// APIGateway post the messages through the api gateway management api
+type APIGateway struct {
+	client *apigatewaymanagementapi.ApiGatewayManagementApi
+}
+
+// NewAPIGateway create a poster for the websocket api stage reachable at the given endpoint,
+// https://{api-id}.execute-api.{region}.amazonaws.com/{stage}
+func NewAPIGateway(sess *session.Session, endpoint string) APIGateway {
+	return APIGateway{client: apigatewaymanagementapi.New(sess, aws.NewConfig().WithEndpoint(endpoint))}
+}
+
+// Post send the data to the connection
+func (g APIGateway) Post(ctx context.Context, connectionID string, data []byte) error {
+	_, err := g.client.PostToConnectionWithContext(ctx, &apigatewaymanagementapi.PostToConnectionInput{
+		ConnectionId: aws.String(connectionID),
+		Data:         data,
+	})
+	if err == nil {
+		return nil
+	}
+
+	var aerr awserr.Error
+	if errors.As(err, &aerr) && aerr.Code() == apigatewaymanagementapi.ErrCodeGoneException {
+		return fmt.Errorf("%w: %v", ErrGone, connectionID)
+	}
+
+	return fmt.Errorf("failed to post to connection %v: %v", connectionID, err)
+}
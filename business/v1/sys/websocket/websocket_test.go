@@ -0,0 +1,59 @@
+package websocket_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"vtc/business/v1/sys/websocket"
+)
+
+const (
+	success = "\u2713"
+	failure = "\u2717"
+)
+
+func Test_APIGateway(t *testing.T) {
+	t.Log("Given the need to push messages to the websocket clients")
+	{
+		received := map[string]string{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/@connections/gone" {
+				w.Header().Set("x-amzn-ErrorType", "GoneException")
+				w.WriteHeader(http.StatusGone)
+				w.Write([]byte(`{}`))
+				return
+			}
+
+			body, _ := io.ReadAll(r.Body)
+			received[r.URL.Path] = string(body)
+		}))
+		defer srv.Close()
+
+		sess := session.Must(session.NewSession(&aws.Config{
+			Region:      aws.String("eu-west-1"),
+			Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		}))
+
+		poster := websocket.NewAPIGateway(sess, srv.URL)
+
+		if err := poster.Post(context.Background(), "conn-1", []byte(`{"type":"ride_update"}`)); err != nil {
+			t.Fatalf("\t%s\t Test: \tShould post the message: %v", failure, err)
+		}
+		if received["/@connections/conn-1"] != `{"type":"ride_update"}` {
+			t.Fatalf("\t%s\t Test: \tShould send the message to the connection, receive %v", failure, received)
+		}
+		t.Logf("\t%s\t Test: \tShould post the message to the connection", success)
+
+		if err := poster.Post(context.Background(), "gone", []byte(`{}`)); !errors.Is(err, websocket.ErrGone) {
+			t.Fatalf("\t%s\t Test: \tShould report a closed connection as gone, receive %v", failure, err)
+		}
+		t.Logf("\t%s\t Test: \tShould report a closed connection as gone", success)
+	}
+}
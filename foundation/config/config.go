@@ -24,14 +24,77 @@ type Env struct {
 		Key string `conf:"env:STRIPE_KEY,required"`
 	}
 	Providers struct {
-		Timeout int `conf:"env:PROVIDERS_DEFAULT_TIMEOUT"`
-		MySam   struct {
-			APIKey string `conf:"env:MY_SAM_API_KEY"`
+		Timeout          int `conf:"env:PROVIDERS_DEFAULT_TIMEOUT"`
+		MaxRetries       int `conf:"env:PROVIDERS_MAX_RETRIES,default:2"`
+		BackoffMs        int `conf:"env:PROVIDERS_RETRY_BACKOFF_MS,default:100"`
+		BreakerThreshold int `conf:"env:PROVIDERS_BREAKER_THRESHOLD,default:5"`
+		BreakerCooldown  int `conf:"env:PROVIDERS_BREAKER_COOLDOWN,default:30"`
+		MySam            struct {
+			APIKey   string `conf:"env:MY_SAM_API_KEY"`
+			BaseURL  string `conf:"env:MY_SAM_BASE_URL"`
+			Timeout  int    `conf:"env:MY_SAM_TIMEOUT,default:0"`
+			Coverage string `conf:"env:MY_SAM_COVERAGE"`
+			// WebhookSecret is the shared secret mysam send with its status callbacks
+			WebhookSecret string `conf:"env:MY_SAM_WEBHOOK_SECRET"`
+			// CancellationFee is charged when a ride is cancelled more than CancellationGraceMinutes after a driver
+			// was assigned
+			CancellationFee          int `conf:"env:MY_SAM_CANCELLATION_FEE,default:10"`
+			CancellationGraceMinutes int `conf:"env:MY_SAM_CANCELLATION_GRACE_MINUTES,default:5"`
 		}
 		Uber struct {
-			Cookie string `conf:"env:UBER_COOKIE"`
+			Cookie   string `conf:"env:UBER_COOKIE"`
+			BaseURL  string `conf:"env:UBER_BASE_URL"`
+			Timeout  int    `conf:"env:UBER_TIMEOUT,default:0"`
+			Coverage string `conf:"env:UBER_COVERAGE"`
+			// WebhookSecret is the key uber sign its status callbacks with
+			WebhookSecret string `conf:"env:UBER_WEBHOOK_SECRET"`
 		}
 	}
+	Offers struct {
+		RetentionHours        int `conf:"env:OFFER_RETENTION_HOURS,default:24"`
+		PriceTolerancePercent int `conf:"env:OFFER_PRICE_TOLERANCE_PERCENT,default:5"`
+	}
+	Geo struct {
+		OSRMURL                  string `conf:"env:OSRM_BASE_URL"`
+		Timeout                  int    `conf:"env:OSRM_TIMEOUT,default:2"`
+		DistanceTolerancePercent int    `conf:"env:DISTANCE_TOLERANCE_PERCENT,default:30"`
+	}
+	Reservations struct {
+		ReminderMinutes     int `conf:"env:RESERVATION_REMINDER_MINUTES,default:60"`
+		ConfirmationMinutes int `conf:"env:RESERVATION_CONFIRMATION_MINUTES,default:90"`
+		CheckIntervalHours  int `conf:"env:RESERVATION_CHECK_INTERVAL_HOURS,default:6"`
+		PreAuthValidityDays int `conf:"env:PREAUTH_VALIDITY_DAYS,default:7"`
+		PreAuthRenewalHours int `conf:"env:PREAUTH_RENEWAL_HOURS,default:24"`
+	}
+	Refresh struct {
+		Concurrency int `conf:"env:RIDE_REFRESH_CONCURRENCY,default:10"`
+	}
+	WebSocket struct {
+		Endpoint string `conf:"env:WEBSOCKET_ENDPOINT"`
+		TTLHours int    `conf:"env:WEBSOCKET_CONNECTION_TTL_HOURS,default:2"`
+	}
+	Webhooks struct {
+		RetentionDays int `conf:"env:WEBHOOK_RETENTION_DAYS,default:7"`
+	}
+	Invoices struct {
+		// Bucket store the invoices, they are written to LocalDir when it is empty
+		Bucket         string `conf:"env:INVOICE_BUCKET"`
+		LocalDir       string `conf:"env:INVOICE_LOCAL_DIR,default:invoices"`
+		VATPercent     int    `conf:"env:INVOICE_VAT_PERCENT,default:10"`
+		LinkTTLMinutes int    `conf:"env:INVOICE_LINK_TTL_MINUTES,default:15"`
+	}
+	Refunds struct {
+		// SupportGroup is the cognito group of the support staff allowed to refund rides
+		SupportGroup string `conf:"env:REFUND_SUPPORT_GROUP,default:support"`
+	}
+	Reviews struct {
+		// WindowDays is the number of days after its completion a ride can be reviewed
+		WindowDays int `conf:"env:REVIEW_WINDOW_DAYS,default:7"`
+	}
+	Tracking struct {
+		CacheSeconds int `conf:"env:TRACKING_CACHE_SECONDS,default:10"`
+		MaxPositions int `conf:"env:TRACKING_MAX_POSITIONS,default:50"`
+	}
 }
 
 var (
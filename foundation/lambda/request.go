@@ -15,6 +15,7 @@ import (
 )
 
 var (
+	ErrUnauthenticated                    = errors.New("request not authenticated")
 	ContentTypeHeaderMissingError         = errors.New("content type header missing")
 	ContentTypeHeaderNotMultipartError    = errors.New("content type header not multipart error")
 	ContentTypeHeaderMissingBoundaryError = errors.New("content type header missing boundary error")
@@ -73,3 +74,63 @@ func NewReaderMultipart(req events.APIGatewayProxyRequest) (*multipart.Reader, e
 
 	return multipart.NewReader(strings.NewReader(req.Body), boundary), nil
 }
+
+// Identity represent the cognito user authenticated by the authorizer of the route
+type Identity struct {
+	Username string
+	Email    string
+	Groups   []string
+}
+
+// InGroup check if the user belong to the given cognito group
+func (i Identity) InGroup(group string) bool {
+	for _, g := range i.Groups {
+		if g == group {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetIdentity return the user authenticated by the cognito authorizer of the route, read from the claims of its
+// token. It fails on a route without authorizer.
+func GetIdentity(req events.APIGatewayProxyRequest) (Identity, error) {
+	claims, ok := req.RequestContext.Authorizer["claims"].(map[string]interface{})
+	if !ok {
+		return Identity{}, ErrUnauthenticated
+	}
+
+	username, _ := claims["cognito:username"].(string)
+	if len(username) == 0 {
+		return Identity{}, ErrUnauthenticated
+	}
+
+	email, _ := claims["email"].(string)
+
+	return Identity{
+		Username: username,
+		Email:    email,
+		Groups:   claimGroups(claims["cognito:groups"]),
+	}, nil
+}
+
+// claimGroups read the cognito groups claim, api gateway flatten it into a string such as "a,b" or "[a b]"
+func claimGroups(claim interface{}) []string {
+	var groups []string
+
+	switch c := claim.(type) {
+	case []interface{}:
+		for _, g := range c {
+			if s, ok := g.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+	case string:
+		groups = strings.FieldsFunc(strings.Trim(c, "[]"), func(r rune) bool {
+			return r == ',' || r == ' '
+		})
+	}
+
+	return groups
+}
@@ -1,7 +1,10 @@
 package lambda_test
 
 import (
+	"errors"
 	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
 	"vtc/foundation/lambda"
 )
 
@@ -25,3 +28,45 @@ func Test_DecodeBody(t *testing.T) {
 		t.Logf("\t%s\t Test: \tShould be able to decode json body:", success)
 	}
 }
+
+func Test_GetIdentity(t *testing.T) {
+	t.Log("Given the need to read the user authenticated by the cognito authorizer")
+	{
+		tests := []struct {
+			name   string
+			groups interface{}
+		}{
+			{name: "a list of groups", groups: []interface{}{"admin", "support"}},
+			{name: "groups joined by a comma", groups: "admin,support"},
+			{name: "groups flattened by api gateway", groups: "[admin support]"},
+		}
+
+		for _, tt := range tests {
+			req := events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Authorizer: map[string]interface{}{
+						"claims": map[string]interface{}{
+							"cognito:username": "jdupont",
+							"email":            "support@thegoodseat.fr",
+							"cognito:groups":   tt.groups,
+						},
+					},
+				},
+			}
+
+			i, err := lambda.GetIdentity(req)
+			if err != nil {
+				t.Fatalf("\t%s\t Test: \tShould read the identity with %s: %v", failure, tt.name, err)
+			}
+			if i.Username != "jdupont" || i.Email != "support@thegoodseat.fr" || !i.InGroup("support") || i.InGroup("other") {
+				t.Fatalf("\t%s\t Test: \tShould read the identity with %s: got %+v", failure, tt.name, i)
+			}
+			t.Logf("\t%s\t Test: \tShould read the identity with %s", success, tt.name)
+		}
+
+		if _, err := lambda.GetIdentity(events.APIGatewayProxyRequest{}); !errors.Is(err, lambda.ErrUnauthenticated) {
+			t.Fatalf("\t%s\t Test: \tShould reject a request without authorizer: got %v", failure, err)
+		}
+		t.Logf("\t%s\t Test: \tShould reject a request without authorizer", success)
+	}
+}